@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"log"
+	neturl "net/url"
 	"strings"
 
 	"github.com/gocolly/colly/v2"
@@ -33,6 +33,35 @@ const (
 	Cogir                  Agency = "Cogir"
 )
 
+// Pagination par lien "page suivante" standard (<link rel="next"> dans l'en-tête ou <a rel="next">)
+var relNextPagination = &Pagination{NextSelector: "link[rel='next'], a[rel='next']"}
+
+// Pagination des sites WordPress (liens paginate_links)
+var wordPressPagination = &Pagination{NextSelector: "a.next.page-numbers, link[rel='next'], a[rel='next']"}
+
+// Pagination des API de recherche JSON : paramètre de page, jusqu'au nombre total d'annonces fourni par l'API
+var apiPagination = &Pagination{PageParam: "page"}
+
+/**
+ * init enregistre le scraper de chaque agence dans le registre.
+ */
+func init() {
+	RegisterScraper(Afedim, &agencyScraper{setupMainPage: setupMainPageAfedim, processDetailPages: processDetailPagesAfedim, pagination: relNextPagination})
+	RegisterScraper(Giboire, &agencyScraper{setupMainPage: setupMainPageGiboire, processDetailPages: processDetailPagesGiboire, pagination: relNextPagination})
+	RegisterScraper(Foncia, &agencyScraper{setupMainPage: setupMainPageFoncia, processDetailPages: processDetailPagesFoncia, decodeAPI: decodeAPIFoncia, pagination: apiPagination})
+	RegisterScraper(AgenceDuColombier, &agencyScraper{setupMainPage: setupMainPageAgenceDuColombier, processDetailPages: processDetailPagesAgenceDuColombier, pagination: wordPressPagination})
+	RegisterScraper(LaFrancaiseImmobiliere, &agencyScraper{setupMainPage: setupMainPageLaFrancaiseImmobiliere, processDetailPages: processDetailPagesLaFrancaiseImmobiliere, pagination: wordPressPagination})
+	RegisterScraper(Guenno, &agencyScraper{setupMainPage: setupMainPageGuenno, processDetailPages: processDetailPagesGuenno, pagination: relNextPagination})
+	RegisterScraper(LaMotte, &agencyScraper{setupMainPage: setupMainPageLaMotte, processDetailPages: processDetailPagesLaMotte, pagination: relNextPagination})
+	RegisterScraper(Kermarrec, &agencyScraper{setupMainPage: setupMainPageKermarrec, processDetailPages: processDetailPagesKermarrec, pagination: wordPressPagination})
+	RegisterScraper(Nestenn, &agencyScraper{setupMainPage: setupMainPageNestenn, processDetailPages: processDetailPagesNestenn, pagination: relNextPagination})
+	RegisterScraper(SquareHabitat, &agencyScraper{setupMainPage: setupMainPageSquareHabitat, deriveAnnouncement: deriveAnnouncementSquareHabitat, decodeAPI: decodeAPISquareHabitat, pagination: apiPagination})
+	RegisterScraper(CAImmobilier, &agencyScraper{setupMainPage: setupMainPageCAImmobilier, deriveAnnouncement: deriveAnnouncementCAImmobilier, pagination: relNextPagination})
+	RegisterScraper(PigeaultImmobilier, &agencyScraper{setupMainPage: setupMainPagePigeaultImmobilier, processDetailPages: processDetailPagesPigeaultImmobilier, pagination: wordPressPagination})
+	RegisterScraper(LaForetImmobilier, &agencyScraper{setupMainPage: setupMainPageLaForetImmobilier, processDetailPages: processDetailPagesLaForetImmobilier, pagination: relNextPagination})
+	RegisterScraper(Cogir, &agencyScraper{setupMainPage: setupMainPageCogir, processDetailPages: processDetailPagesCogir, pagination: &Pagination{PageParam: "page"}})
+}
+
 /**
  * setupMainPageAfedim configure le collecteur pour la page principale de l'agence Afedim.
  * @param {colly.Collector} collector - Le collecteur à configurer.
@@ -52,8 +81,16 @@ func setupMainPageAfedim(collector *colly.Collector, detailPageURLs *[]string) {
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces Afedim
+var detailSelectorsAfedim = detailSelectors{
+	title:       "h1",
+	description: "div.description, div[class*='descriptif']",
+	features:    "div[class*='criteres'], div[class*='caracteristiques']",
+	photos:      "div[class*='photo'] img, div[class*='slider'] img",
+}
+
 /**
- * processDetailPagesAfedim extrait les références des annonces de la page de détail de l'agence Afedim.
+ * processDetailPagesAfedim extrait les références et les informations des annonces de la page de détail de l'agence Afedim.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -67,10 +104,12 @@ func processDetailPagesAfedim(collector *colly.Collector, announcements *[]Annou
 
 		if reference != "" {
 			url := detail.Request.URL.String()
-			*announcements = append(*announcements, Announcement{
+			announcement := Announcement{
 				propertyReference: reference,
 				url:               url,
-			})
+			}
+			extractListingDetails(&announcement, detail, detailSelectorsAfedim)
+			*announcements = append(*announcements, announcement)
 		}
 	})
 }
@@ -104,8 +143,16 @@ func setupMainPageGiboire(collector *colly.Collector, detailPageURLs *[]string)
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces Giboire
+var detailSelectorsGiboire = detailSelectors{
+	title:       "h1",
+	description: "div.presentation-bien_desc, div.presentation-bien_exclu_desc",
+	features:    "div.presentation-bien",
+	photos:      "div.presentation-bien_slider img, div.swiper-slide img",
+}
+
 /**
- * processDetailPagesGiboire extrait les références des annonces de la page de détail de l'agence Giboire.
+ * processDetailPagesGiboire extrait les références et les informations des annonces de la page de détail de l'agence Giboire.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -123,13 +170,15 @@ func processDetailPagesGiboire(collector *colly.Collector, announcements *[]Anno
 				url := detail.Request.URL.String()
 
 				// Ajouter l'annonce à la liste
-				*announcements = append(*announcements, Announcement{
+				announcement := Announcement{
 					propertyReference: reference,
 					url:               url,
-				})
+				}
+				extractListingDetails(&announcement, detail, detailSelectorsGiboire)
+				*announcements = append(*announcements, announcement)
 			}
 		} else {
-			log.Printf("Impossible d'extraire la référence depuis : %s", fullValue)
+			elementLogger(detail).Warn("Impossible d'extraire la référence", "text", fullValue)
 		}
 	})
 }
@@ -168,8 +217,17 @@ func setupMainPageFoncia(collector *colly.Collector, detailPageURLs *[]string) {
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces Foncia
+var detailSelectorsFoncia = detailSelectors{
+	title:       "h1",
+	description: "div.section-description, p.description",
+	rent:        "p.price, div.price",
+	features:    "div.section-criteria, ul.criteria-list",
+	photos:      "div.gallery img, div.carousel img",
+}
+
 /**
- * processDetailPagesFoncia extrait les références des annonces de la page de détail de l'agence Foncia.
+ * processDetailPagesFoncia extrait les références et les informations des annonces de la page de détail de l'agence Foncia.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -187,15 +245,17 @@ func processDetailPagesFoncia(collector *colly.Collector, announcements *[]Annou
 				url := detail.Request.URL.String()
 
 				// Ajouter l'annonce à la liste
-				*announcements = append(*announcements, Announcement{
+				announcement := Announcement{
 					propertyReference: reference,
 					url:               url,
-				})
+				}
+				extractListingDetails(&announcement, detail, detailSelectorsFoncia)
+				*announcements = append(*announcements, announcement)
 			} else {
-				log.Printf("Référence vide après extraction depuis : %s", fullValue)
+				elementLogger(detail).Warn("Référence vide après extraction", "text", fullValue)
 			}
 		} else {
-			log.Printf("Erreur lors de l'extraction de la référence depuis : %s, erreur : %v", fullValue, err)
+			elementLogger(detail).Warn("Erreur lors de l'extraction de la référence", "text", fullValue, "error", err)
 		}
 	})
 }
@@ -219,8 +279,17 @@ func setupMainPageAgenceDuColombier(collector *colly.Collector, detailPageURLs *
 
 }
 
+// Sélecteurs des informations détaillées des annonces Agence du Colombier
+var detailSelectorsAgenceDuColombier = detailSelectors{
+	title:       "h1.entry-title, h1",
+	description: "div.wpestate_property_description, div.property_description",
+	rent:        "div.price_area, span.price_area",
+	features:    "div.wpestate_estate_property_design_intext_details, div.property_categs",
+	photos:      "div.gallery_wrapper img, div#carousel-listing img",
+}
+
 /**
- * processDetailPagesAgenceDuColombier extrait les références des annonces de la page de détail de l'agence Agence du Colombier.
+ * processDetailPagesAgenceDuColombier extrait les références et les informations des annonces de la page de détail de l'agence Agence du Colombier.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -243,13 +312,15 @@ func processDetailPagesAgenceDuColombier(collector *colly.Collector, announcemen
 						url := detail.Request.URL.String()
 
 						// Ajouter l'annonce à la liste des résultats
-						*announcements = append(*announcements, Announcement{
+						announcement := Announcement{
 							propertyReference: reference,
 							url:               url,
-						})
+						}
+						extractListingDetails(&announcement, detail, detailSelectorsAgenceDuColombier)
+						*announcements = append(*announcements, announcement)
 					}
 				} else {
-					log.Printf("Impossible d'extraire la référence depuis : %s", fullText)
+					elementLogger(el).Warn("Impossible d'extraire la référence", "text", fullText)
 				}
 			}
 		})
@@ -269,13 +340,22 @@ func setupMainPageLaFrancaiseImmobiliere(collector *colly.Collector, detailPageU
 		if detailLink != "" {
 			*detailPageURLs = append(*detailPageURLs, detailLink)
 		} else {
-			log.Println("Lien de détail introuvable dans cet article.")
+			elementLogger(e).Debug("Lien de détail introuvable dans cet article")
 		}
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces La Française Immobilière
+var detailSelectorsLaFrancaiseImmobiliere = detailSelectors{
+	title:       "h1",
+	description: "div.description, div#descriptif",
+	rent:        "p.prix, span.prix",
+	features:    "div#top_infos, div.caracteristiques, ul.infos",
+	photos:      "div.slider img, div.galerie img",
+}
+
 /**
- * processDetailPagesLaFrancaiseImmobiliere extrait les références des annonces de la page de détail de l'agence La Française Immobilière.
+ * processDetailPagesLaFrancaiseImmobiliere extrait les références et les informations des annonces de la page de détail de l'agence La Française Immobilière.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -293,15 +373,17 @@ func processDetailPagesLaFrancaiseImmobiliere(collector *colly.Collector, announ
 				url := detail.Request.URL.String()
 
 				// Ajouter l'annonce à la liste
-				*announcements = append(*announcements, Announcement{
+				announcement := Announcement{
 					propertyReference: reference,
 					url:               url,
-				})
+				}
+				extractListingDetails(&announcement, detail, detailSelectorsLaFrancaiseImmobiliere)
+				*announcements = append(*announcements, announcement)
 			} else {
-				log.Printf("Référence vide après extraction depuis : %s", fullValue)
+				elementLogger(detail).Warn("Référence vide après extraction", "text", fullValue)
 			}
 		} else {
-			log.Printf("Erreur lors de l'extraction de la référence depuis : %s, erreur : %v", fullValue, err)
+			elementLogger(detail).Warn("Erreur lors de l'extraction de la référence", "text", fullValue, "error", err)
 		}
 	})
 }
@@ -324,14 +406,23 @@ func setupMainPageGuenno(collector *colly.Collector, detailPageURLs *[]string) {
 			if href != "" {
 				*detailPageURLs = append(*detailPageURLs, href)
 			} else {
-				log.Println("Aucun lien trouvé dans cet article.")
+				elementLogger(article).Debug("Aucun lien trouvé dans cet article")
 			}
 		})
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces Guenno
+var detailSelectorsGuenno = detailSelectors{
+	title:       "h1",
+	description: "div#realty_area div[itemprop='description'], div.realty-description",
+	rent:        "span[itemprop='price'], div.price",
+	features:    "div#realty_area.realty_details",
+	photos:      "div.realty-photos img, div.slider img",
+}
+
 /**
- * processDetailPagesGuenno extrait les références des annonces de la page de détail de l'agence Guenno.
+ * processDetailPagesGuenno extrait les références et les informations des annonces de la page de détail de l'agence Guenno.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -351,15 +442,17 @@ func processDetailPagesGuenno(collector *colly.Collector, announcements *[]Annou
 				url := detail.Request.URL.String()
 
 				// Ajouter l'annonce à la liste
-				*announcements = append(*announcements, Announcement{
+				announcement := Announcement{
 					propertyReference: reference,
 					url:               url,
-				})
+				}
+				extractListingDetails(&announcement, detail, detailSelectorsGuenno)
+				*announcements = append(*announcements, announcement)
 			} else {
-				log.Printf("Référence vide après extraction depuis : %s", fullValue)
+				elementLogger(detail).Warn("Référence vide après extraction", "text", fullValue)
 			}
 		} else {
-			log.Printf("Impossible de trouver la référence dans : %s", fullValue)
+			elementLogger(detail).Warn("Impossible de trouver la référence", "text", fullValue)
 		}
 	})
 }
@@ -384,15 +477,24 @@ func setupMainPageLaMotte(collector *colly.Collector, detailPageURLs *[]string)
 				if href != "" {
 					*detailPageURLs = append(*detailPageURLs, href)
 				} else {
-					log.Printf("Annonce %d : Aucun lien trouvé", i+1)
+					elementLogger(annonce).Debug("Aucun lien trouvé pour l'annonce", "index", i+1)
 				}
 			})
 		})
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces La Motte
+var detailSelectorsLaMotte = detailSelectors{
+	title:       "h1",
+	description: "div.description__content, div.bien__description",
+	rent:        "p.price, div.heading__price",
+	features:    "div.bien__caracteristiques, div.heading__delivery",
+	photos:      "div.bien__slider img, div.swiper-slide img",
+}
+
 /**
- * processDetailPagesLaMotte extrait les références des annonces de la page de détail de l'agence La Motte.
+ * processDetailPagesLaMotte extrait les références et les informations des annonces de la page de détail de l'agence La Motte.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -412,15 +514,17 @@ func processDetailPagesLaMotte(collector *colly.Collector, announcements *[]Anno
 				url := detail.Request.URL.String()
 
 				// Ajouter l'annonce à la liste
-				*announcements = append(*announcements, Announcement{
+				announcement := Announcement{
 					propertyReference: lot,
 					url:               url,
-				})
+				}
+				extractListingDetails(&announcement, detail, detailSelectorsLaMotte)
+				*announcements = append(*announcements, announcement)
 			} else {
-				log.Printf("Lot vide après extraction depuis : %s", fullValue)
+				elementLogger(detail).Warn("Lot vide après extraction", "text", fullValue)
 			}
 		} else {
-			log.Printf("Impossible de trouver le lot dans : %s", fullValue)
+			elementLogger(detail).Warn("Impossible de trouver le lot", "text", fullValue)
 		}
 	})
 }
@@ -443,14 +547,23 @@ func setupMainPageKermarrec(collector *colly.Collector, detailPageURLs *[]string
 			if href != "" {
 				*detailPageURLs = append(*detailPageURLs, href)
 			} else {
-				log.Println("Aucun lien trouvé dans cet article.")
+				elementLogger(article).Debug("Aucun lien trouvé dans cet article")
 			}
 		})
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces Kermarrec
+var detailSelectorsKermarrec = detailSelectors{
+	title:       "header.entry-header h1, h1",
+	description: "div.entry-content div.description, div.entry-content p",
+	rent:        "header.entry-header span.prix, span.price",
+	features:    "div.entry-content",
+	photos:      "div.gallery img, div.slider img",
+}
+
 /**
- * processDetailPagesKermarrec extrait les références des annonces de la page de détail de l'agence Kermarrec.
+ * processDetailPagesKermarrec extrait les références et les informations des annonces de la page de détail de l'agence Kermarrec.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -471,15 +584,17 @@ func processDetailPagesKermarrec(collector *colly.Collector, announcements *[]An
 				url := detail.Request.URL.String()
 
 				// Ajouter l'annonce à la liste
-				*announcements = append(*announcements, Announcement{
+				announcement := Announcement{
 					propertyReference: reference,
 					url:               url,
-				})
+				}
+				extractListingDetails(&announcement, detail, detailSelectorsKermarrec)
+				*announcements = append(*announcements, announcement)
 			} else {
-				log.Printf("Référence vide après extraction depuis : %s", fullValue)
+				elementLogger(detail).Warn("Référence vide après extraction", "text", fullValue)
 			}
 		} else {
-			log.Printf("Impossible de trouver la référence dans : %s", fullValue)
+			elementLogger(detail).Warn("Impossible de trouver la référence", "text", fullValue)
 		}
 	})
 }
@@ -502,14 +617,23 @@ func setupMainPageNestenn(collector *colly.Collector, detailPageURLs *[]string)
 			if href != "" {
 				*detailPageURLs = append(*detailPageURLs, href)
 			} else {
-				log.Println("Aucun lien trouvé dans cette annonce.")
+				elementLogger(property).Debug("Aucun lien trouvé dans cette annonce")
 			}
 		})
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces Nestenn
+var detailSelectorsNestenn = detailSelectors{
+	title:       "h1",
+	description: "div.property_description, div#description",
+	rent:        "div.property_price, span.price",
+	features:    "div.property_details, div.property_caracteristiques",
+	photos:      "div.property_slider img, div.slider img",
+}
+
 /**
- * processDetailPagesNestenn extrait les références des annonces de la page de détail de l'agence Nestenn.
+ * processDetailPagesNestenn extrait les références et les informations des annonces de la page de détail de l'agence Nestenn.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -533,18 +657,20 @@ func processDetailPagesNestenn(collector *colly.Collector, announcements *[]Anno
 					url := detail.Request.URL.String()
 
 					// Ajouter l'annonce à la liste
-					*announcements = append(*announcements, Announcement{
+					announcement := Announcement{
 						propertyReference: reference,
 						url:               url,
-					})
+					}
+					extractListingDetails(&announcement, detail, detailSelectorsNestenn)
+					*announcements = append(*announcements, announcement)
 				} else {
-					log.Printf("Référence vide après extraction depuis : %s", fullValue)
+					elementLogger(detail).Warn("Référence vide après extraction", "text", fullValue)
 				}
 			} else {
-				log.Printf("Impossible de diviser la chaîne pour trouver la référence : %s", fullValue)
+				elementLogger(detail).Warn("Impossible de diviser la chaîne pour trouver la référence", "text", fullValue)
 			}
 		} else {
-			log.Printf("Pas de 'Réf :' trouvé dans : %s", fullValue)
+			elementLogger(detail).Warn("Pas de 'Réf :' trouvé", "text", fullValue)
 		}
 	})
 }
@@ -562,16 +688,53 @@ func setupMainPageSquareHabitat(collector *colly.Collector, details *[]string) {
 		e.ForEach("div.card-container", func(index int, property *colly.HTMLElement) {
 			description := property.ChildText("app-card-bien > msl-card > div:nth-of-type(2) > div:nth-of-type(4) > app-texte-on-off > div.container > div.text-container > p")
 
-			// Vérifier si une description est trouvée
-			if description == "" {
-				log.Printf("Aucune description trouvée pour l'annonce %d", index+1)
-			} else {
-				*details = append(*details, description)
+			// Identifiant du bien (attribut data-id) et lien vers la page de détail, quand la carte les expose
+			id := firstNonEmpty(property.Attr("data-id"), property.ChildAttr("[data-id]", "data-id"))
+			detailURL := property.ChildAttr("a[href]", "href")
+
+			// Vérifier si l'annonce peut être identifiée
+			if description == "" && id == "" && detailURL == "" {
+				elementLogger(property).Warn("Aucune description ni identifiant trouvé pour l'annonce", "index", index+1)
+				return
 			}
+
+			// Les informations de la carte sont encodées en paramètres de requête dans l'élément collecté
+			item := neturl.Values{}
+			item.Set("id", strings.TrimSpace(id))
+			if detailURL != "" {
+				item.Set("url", e.Request.AbsoluteURL(detailURL))
+			}
+			item.Set("description", description)
+			*details = append(*details, item.Encode())
 		})
 	})
 }
 
+/**
+ * deriveAnnouncementSquareHabitat construit l'annonce Square Habitat depuis la page de résultats.
+ * La référence est l'identifiant du bien (attribut data-id, sinon URL de la page de détail),
+ * ou à défaut une empreinte de la description normalisée.
+ * @param {string} listingItem - Les informations de la carte, encodées en paramètres de requête (id, url, description).
+ * @return {Announcement} - L'annonce dérivée.
+ */
+func deriveAnnouncementSquareHabitat(listingItem string) Announcement {
+	item, _ := neturl.ParseQuery(listingItem)
+	description := item.Get("description")
+	detailURL := item.Get("url")
+
+	announcement := Announcement{
+		propertyReference: firstNonEmpty(item.Get("id"), idFromURL(detailURL), hashReference(description)),
+		url:               detailURL,
+		description:       description,
+	}
+
+	// Référence des versions précédentes : empreinte de la description (la description elle-même est migrée au chargement)
+	if legacy := hashReference(description); legacy != "" && legacy != announcement.propertyReference {
+		announcement.legacyReferences = []string{legacy}
+	}
+	return announcement
+}
+
 /**
  * setupMainPageCAImmobilier configure le collecteur pour la page principale de CA Immobilier.
  * @param {colly.Collector} collector - Le collecteur à configurer.
@@ -593,16 +756,37 @@ func setupMainPageCAImmobilier(collector *colly.Collector, detailPageURLs *[]str
 					}
 				})
 			} else {
-				log.Println("Div supplémentaire ignorée.")
+				elementLogger(annonceDiv).Debug("Div supplémentaire ignorée")
 			}
 		})
 	})
 }
 
 /**
- * processDetailPagesCAImmobilier extrait les références des annonces de la page de détail de CA Immobilier.
+ * deriveAnnouncementCAImmobilier construit l'annonce CA Immobilier depuis l'URL de la page de détail.
+ * La référence est l'identifiant extrait de l'URL (sans paramètres ni libellé), ou à défaut une empreinte de l'URL.
+ * @param {string} detailPageURL - L'URL de la page de détail.
+ * @return {Announcement} - L'annonce dérivée.
+ */
+func deriveAnnouncementCAImmobilier(detailPageURL string) Announcement {
+	announcement := Announcement{
+		propertyReference: firstNonEmpty(idFromURL(detailPageURL), hashReference(detailPageURL)),
+		url:               detailPageURL,
+	}
+
+	// Référence des versions précédentes : partie de l'URL après le dernier "/"
+	if lastSlashIndex := strings.LastIndex(detailPageURL, "/"); lastSlashIndex != -1 && lastSlashIndex+1 < len(detailPageURL) {
+		if legacy := detailPageURL[lastSlashIndex+1:]; legacy != announcement.propertyReference {
+			announcement.legacyReferences = []string{legacy}
+		}
+	}
+	return announcement
+}
+
+/**
+ * setupMainPagePigeaultImmobilier configure le collecteur pour la page principale de Pigeault Immobilier.
  * @param {colly.Collector} collector - Le collecteur à configurer.
- * @param {[]Announcement} announcements - La liste des annonces à remplir.
+ * @param {[]string} detailPageURLs - La liste des URLs des pages de détail.
  * @return {void}
  */
 func setupMainPagePigeaultImmobilier(collector *colly.Collector, detailPageURLs *[]string) {
@@ -615,14 +799,23 @@ func setupMainPagePigeaultImmobilier(collector *colly.Collector, detailPageURLs
 			if href != "" {
 				*detailPageURLs = append(*detailPageURLs, href)
 			} else {
-				log.Println("Aucun href trouvé pour cet article")
+				elementLogger(article).Debug("Aucun href trouvé pour cet article")
 			}
 		})
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces Pigeault Immobilier
+var detailSelectorsPigeaultImmobilier = detailSelectors{
+	title:       "h1",
+	description: "div.description, div#descriptif",
+	rent:        "div#top_infos p.prix, p.prix",
+	features:    "div#top_infos, div.caracteristiques, ul.infos",
+	photos:      "div.slider img, div.galerie img",
+}
+
 /**
- * processDetailPagesPigeaultImmobilier extrait les références des annonces de la page de détail de Pigeault Immobilier.
+ * processDetailPagesPigeaultImmobilier extrait les références et les informations des annonces de la page de détail de Pigeault Immobilier.
  * @param {colly.Collector} collector - Le collecteur à configurer.
  * @param {[]Announcement} announcements - La liste des annonces à remplir.
  * @return {void}
@@ -643,15 +836,17 @@ func processDetailPagesPigeaultImmobilier(collector *colly.Collector, announceme
 				url := detail.Request.URL.String()
 
 				// Ajouter l'annonce à la liste
-				*announcements = append(*announcements, Announcement{
+				announcement := Announcement{
 					propertyReference: reference,
 					url:               url,
-				})
+				}
+				extractListingDetails(&announcement, detail, detailSelectorsPigeaultImmobilier)
+				*announcements = append(*announcements, announcement)
 			} else {
-				log.Printf("Référence vide après extraction depuis : %s", fullValue)
+				elementLogger(detail).Warn("Référence vide après extraction", "text", fullValue)
 			}
 		} else {
-			log.Printf("Impossible de trouver la référence dans : %s", fullValue)
+			elementLogger(detail).Warn("Impossible de trouver la référence", "text", fullValue)
 		}
 	})
 }
@@ -675,6 +870,16 @@ func setupMainPageLaForetImmobilier(collector *colly.Collector, detailPageURLs *
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces La Foret Immobilier
+var detailSelectorsLaForetImmobilier = detailSelectors{
+	title:       "h1",
+	description: "section.property-content div.property-description, section.property-content p",
+	rent:        "div.property__price, span.property-price",
+	features:    "section.property__block.property-content",
+	energyClass: "div.dpe__value, span.energy-class",
+	photos:      "div.property__gallery img, div.swiper-slide img",
+}
+
 func processDetailPagesLaForetImmobilier(collector *colly.Collector, announcements *[]Announcement) {
 	// Cibler la section contenant les informations de l'annonce
 	collector.OnHTML("section.property__block.property-content", func(detail *colly.HTMLElement) {
@@ -691,13 +896,16 @@ func processDetailPagesLaForetImmobilier(collector *colly.Collector, announcemen
 			// URL actuelle de la page
 			url := detail.Request.URL.String()
 
-			// Ajouter l'annonce avec les références à la liste
-			*announcements = append(*announcements, Announcement{
-				propertyReference: fmt.Sprintf("Web: %s, Agence: %s", webRef, agencyRef),
+			// Référence canonique : référence web, sinon identifiant JSON-LD ou de l'URL, sinon référence agence
+			announcement := Announcement{
+				propertyReference: firstNonEmpty(webRef, idFromJSONLD(detail.DOM.Parents().Last()), idFromURL(url), agencyRef),
 				url:               url,
-			})
+				legacyReferences:  []string{fmt.Sprintf("Web: %s, Agence: %s", webRef, agencyRef)},
+			}
+			extractListingDetails(&announcement, detail, detailSelectorsLaForetImmobilier)
+			*announcements = append(*announcements, announcement)
 		} else {
-			log.Println("Aucune référence trouvée dans cette annonce")
+			elementLogger(detail).Warn("Aucune référence trouvée dans cette annonce")
 		}
 	})
 }
@@ -717,6 +925,15 @@ func setupMainPageCogir(collector *colly.Collector, announcementURLs *[]string)
 	})
 }
 
+// Sélecteurs des informations détaillées des annonces Cogir
+var detailSelectorsCogir = detailSelectors{
+	title:       "h1",
+	description: "div.detail_description, div.description",
+	rent:        "div.detail_header div.prix, span.prix",
+	features:    "div.detail_header, div.detail_criteres",
+	photos:      "div.detail_photos img, div.slider img",
+}
+
 func processDetailPagesCogir(collector *colly.Collector, announcements *[]Announcement) {
 	// Cibler la section contenant les informations de l'annonce
 	collector.OnHTML("div.detail_header", func(detail *colly.HTMLElement) {
@@ -730,10 +947,12 @@ func processDetailPagesCogir(collector *colly.Collector, announcements *[]Announ
 			url := detail.Request.URL.String()
 
 			// Ajouter l'annonce avec la référence à la liste
-			*announcements = append(*announcements, Announcement{
+			announcement := Announcement{
 				propertyReference: ref,
 				url:               url,
-			})
+			}
+			extractListingDetails(&announcement, detail, detailSelectorsCogir)
+			*announcements = append(*announcements, announcement)
 		}
 	})
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+/**
+ * fonciaSearchResponse est la réponse JSON de l'API de recherche de Foncia, chargée en XHR par le site Angular.
+ * Seuls les champs utilisés sont décodés.
+ * @property {int} TotalCount - Nombre total d'annonces de la recherche.
+ * @property {[]fonciaListing} Annonces - Annonces de la page.
+ */
+type fonciaSearchResponse struct {
+	TotalCount int             `json:"totalCount"`
+	Annonces   []fonciaListing `json:"annonces"`
+}
+
+/**
+ * fonciaListing est une annonce de la réponse de l'API Foncia.
+ */
+type fonciaListing struct {
+	Reference         string   `json:"reference"`
+	URL               string   `json:"url"`
+	Titre             string   `json:"titre"`
+	Description       string   `json:"description"`
+	Loyer             *float64 `json:"loyer"`
+	Charges           *float64 `json:"charges"`
+	Surface           *float64 `json:"surface"`
+	NbPieces          *int     `json:"nbPieces"`
+	NbChambres        *int     `json:"nbChambres"`
+	Meuble            *bool    `json:"meuble"`
+	DateDisponibilite string   `json:"dateDisponibilite"`
+	Localisation      struct {
+		Ville      string `json:"ville"`
+		CodePostal string `json:"codePostal"`
+	} `json:"localisation"`
+	Diagnostic struct {
+		ClasseEnergie string `json:"classeEnergie"`
+	} `json:"diagnostic"`
+	Photos []struct {
+		URL string `json:"url"`
+	} `json:"photos"`
+}
+
+/**
+ * decodeAPIFoncia décode une page de résultats de l'API de recherche Foncia.
+ * @param {[]byte} body - Le corps de la réponse.
+ * @param {colly.Request} request - La requête, pour construire les URLs absolues.
+ * @return {[]Announcement} - Les annonces de la page.
+ * @return {int} - Le nombre total d'annonces de la recherche.
+ * @return {error} - Erreur de décodage.
+ */
+func decodeAPIFoncia(body []byte, request *colly.Request) ([]Announcement, int, error) {
+	var response fonciaSearchResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, 0, fmt.Errorf("réponse Foncia invalide : %w", err)
+	}
+
+	var announcements []Announcement
+	for _, listing := range response.Annonces {
+		if listing.Reference == "" {
+			continue
+		}
+
+		var photoURLs []string
+		for _, photo := range listing.Photos {
+			photoURLs = append(photoURLs, photo.URL)
+		}
+
+		announcements = append(announcements, Announcement{
+			propertyReference: listing.Reference,
+			url:               absoluteURL(request, listing.URL),
+			title:             cleanText(listing.Titre),
+			description:       cleanText(listing.Description),
+			rent:              listing.Loyer,
+			charges:           listing.Charges,
+			surface:           listing.Surface,
+			rooms:             listing.NbPieces,
+			bedrooms:          listing.NbChambres,
+			city:              cleanText(listing.Localisation.Ville),
+			postcode:          strings.TrimSpace(listing.Localisation.CodePostal),
+			furnished:         listing.Meuble,
+			energyClass:       strings.ToUpper(strings.TrimSpace(listing.Diagnostic.ClasseEnergie)),
+			availableDate:     strings.TrimSpace(listing.DateDisponibilite),
+			photoURLs:         absoluteURLs(request, photoURLs),
+		})
+	}
+
+	return announcements, response.TotalCount, nil
+}
+
+/**
+ * squareHabitatSearchResponse est la réponse JSON de l'API de recherche de Square Habitat, chargée en XHR par le site.
+ * Seuls les champs utilisés sont décodés.
+ * @property {int} NbResultats - Nombre total d'annonces de la recherche.
+ * @property {[]squareHabitatListing} Biens - Annonces de la page.
+ */
+type squareHabitatSearchResponse struct {
+	NbResultats int                    `json:"nbResultats"`
+	Biens       []squareHabitatListing `json:"biens"`
+}
+
+/**
+ * squareHabitatListing est une annonce de la réponse de l'API Square Habitat.
+ * L'identifiant est numérique ou textuel selon les biens : il est décodé tel quel.
+ */
+type squareHabitatListing struct {
+	ID                json.RawMessage `json:"id"`
+	Reference         string          `json:"reference"`
+	URLDetail         string          `json:"urlDetail"`
+	Libelle           string          `json:"libelle"`
+	Description       string          `json:"description"`
+	Prix              *float64        `json:"prix"`
+	Charges           *float64        `json:"charges"`
+	SurfaceHabitable  *float64        `json:"surfaceHabitable"`
+	NbPieces          *int            `json:"nbPieces"`
+	NbChambres        *int            `json:"nbChambres"`
+	Meuble            *bool           `json:"meuble"`
+	Ville             string          `json:"ville"`
+	CodePostal        string          `json:"codePostal"`
+	ClasseEnergie     string          `json:"classeEnergie"`
+	DateDisponibilite string          `json:"dateDisponibilite"`
+	Photos            []string        `json:"photos"`
+}
+
+/**
+ * decodeAPISquareHabitat décode une page de résultats de l'API de recherche Square Habitat.
+ * La référence de l'annonce est sa référence d'agence, ou son identifiant à défaut.
+ * @param {[]byte} body - Le corps de la réponse.
+ * @param {colly.Request} request - La requête, pour construire les URLs absolues.
+ * @return {[]Announcement} - Les annonces de la page.
+ * @return {int} - Le nombre total d'annonces de la recherche.
+ * @return {error} - Erreur de décodage.
+ */
+func decodeAPISquareHabitat(body []byte, request *colly.Request) ([]Announcement, int, error) {
+	var response squareHabitatSearchResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, 0, fmt.Errorf("réponse Square Habitat invalide : %w", err)
+	}
+
+	var announcements []Announcement
+	for _, listing := range response.Biens {
+		reference := strings.TrimSpace(listing.Reference)
+		if reference == "" {
+			reference = strings.Trim(string(listing.ID), `" `)
+		}
+		if reference == "" || reference == "null" {
+			continue
+		}
+
+		announcements = append(announcements, Announcement{
+			propertyReference: reference,
+			url:               absoluteURL(request, listing.URLDetail),
+			title:             cleanText(listing.Libelle),
+			description:       cleanText(listing.Description),
+			rent:              listing.Prix,
+			charges:           listing.Charges,
+			surface:           listing.SurfaceHabitable,
+			rooms:             listing.NbPieces,
+			bedrooms:          listing.NbChambres,
+			city:              cleanText(listing.Ville),
+			postcode:          strings.TrimSpace(listing.CodePostal),
+			furnished:         listing.Meuble,
+			energyClass:       strings.ToUpper(strings.TrimSpace(listing.ClasseEnergie)),
+			availableDate:     strings.TrimSpace(listing.DateDisponibilite),
+			photoURLs:         absoluteURLs(request, listing.Photos),
+		})
+	}
+
+	return announcements, response.NbResultats, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// pointer retourne un pointeur vers une valeur, pour écrire les annonces attendues
+func pointer[T any](value T) *T {
+	return &value
+}
+
+// readFixture lit une réponse enregistrée du dossier testdata
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("lecture de la fixture %s : %v", name, err)
+	}
+	return data
+}
+
+// fixtureRequest est la requête servant de base aux URLs relatives des réponses
+func fixtureRequest(t *testing.T, rawURL string) *colly.Request {
+	t.Helper()
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("URL invalide %q : %v", rawURL, err)
+	}
+	return &colly.Request{URL: parsedURL}
+}
+
+func TestDecodeAPIResponses(t *testing.T) {
+	tests := []struct {
+		name      string
+		fixture   string
+		baseURL   string
+		decode    func(body []byte, request *colly.Request) ([]Announcement, int, error)
+		wantTotal int
+		want      []Announcement
+	}{
+		{
+			name:      "Foncia",
+			fixture:   "foncia_search.json",
+			baseURL:   "https://fr.foncia.com/api/annonces?page=1",
+			decode:    decodeAPIFoncia,
+			wantTotal: 2,
+			want: []Announcement{
+				{
+					propertyReference: "39110LO25287",
+					url:               "https://fr.foncia.com/location/rennes-35/appartement/2-pieces/39110LO25287",
+					title:             "Appartement 2 pièces 45 m²",
+					description:       "Appartement T2 lumineux, proche métro Sainte-Anne. Cuisine équipée.",
+					rent:              pointer(680.0),
+					charges:           pointer(45.5),
+					surface:           pointer(45.2),
+					rooms:             pointer(2),
+					bedrooms:          pointer(1),
+					city:              "Rennes",
+					postcode:          "35000",
+					furnished:         pointer(false),
+					energyClass:       "D",
+					availableDate:     "2026-11-15",
+					photoURLs: []string{
+						"https://images.foncia.com/39110LO25287/1.jpg",
+						"https://fr.foncia.com/media/39110LO25287/2.jpg",
+					},
+				},
+				{
+					propertyReference: "39110LO25301",
+					url:               "https://fr.foncia.com/location/chantepie-35135/appartement/3-pieces/39110LO25301",
+					title:             "Appartement 3 pièces",
+					rent:              pointer(695.0),
+					rooms:             pointer(3),
+					city:              "Chantepie",
+					postcode:          "35135",
+				},
+			},
+		},
+		{
+			name:      "Square Habitat",
+			fixture:   "squarehabitat_search.json",
+			baseURL:   "https://www.squarehabitat.fr/api/recherche?page=1",
+			decode:    decodeAPISquareHabitat,
+			wantTotal: 2,
+			want: []Announcement{
+				{
+					propertyReference: "035-LOC-1284571",
+					url:               "https://www.squarehabitat.fr/annonce/location/appartement/rennes-35000/1284571",
+					title:             "Location appartement 2 pièces Rennes",
+					description:       "Rennes centre, appartement meublé de 38 m², disponible immédiatement.",
+					rent:              pointer(640.0),
+					charges:           pointer(60.0),
+					surface:           pointer(38.0),
+					rooms:             pointer(2),
+					bedrooms:          pointer(1),
+					city:              "Rennes",
+					postcode:          "35000",
+					furnished:         pointer(true),
+					energyClass:       "C",
+					availableDate:     "Immédiatement",
+					photoURLs:         []string{"https://photos.squarehabitat.fr/1284571/a.jpg"},
+				},
+				{
+					propertyReference: "A-99812",
+					url:               "https://www.squarehabitat.fr/annonce/location/appartement/cesson-sevigne-35510/A-99812",
+					title:             "Location appartement 1 pièce Cesson-Sévigné",
+					rent:              pointer(520.0),
+					city:              "Cesson-Sévigné",
+					postcode:          "35510",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			announcements, total, err := test.decode(readFixture(t, test.fixture), fixtureRequest(t, test.baseURL))
+			if err != nil {
+				t.Fatalf("décodage : %v", err)
+			}
+			if total != test.wantTotal {
+				t.Errorf("total = %d, attendu %d", total, test.wantTotal)
+			}
+			if len(announcements) != len(test.want) {
+				t.Fatalf("annonces = %d, attendu %d", len(announcements), len(test.want))
+			}
+			for i := range test.want {
+				if got, want := announcements[i].Data(), test.want[i].Data(); !reflect.DeepEqual(got, want) {
+					t.Errorf("annonce %d :\nobtenu  %+v\nattendu %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeAPIResponseInvalid(t *testing.T) {
+	if _, _, err := decodeAPIFoncia([]byte(`<html></html>`), fixtureRequest(t, "https://fr.foncia.com/")); err == nil {
+		t.Error("une réponse HTML doit être refusée")
+	}
+}
+
+func TestScrapeAnnouncementFromAPI(t *testing.T) {
+	body := readFixture(t, "squarehabitat_search.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	announcements, err := NewCollyService().ScrapeAnnouncement(context.Background(), SquareHabitat, server.URL+"/api/recherche?page=1", 5)
+	if err != nil {
+		t.Fatalf("ScrapeAnnouncement : %v", err)
+	}
+
+	// Le nombre total d'annonces est atteint dès la première page : aucune autre page n'est demandée
+	var references []string
+	for _, announcement := range announcements {
+		references = append(references, announcement.propertyReference)
+	}
+	if want := []string{"035-LOC-1284571", "A-99812"}; !reflect.DeepEqual(references, want) {
+		t.Errorf("références = %v, attendu %v", references, want)
+	}
+	if announcements[0].url != server.URL+"/annonce/location/appartement/rennes-35000/1284571" {
+		t.Errorf("url = %q", announcements[0].url)
+	}
+}
+
+func TestScrapeAnnouncementFromAPIDecodeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write([]byte(`{"results": [`))
+	}))
+	t.Cleanup(server.Close)
+
+	// Une réponse illisible est une erreur du scraping : les annonces de la page ne sont pas considérées absentes
+	collyService := NewCollyService()
+	if _, err := collyService.ScrapeAnnouncement(context.Background(), SquareHabitat, server.URL+"/api/recherche?page=1", 5); err != nil {
+		t.Fatalf("ScrapeAnnouncement : %v", err)
+	}
+	if stats := collyService.Stats(); stats.Errors != 1 || stats.Complete() {
+		t.Errorf("statistiques = %+v, attendu une erreur", stats)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/gocolly/colly/v2"
+)
+
+/**
+ * AgencyDefinition décrit dans config.yaml une agence scrapée par sélecteurs CSS, sans code Go : page de résultats,
+ * pages de détail et pagination. Les agences écrites en Go restent disponibles pour les sites plus complexes.
+ * @property {Agency} Name - Nom de l'agence, utilisé dans agency des recherches.
+ * @property {ListingDefinition} Listing - Page de résultats.
+ * @property {DetailDefinition} Detail - Pages de détail (nil si les annonces sont dérivées des liens de la page de résultats).
+ * @property {Pagination} Pagination - Pagination de la page de résultats (lien rel="next" par défaut).
+ */
+type AgencyDefinition struct {
+	Name       Agency            `yaml:"name"`
+	Listing    ListingDefinition `yaml:"listing"`
+	Detail     *DetailDefinition `yaml:"detail"`
+	Pagination *Pagination       `yaml:"pagination"`
+}
+
+/**
+ * ListingDefinition décrit la page de résultats d'une agence déclarée.
+ * @property {string} Container - Sélecteur de l'élément contenant les annonces ("body" par défaut).
+ * @property {string} Item - Sélecteur de chaque annonce dans le conteneur.
+ * @property {string} Link - Sélecteur du lien dans l'annonce (l'annonce elle-même si vide).
+ * @property {string} LinkAttr - Attribut portant l'URL de la page de détail ("href" par défaut).
+ * @property {string} BaseURL - URL de base des liens relatifs (URL de la page de résultats par défaut).
+ */
+type ListingDefinition struct {
+	Container string `yaml:"container"`
+	Item      string `yaml:"item"`
+	Link      string `yaml:"link"`
+	LinkAttr  string `yaml:"link_attr"`
+	BaseURL   string `yaml:"base_url"`
+}
+
+/**
+ * DetailDefinition décrit les pages de détail d'une agence déclarée.
+ * @property {string} Container - Sélecteur de l'élément contenant l'annonce ("html" par défaut).
+ * @property {string} Reference - Sélecteur de la référence de l'annonce (identifiant extrait de l'URL si vide).
+ * @property {string} ReferencePattern - Expression régulière appliquée au texte de la référence : premier groupe capturant, sinon correspondance entière (ex : "Réf :\\s*(\\S+)").
+ * @property {FieldSelectors} Fields - Sélecteurs des informations de l'annonce.
+ */
+type DetailDefinition struct {
+	Container        string         `yaml:"container"`
+	Reference        string         `yaml:"reference"`
+	ReferencePattern string         `yaml:"reference_pattern"`
+	Fields           FieldSelectors `yaml:"fields"`
+}
+
+/**
+ * FieldSelectors regroupe les sélecteurs des informations d'une annonce déclarée ; une information sans sélecteur
+ * est recherchée dans le texte de la page, comme pour les agences écrites en Go.
+ */
+type FieldSelectors struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Rent        string `yaml:"rent"`
+	Charges     string `yaml:"charges"`
+	Surface     string `yaml:"surface"`
+	Rooms       string `yaml:"rooms"`
+	Bedrooms    string `yaml:"bedrooms"`
+	Location    string `yaml:"location"`
+	EnergyClass string `yaml:"energy_class"`
+	Features    string `yaml:"features"`
+	Photos      string `yaml:"photos"`
+}
+
+/**
+ * selectors convertit les sélecteurs déclarés en sélecteurs d'extraction des informations détaillées.
+ * @return {detailSelectors} - Les sélecteurs.
+ */
+func (fields FieldSelectors) selectors() detailSelectors {
+	return detailSelectors{
+		title:       fields.Title,
+		description: fields.Description,
+		rent:        fields.Rent,
+		charges:     fields.Charges,
+		surface:     fields.Surface,
+		rooms:       fields.Rooms,
+		bedrooms:    fields.Bedrooms,
+		location:    fields.Location,
+		energyClass: fields.EnergyClass,
+		features:    fields.Features,
+		photos:      fields.Photos,
+	}
+}
+
+// Agences déclarées dans la configuration, remplacées à chaque chargement (protégées par scrapersMutex)
+var declaredAgencies = make(map[Agency]bool)
+
+/**
+ * RegisterAgencyDefinitions enregistre les agences déclarées dans la configuration, à la place de celles du
+ * chargement précédent. Une agence invalide ou portant le nom d'une agence existante n'est pas enregistrée.
+ * @param {[]AgencyDefinition} definitions - Les agences déclarées.
+ * @return {error} - Les erreurs de toutes les agences refusées.
+ */
+func RegisterAgencyDefinitions(definitions []AgencyDefinition) error {
+	scrapersMutex.Lock()
+	defer scrapersMutex.Unlock()
+
+	for agency := range declaredAgencies {
+		delete(scrapers, agency)
+	}
+	declaredAgencies = make(map[Agency]bool)
+
+	var errs []error
+	for i, definition := range definitions {
+		prefix := fmt.Sprintf("agencies[%d]", i)
+		if definition.Name != "" {
+			prefix += fmt.Sprintf(" (%s)", definition.Name)
+		}
+
+		scraper, err := definition.scraper()
+		if err == nil {
+			if declaredAgencies[definition.Name] {
+				err = errors.New("agence déclarée plusieurs fois")
+			} else if _, exists := scrapers[definition.Name]; exists {
+				err = errors.New("nom déjà utilisé par une agence intégrée")
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s : %w", prefix, err))
+			continue
+		}
+
+		scrapers[definition.Name] = scraper
+		declaredAgencies[definition.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+/**
+ * scraper vérifie la déclaration et construit le scraper de l'agence.
+ * @return {agencyScraper} - Le scraper de l'agence.
+ * @return {error} - Toutes les erreurs de la déclaration.
+ */
+func (definition AgencyDefinition) scraper() (*agencyScraper, error) {
+	var errs []error
+	requireSelector := func(value string, field string, required bool) {
+		if value == "" {
+			if required {
+				errs = append(errs, fmt.Errorf("%s est obligatoire", field))
+			}
+		} else if _, err := cascadia.Compile(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s : sélecteur invalide %q", field, value))
+		}
+	}
+
+	if strings.TrimSpace(string(definition.Name)) == "" {
+		errs = append(errs, errors.New("name est obligatoire"))
+	}
+
+	listing := definition.Listing
+	requireSelector(listing.Container, "listing.container", false)
+	requireSelector(listing.Item, "listing.item", true)
+	requireSelector(listing.Link, "listing.link", false)
+	var baseURL *url.URL
+	if listing.BaseURL != "" {
+		parsedURL, err := url.Parse(listing.BaseURL)
+		if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+			errs = append(errs, fmt.Errorf("listing.base_url invalide : %q", listing.BaseURL))
+		}
+		baseURL = parsedURL
+	}
+
+	var referencePattern *regexp.Regexp
+	if detail := definition.Detail; detail != nil {
+		requireSelector(detail.Container, "detail.container", false)
+		requireSelector(detail.Reference, "detail.reference", false)
+		if detail.ReferencePattern != "" {
+			if detail.Reference == "" {
+				errs = append(errs, errors.New("detail.reference_pattern nécessite detail.reference"))
+			}
+			pattern, err := regexp.Compile(detail.ReferencePattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("detail.reference_pattern invalide : %w", err))
+			}
+			referencePattern = pattern
+		}
+		fields := detail.Fields
+		for _, field := range []struct{ name, selector string }{
+			{"title", fields.Title}, {"description", fields.Description}, {"rent", fields.Rent}, {"charges", fields.Charges},
+			{"surface", fields.Surface}, {"rooms", fields.Rooms}, {"bedrooms", fields.Bedrooms}, {"location", fields.Location},
+			{"energy_class", fields.EnergyClass}, {"features", fields.Features}, {"photos", fields.Photos},
+		} {
+			requireSelector(field.selector, "detail.fields."+field.name, false)
+		}
+	}
+
+	pagination := relNextPagination
+	if definition.Pagination != nil {
+		pagination = definition.Pagination
+		requireSelector(pagination.NextSelector, "pagination.next", false)
+		requireSelector(pagination.TotalSelector, "pagination.total", false)
+		if pagination.PageSize < 0 {
+			errs = append(errs, errors.New("pagination.page_size doit être positif"))
+		}
+		if pagination.TotalSelector != "" && (pagination.PageParam == "" || pagination.PageSize == 0) {
+			errs = append(errs, errors.New("pagination.total nécessite pagination.page_param et pagination.page_size"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	scraper := &agencyScraper{
+		setupMainPage: definition.setupMainPage(baseURL),
+		pagination:    pagination,
+	}
+	if definition.Detail != nil {
+		scraper.processDetailPages = definition.processDetailPages(referencePattern)
+	} else {
+		scraper.deriveAnnouncement = deriveAnnouncementFromURL
+	}
+	return scraper, nil
+}
+
+/**
+ * setupMainPage construit la fonction de configuration de la page de résultats d'une agence déclarée.
+ * @param {url.URL} baseURL - URL de base des liens relatifs, nil pour l'URL de la page de résultats.
+ * @return {func} - La fonction de configuration.
+ */
+func (definition AgencyDefinition) setupMainPage(baseURL *url.URL) func(collector *colly.Collector, listingItems *[]string) {
+	listing := definition.Listing
+	container := firstNonEmpty(listing.Container, "body")
+	linkAttr := firstNonEmpty(listing.LinkAttr, "href")
+
+	return func(collector *colly.Collector, listingItems *[]string) {
+		collector.OnHTML(container, func(e *colly.HTMLElement) {
+			e.ForEach(listing.Item, func(_ int, item *colly.HTMLElement) {
+				var href string
+				if listing.Link != "" {
+					href = item.ChildAttr(listing.Link, linkAttr)
+				} else {
+					href = item.Attr(linkAttr)
+				}
+				href = strings.TrimSpace(href)
+				if href == "" {
+					elementLogger(item).Debug("Aucun lien trouvé dans cette annonce")
+					return
+				}
+
+				if baseURL != nil {
+					if link, err := url.Parse(href); err == nil {
+						href = baseURL.ResolveReference(link).String()
+					}
+				} else {
+					href = item.Request.AbsoluteURL(href)
+				}
+				*listingItems = append(*listingItems, href)
+			})
+		})
+	}
+}
+
+/**
+ * processDetailPages construit la fonction de configuration des pages de détail d'une agence déclarée.
+ * @param {regexp.Regexp} referencePattern - Expression régulière de la référence, nil pour le texte entier.
+ * @return {func} - La fonction de configuration.
+ */
+func (definition AgencyDefinition) processDetailPages(referencePattern *regexp.Regexp) func(collector *colly.Collector, announcements *[]Announcement) {
+	detail := definition.Detail
+	container := firstNonEmpty(detail.Container, "html")
+	selectors := detail.Fields.selectors()
+
+	return func(collector *colly.Collector, announcements *[]Announcement) {
+		collector.OnHTML(container, func(element *colly.HTMLElement) {
+			pageURL := element.Request.URL.String()
+
+			var reference string
+			if detail.Reference == "" {
+				reference = idFromURL(pageURL)
+			} else {
+				text := cleanText(element.DOM.Find(detail.Reference).First().Text())
+				reference = text
+				if referencePattern != nil {
+					reference = ""
+					if matches := referencePattern.FindStringSubmatch(text); len(matches) > 1 {
+						reference = matches[1]
+					} else if len(matches) == 1 {
+						reference = matches[0]
+					}
+				}
+				if reference = strings.TrimSpace(reference); reference == "" {
+					elementLogger(element).Warn("Impossible de trouver la référence", "text", text)
+					return
+				}
+			}
+			if reference == "" {
+				elementLogger(element).Warn("Impossible d'extraire la référence de l'URL")
+				return
+			}
+
+			announcement := Announcement{
+				propertyReference: reference,
+				url:               pageURL,
+			}
+			extractListingDetails(&announcement, element, selectors)
+			*announcements = append(*announcements, announcement)
+		})
+	}
+}
+
+/**
+ * deriveAnnouncementFromURL dérive une annonce d'une agence déclarée sans pages de détail depuis son URL.
+ * @param {string} detailPageURL - L'URL de l'annonce collectée sur la page de résultats.
+ * @return {Announcement} - L'annonce avec sa référence dérivée.
+ */
+func deriveAnnouncementFromURL(detailPageURL string) Announcement {
+	return Announcement{
+		propertyReference: firstNonEmpty(idFromURL(detailPageURL), hashReference(detailPageURL)),
+		url:               detailPageURL,
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Guenno décrite dans la configuration, avec les sélecteurs du scraper écrit en Go
+const guennoDefinitionYAML = `
+agencies:
+  - name: Guenno déclarée
+    listing:
+      container: div.section-content
+      item: article
+      link: a
+    detail:
+      container: div#realty_area.realty_details
+      reference: span.grey-ref
+      reference_pattern: 'Ref :\s*(\S+)'
+      fields:
+        title: h1
+        description: "div#realty_area div[itemprop='description'], div.realty-description"
+        rent: "span[itemprop='price'], div.price"
+        features: div#realty_area.realty_details
+        photos: div.realty-photos img, div.slider img
+targets:
+  - agency: Guenno déclarée
+    url: https://www.guenno.com/location
+    title: GUENNO
+`
+
+// writeConfig écrit un fichier de configuration temporaire et retourne son chemin
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("écriture de la configuration : %v", err)
+	}
+	// Les agences déclarées ne doivent pas rester enregistrées pour les autres tests
+	t.Cleanup(func() { _ = RegisterAgencyDefinitions(nil) })
+	return path
+}
+
+func TestAgencyDefinitionMatchesGuenno(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Join("testdata", "agencies"))))
+	t.Cleanup(server.Close)
+
+	if _, err := LoadConfig(writeConfig(t, guennoDefinitionYAML)); err != nil {
+		t.Fatalf("LoadConfig : %v", err)
+	}
+	declared, err := GetScraper("Guenno déclarée")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handWritten, err := GetScraper(Guenno)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(declared.Pagination(), handWritten.Pagination()) {
+		t.Errorf("pagination = %+v, attendu %+v", declared.Pagination(), handWritten.Pagination())
+	}
+
+	listingURL := server.URL + "/guenno/listing.html"
+	if got, want := scrapeListingFixture(t, declared, listingURL), scrapeListingFixture(t, handWritten, listingURL); !reflect.DeepEqual(got, want) {
+		t.Errorf("éléments de la page de résultats :\nobtenu  %q\nattendu %q", got, want)
+	}
+
+	detailURL := server.URL + "/guenno/detail.html"
+	got, want := scrapeDetailFixture(t, declared, detailURL), scrapeDetailFixture(t, handWritten, detailURL)
+	if len(got) != len(want) || len(want) == 0 {
+		t.Fatalf("annonces = %d, attendu %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i].Data(), want[i].Data()) {
+			t.Errorf("annonce %d :\nobtenu  %+v\nattendu %+v", i, got[i].Data(), want[i].Data())
+		}
+	}
+}
+
+func TestAgencyDefinitionWithoutDetailPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><body><ul class="biens">
+			<li class="bien" data-url="/location/t2-rennes-4521">T2 Rennes</li>
+			<li class="bien" data-url="">Sans lien</li>
+			<li class="bien" data-url="https://www.exemple.fr/location/studio-4530">Studio</li>
+		</ul></body></html>`))
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { _ = RegisterAgencyDefinitions(nil) })
+
+	// Liens relatifs résolus avec base_url, annonces dérivées de l'URL sans visiter les pages de détail
+	err := RegisterAgencyDefinitions([]AgencyDefinition{{
+		Name:    "Exemple",
+		Listing: ListingDefinition{Container: "ul.biens", Item: "li.bien", LinkAttr: "data-url", BaseURL: "https://www.exemple.fr/"},
+	}})
+	if err != nil {
+		t.Fatalf("RegisterAgencyDefinitions : %v", err)
+	}
+	scraper, err := GetScraper("Exemple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scraper.HasDetailPages() {
+		t.Error("une agence sans detail ne doit pas visiter de pages de détail")
+	}
+
+	listingItems := scrapeListingFixture(t, scraper, server.URL)
+	wantItems := []string{"https://www.exemple.fr/location/t2-rennes-4521", "https://www.exemple.fr/location/studio-4530"}
+	if !reflect.DeepEqual(listingItems, wantItems) {
+		t.Fatalf("éléments de la page de résultats :\nobtenu  %q\nattendu %q", listingItems, wantItems)
+	}
+	if announcement := scraper.DeriveAnnouncement(listingItems[0]); announcement.propertyReference != "4521" || announcement.url != wantItems[0] {
+		t.Errorf("annonce dérivée = %q (%s), attendu 4521", announcement.propertyReference, announcement.url)
+	}
+}
+
+func TestRegisterAgencyDefinitionsErrors(t *testing.T) {
+	t.Cleanup(func() { _ = RegisterAgencyDefinitions(nil) })
+
+	valid := AgencyDefinition{Name: "Valide", Listing: ListingDefinition{Item: "article", Link: "a"}}
+	err := RegisterAgencyDefinitions([]AgencyDefinition{
+		valid,
+		{Name: Guenno, Listing: ListingDefinition{Item: "article"}},
+		valid,
+		{Name: "Sans annonce"},
+		{Name: "Sélecteur", Listing: ListingDefinition{Item: "div["}},
+		{Name: "Base", Listing: ListingDefinition{Item: "article", BaseURL: "/location"}},
+		{Name: "Référence", Listing: ListingDefinition{Item: "article"}, Detail: &DetailDefinition{Reference: "span.ref", ReferencePattern: "Réf : ("}},
+		{Name: "Pagination", Listing: ListingDefinition{Item: "article"}, Pagination: &Pagination{TotalSelector: "span.total"}},
+	})
+	if err == nil {
+		t.Fatal("les déclarations invalides doivent être refusées")
+	}
+
+	for _, want := range []string{
+		"agencies[1] (Guenno) : nom déjà utilisé par une agence intégrée",
+		"agencies[2] (Valide) : agence déclarée plusieurs fois",
+		"agencies[3] (Sans annonce) : listing.item est obligatoire",
+		"agencies[4] (Sélecteur) : listing.item : sélecteur invalide",
+		"agencies[5] (Base) : listing.base_url invalide",
+		"agencies[6] (Référence) : detail.reference_pattern invalide",
+		"agencies[7] (Pagination) : pagination.total nécessite pagination.page_param et pagination.page_size",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("erreur attendue %q dans :\n%v", want, err)
+		}
+	}
+
+	// Les déclarations valides sont enregistrées, sans remplacer les agences intégrées
+	if _, err := GetScraper("Valide"); err != nil {
+		t.Errorf("agence valide non enregistrée : %v", err)
+	}
+	if scraper, _ := GetScraper(Guenno); scraper.Pagination() != relNextPagination {
+		t.Error("l'agence intégrée Guenno ne doit pas être remplacée")
+	}
+
+	// Un nouveau chargement remplace les agences déclarées précédemment
+	if err := RegisterAgencyDefinitions(nil); err != nil {
+		t.Fatalf("RegisterAgencyDefinitions : %v", err)
+	}
+	if _, err := GetScraper("Valide"); err == nil {
+		t.Error("l'agence déclarée doit être retirée au chargement suivant")
+	}
+}
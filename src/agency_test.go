@@ -0,0 +1,401 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// scrapeListingFixture visite une page de résultats enregistrée et retourne les éléments collectés par le scraper
+func scrapeListingFixture(t *testing.T, scraper Scraper, pageURL string) []string {
+	t.Helper()
+
+	collector := colly.NewCollector()
+	var listingItems []string
+	scraper.SetupMainPage(collector, &listingItems)
+	if err := collector.Visit(pageURL); err != nil {
+		t.Fatalf("visite de %s : %v", pageURL, err)
+	}
+	collector.Wait()
+	return listingItems
+}
+
+// scrapeDetailFixture visite une page de détail enregistrée et retourne les annonces extraites par le scraper
+func scrapeDetailFixture(t *testing.T, scraper Scraper, pageURL string) []Announcement {
+	t.Helper()
+
+	collector := colly.NewCollector()
+	var announcements []Announcement
+	scraper.ProcessDetailPages(collector, &announcements)
+	if err := collector.Visit(pageURL); err != nil {
+		t.Fatalf("visite de %s : %v", pageURL, err)
+	}
+	collector.Wait()
+	return announcements
+}
+
+func TestAgencyScrapers(t *testing.T) {
+	// Pages enregistrées de chaque agence (testdata/agencies/<agence>/listing.html et detail.html), sans accès réseau
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Join("testdata", "agencies"))))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		agency    Agency
+		fixtures  string
+		wantItems []string
+		// Annonces extraites de detail.html, ou dérivées des éléments de la page de résultats sans pages de détail
+		want []Announcement
+	}{
+		{
+			agency:   Afedim,
+			fixtures: "afedim",
+			wantItems: []string{
+				"https://www.afedim.fr/fr/location/appartement/rennes/t2-67012",
+				"https://www.afedim.fr/fr/location/appartement/cesson-sevigne/studio-67044",
+			},
+			want: []Announcement{{
+				propertyReference: "67012",
+				url:               server.URL + "/afedim/detail.html",
+				title:             "Appartement T2 - 35000 Rennes",
+				description:       "Appartement lumineux au 3e étage avec balcon, proche métro Sainte-Anne.",
+				rent:              pointer(712.5),
+				charges:           pointer(62.0),
+				surface:           pointer(48.3),
+				rooms:             pointer(2),
+				bedrooms:          pointer(1),
+				city:              "Rennes",
+				postcode:          "35000",
+				furnished:         pointer(false),
+				energyClass:       "C",
+				availableDate:     "01/12/2026",
+				photoURLs:         []string{server.URL + "/photos/67012/1.jpg", server.URL + "/photos/67012/2.jpg"},
+			}},
+		},
+		{
+			agency:   Giboire,
+			fixtures: "giboire",
+			wantItems: []string{
+				"https://www.giboire.com/location/appartement-rennes-t3-84512/",
+				"https://www.giboire.com/location/maison-chantepie-t4-84530/",
+			},
+			want: []Announcement{{
+				propertyReference: "84512",
+				url:               server.URL + "/giboire/detail.html",
+				title:             "Appartement T3 Rennes Thabor",
+				description:       "Au calme, appartement meublé de 67 m² avec 2 chambres, 35000 Rennes.",
+				rent:              pointer(1050.0),
+				charges:           pointer(85.0),
+				surface:           pointer(67.0),
+				rooms:             pointer(3),
+				bedrooms:          pointer(2),
+				city:              "Rennes",
+				postcode:          "35000",
+				furnished:         pointer(true),
+				energyClass:       "D",
+				photoURLs:         []string{"https://media.giboire.com/84512/1.jpg"},
+			}},
+		},
+		{
+			agency:   Foncia,
+			fixtures: "foncia",
+			wantItems: []string{
+				"https://fr.foncia.com/location/rennes-35/appartement/2-pieces/39110LO25287",
+				"https://fr.foncia.com/location/chantepie-35135/appartement/3-pieces/39110LO25301",
+			},
+			want: []Announcement{{
+				propertyReference: "39110LO25287",
+				url:               server.URL + "/foncia/detail.html",
+				title:             "Appartement 2 pièces 45 m² Rennes",
+				description:       "Appartement T2 lumineux, proche métro Sainte-Anne. Cuisine équipée.",
+				rent:              pointer(680.0),
+				charges:           pointer(45.5),
+				surface:           pointer(45.2),
+				rooms:             pointer(2),
+				bedrooms:          pointer(1),
+				city:              "Rennes",
+				postcode:          "35000",
+				availableDate:     "15/11/2026",
+				photoURLs:         []string{"https://images.foncia.com/39110LO25287/1.jpg"},
+			}},
+		},
+		{
+			agency:   AgenceDuColombier,
+			fixtures: "colombier",
+			wantItems: []string{
+				"https://www.agenceducolombier.fr/properties/t2-rennes-colombier/",
+				"https://www.agenceducolombier.fr/properties/studio-rennes-gare/",
+			},
+			want: []Announcement{{
+				propertyReference: "COL-2231",
+				url:               server.URL + "/colombier/detail.html",
+				title:             "T2 Rennes Colombier",
+				description:       "Appartement vide de 41 m² au 2e étage, quartier Colombier 35000 Rennes. Disponible immédiatement.",
+				rent:              pointer(590.0),
+				surface:           pointer(41.0),
+				rooms:             pointer(2),
+				photoURLs:         []string{server.URL + "/wp-content/uploads/2026/10/t2-colombier-1.jpg"},
+			}},
+		},
+		{
+			agency:   LaFrancaiseImmobiliere,
+			fixtures: "lafrancaise",
+			wantItems: []string{
+				"https://www.lafrancaiseimmobiliere.fr/location/appartement-t3-rennes-sud-gare/",
+				"https://www.lafrancaiseimmobiliere.fr/location/studio-rennes-villejean/",
+			},
+			want: []Announcement{{
+				propertyReference: "LF-3390",
+				url:               server.URL + "/lafrancaise/detail.html",
+				title:             "Appartement T3 Rennes Sud-Gare",
+				description:       "Proche gare, appartement traversant avec cave et parking.",
+				rent:              pointer(845.0),
+				surface:           pointer(64.0),
+				rooms:             pointer(3),
+				bedrooms:          pointer(2),
+				city:              "Rennes",
+				postcode:          "35000",
+				energyClass:       "E",
+				photoURLs: []string{
+					"https://www.lafrancaiseimmobiliere.fr/photos/lf-3390-1.jpg",
+					"https://www.lafrancaiseimmobiliere.fr/photos/lf-3390-2.jpg",
+				},
+			}},
+		},
+		{
+			agency:   Guenno,
+			fixtures: "guenno",
+			wantItems: []string{
+				"https://www.guenno.com/location/appartement/rennes/2-pieces/ref-G1452",
+				"https://www.guenno.com/location/maison/betton/5-pieces/ref-G1460",
+			},
+			want: []Announcement{{
+				propertyReference: "G1452",
+				url:               server.URL + "/guenno/detail.html",
+				title:             "Location appartement 2 pièces Rennes",
+				description:       "Appartement de 39 m² entièrement meublé, centre-ville de Rennes (35000). Charges : 40 €.",
+				rent:              pointer(620.0),
+				charges:           pointer(40.0),
+				surface:           pointer(39.0),
+				city:              "Rennes",
+				postcode:          "35000",
+				furnished:         pointer(true),
+				photoURLs:         []string{server.URL + "/photos/G1452-1.jpg"},
+			}},
+		},
+		{
+			agency:   LaMotte,
+			fixtures: "lamotte",
+			wantItems: []string{
+				"https://www.lamotte.fr/louer/appartement-t2-rennes-bourg-leveque-5521",
+				"https://www.lamotte.fr/louer/appartement-t1-cesson-sevigne-5530",
+			},
+			want: []Announcement{{
+				propertyReference: "B204",
+				url:               server.URL + "/lamotte/detail.html",
+				title:             "Appartement T2 Rennes Bourg-l'Évêque",
+				description:       "Appartement neuf avec terrasse et place de parking en sous-sol.",
+				rent:              pointer(735.0),
+				surface:           pointer(44.0),
+				rooms:             pointer(2),
+				bedrooms:          pointer(1),
+				city:              "Rennes",
+				postcode:          "35000",
+				photoURLs:         []string{"https://www.lamotte.fr/medias/5521/1.jpg"},
+			}},
+		},
+		{
+			agency:   Kermarrec,
+			fixtures: "kermarrec",
+			wantItems: []string{
+				"https://www.kermarrec-habitation.fr/location/appartement-t2-rennes-centre-ll-35981/",
+				"https://www.kermarrec-habitation.fr/location/maison-t5-pace-ll-36012/",
+			},
+			want: []Announcement{{
+				propertyReference: "LL-35981",
+				url:               server.URL + "/kermarrec/detail.html",
+				title:             "Appartement T2 Rennes centre",
+				description:       "Rue de Brest, 35000 Rennes. Appartement de 52 m² avec 1 chambre, non meublé. DPE : B. Disponible de suite.",
+				rent:              pointer(690.0),
+				surface:           pointer(52.0),
+				rooms:             pointer(2),
+				bedrooms:          pointer(1),
+				city:              "Rennes",
+				postcode:          "35000",
+				furnished:         pointer(false),
+				energyClass:       "B",
+				availableDate:     "de suite",
+				photoURLs:         []string{"https://www.kermarrec-habitation.fr/wp-content/uploads/ll-35981-1.jpg"},
+			}},
+		},
+		{
+			agency:   Nestenn,
+			fixtures: "nestenn",
+			wantItems: []string{
+				"https://immobilier-rennes.nestenn.com/location-appartement-2-pieces-rennes-ref-38211402",
+				"https://immobilier-rennes.nestenn.com/location-studio-rennes-ref-38211455",
+			},
+			want: []Announcement{{
+				propertyReference: "38211402",
+				url:               server.URL + "/nestenn/detail.html",
+				title:             "Appartement 2 pièces 38 m² Rennes",
+				description:       "Appartement rénové, quartier Maurepas, proche commerces.",
+				rent:              pointer(655.0),
+				charges:           pointer(35.0),
+				surface:           pointer(38.0),
+				rooms:             pointer(2),
+				bedrooms:          pointer(1),
+				city:              "Rennes",
+				postcode:          "35700",
+				photoURLs:         []string{server.URL + "/photos/38211402/a.jpg", server.URL + "/photos/38211402/b.jpg"},
+			}},
+		},
+		{
+			agency:   SquareHabitat,
+			fixtures: "squarehabitat",
+			wantItems: []string{
+				"description=Rennes+centre%2C+appartement+meubl%C3%A9+de+38+m%C2%B2.&id=035-LOC-1284571&url=" + url.QueryEscape(server.URL+"/annonce/location/appartement/rennes-35000/1284571"),
+				"description=Studio+%C3%A9tudiant+proche+campus+de+Beaulieu.&id=",
+			},
+			want: []Announcement{
+				{
+					propertyReference: "035-LOC-1284571",
+					url:               server.URL + "/annonce/location/appartement/rennes-35000/1284571",
+					description:       "Rennes centre, appartement meublé de 38 m².",
+					legacyReferences:  []string{hashReference("Rennes centre, appartement meublé de 38 m².")},
+				},
+				{
+					propertyReference: hashReference("Studio étudiant proche campus de Beaulieu."),
+					description:       "Studio étudiant proche campus de Beaulieu.",
+				},
+			},
+		},
+		{
+			agency:   CAImmobilier,
+			fixtures: "caimmobilier",
+			wantItems: []string{
+				"https://www.ca-immobilier.fr/louer/bien-appartement/rennes-35000/24815967",
+				"https://www.ca-immobilier.fr/louer/bien-maison/thorigne-fouillard-35235/24816033?origin=list",
+			},
+			want: []Announcement{
+				{
+					propertyReference: "24815967",
+					url:               "https://www.ca-immobilier.fr/louer/bien-appartement/rennes-35000/24815967",
+				},
+				{
+					propertyReference: "24816033",
+					url:               "https://www.ca-immobilier.fr/louer/bien-maison/thorigne-fouillard-35235/24816033?origin=list",
+					legacyReferences:  []string{"24816033?origin=list"},
+				},
+			},
+		},
+		{
+			agency:   PigeaultImmobilier,
+			fixtures: "pigeault",
+			wantItems: []string{
+				"https://www.pigeault-immobilier.fr/annonce/location-appartement-t2-rennes-4471/",
+				"https://www.pigeault-immobilier.fr/annonce/location-maison-t4-vern-sur-seiche-4480/",
+			},
+			want: []Announcement{{
+				propertyReference: "4471",
+				url:               server.URL + "/pigeault/detail.html",
+				title:             "Appartement T2 Rennes Saint-Hélier",
+				description:       "Appartement au 1er étage avec ascenseur, quartier Saint-Hélier.",
+				rent:              pointer(625.0),
+				charges:           pointer(30.0),
+				surface:           pointer(46.5),
+				rooms:             pointer(2),
+				bedrooms:          pointer(1),
+				city:              "Rennes",
+				postcode:          "35000",
+				photoURLs:         []string{server.URL + "/wp-content/uploads/4471-1.jpg"},
+			}},
+		},
+		{
+			agency:   LaForetImmobilier,
+			fixtures: "laforet",
+			wantItems: []string{
+				server.URL + "/agence-immobiliere/rennes/location/appartement/rennes-35000/1457720",
+				server.URL + "/agence-immobiliere/rennes/location/appartement/rennes-35000/1457781",
+			},
+			want: []Announcement{{
+				propertyReference: "LAF-1457720",
+				url:               server.URL + "/laforet/detail.html",
+				title:             "Appartement T3 Rennes",
+				description:       "Grand T3 de 71 m² avec 2 chambres, 35000 Rennes, meublé.",
+				rent:              pointer(910.0),
+				surface:           pointer(71.0),
+				rooms:             pointer(3),
+				bedrooms:          pointer(2),
+				city:              "Rennes",
+				postcode:          "35000",
+				furnished:         pointer(true),
+				energyClass:       "C",
+				photoURLs:         []string{"https://media.laforet.com/1457720/1.jpg"},
+				legacyReferences:  []string{"Web: LAF-1457720, Agence: RE-2231"},
+			}},
+		},
+		{
+			agency:   Cogir,
+			fixtures: "cogir",
+			wantItems: []string{
+				"https://www.cogir.fr/location/appartement-rennes-t2-loc1185",
+				"https://www.cogir.fr/location/appartement-saint-gregoire-t3-loc1192",
+			},
+			want: []Announcement{{
+				propertyReference: "LOC1185",
+				url:               server.URL + "/cogir/detail.html",
+				title:             "Appartement T2 Rennes",
+				description:       "Appartement au calme, cuisine aménagée, cave.",
+				rent:              pointer(560.0),
+				surface:           pointer(42.0),
+				rooms:             pointer(2),
+				city:              "Rennes",
+				postcode:          "35000",
+				photoURLs:         []string{server.URL + "/photos/loc1185/1.jpg"},
+			}},
+		},
+	}
+
+	// Chaque agence enregistrée a ses fixtures
+	if len(tests) != len(RegisteredAgencies()) {
+		t.Errorf("%d agences testées, %d enregistrées", len(tests), len(RegisteredAgencies()))
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.agency), func(t *testing.T) {
+			scraper, err := GetScraper(test.agency)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			listingItems := scrapeListingFixture(t, scraper, server.URL+"/"+test.fixtures+"/listing.html")
+			if !reflect.DeepEqual(listingItems, test.wantItems) {
+				t.Errorf("éléments de la page de résultats :\nobtenu  %q\nattendu %q", listingItems, test.wantItems)
+			}
+
+			var announcements []Announcement
+			if scraper.HasDetailPages() {
+				announcements = scrapeDetailFixture(t, scraper, server.URL+"/"+test.fixtures+"/detail.html")
+			} else {
+				for _, listingItem := range listingItems {
+					announcements = append(announcements, scraper.DeriveAnnouncement(listingItem))
+				}
+			}
+
+			if len(announcements) != len(test.want) {
+				t.Fatalf("annonces = %d, attendu %d", len(announcements), len(test.want))
+			}
+			for i := range test.want {
+				if got, want := announcements[i], test.want[i]; !reflect.DeepEqual(got.Data(), want.Data()) || !reflect.DeepEqual(got.legacyReferences, want.legacyReferences) {
+					t.Errorf("annonce %d :\nobtenu  %+v (anciennes références %q)\nattendu %+v (anciennes références %q)",
+						i, got.Data(), got.legacyReferences, want.Data(), want.legacyReferences)
+				}
+			}
+		})
+	}
+}
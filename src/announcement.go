@@ -0,0 +1,523 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+/**
+ * Announcement est une structure pour stocker les informations sur les annonces de bien immobilier.
+ * Les champs numériques et booléens sont des pointeurs : nil signifie que l'agence n'expose pas l'information.
+ * @property {string} propertyReference - Référence du bien immobilier.
+ * @property {string} url - URL de la page de détails de l'annonce.
+ * @property {string} title - Titre de l'annonce.
+ * @property {string} description - Description de l'annonce.
+ * @property {*float64} rent - Loyer mensuel en euros.
+ * @property {*float64} charges - Charges mensuelles en euros.
+ * @property {*float64} surface - Surface habitable en m².
+ * @property {*int} rooms - Nombre de pièces.
+ * @property {*int} bedrooms - Nombre de chambres.
+ * @property {string} city - Ville du bien.
+ * @property {string} postcode - Code postal du bien.
+ * @property {*bool} furnished - Bien meublé ou non.
+ * @property {string} energyClass - Classe énergie (DPE), de A à G.
+ * @property {string} availableDate - Date de disponibilité, telle qu'affichée par l'agence.
+ * @property {[]string} photoURLs - URLs absolues des photos.
+ * @property {[]string} legacyReferences - Références de l'annonce dans les versions précédentes, reprises par le stockage.
+ */
+type Announcement struct {
+	propertyReference string
+	url               string
+	title             string
+	description       string
+	rent              *float64
+	charges           *float64
+	surface           *float64
+	rooms             *int
+	bedrooms          *int
+	city              string
+	postcode          string
+	furnished         *bool
+	energyClass       string
+	availableDate     string
+	photoURLs         []string
+	legacyReferences  []string
+}
+
+/**
+ * AnnouncementData est la représentation sérialisable d'une annonce (webhooks, exports).
+ */
+type AnnouncementData struct {
+	Reference     string   `json:"reference"`
+	URL           string   `json:"url,omitempty"`
+	Title         string   `json:"title,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Rent          *float64 `json:"rent,omitempty"`
+	Charges       *float64 `json:"charges,omitempty"`
+	Surface       *float64 `json:"surface,omitempty"`
+	Rooms         *int     `json:"rooms,omitempty"`
+	Bedrooms      *int     `json:"bedrooms,omitempty"`
+	City          string   `json:"city,omitempty"`
+	Postcode      string   `json:"postcode,omitempty"`
+	Furnished     *bool    `json:"furnished,omitempty"`
+	EnergyClass   string   `json:"energyClass,omitempty"`
+	AvailableDate string   `json:"availableDate,omitempty"`
+	PhotoURLs     []string `json:"photoURLs,omitempty"`
+}
+
+/**
+ * Data retourne la représentation sérialisable de l'annonce.
+ * @return {AnnouncementData} - Les données de l'annonce.
+ */
+func (announcement Announcement) Data() AnnouncementData {
+	return AnnouncementData{
+		Reference:     announcement.propertyReference,
+		URL:           announcement.url,
+		Title:         announcement.title,
+		Description:   announcement.description,
+		Rent:          announcement.rent,
+		Charges:       announcement.charges,
+		Surface:       announcement.surface,
+		Rooms:         announcement.rooms,
+		Bedrooms:      announcement.bedrooms,
+		City:          announcement.city,
+		Postcode:      announcement.postcode,
+		Furnished:     announcement.furnished,
+		EnergyClass:   announcement.energyClass,
+		AvailableDate: announcement.availableDate,
+		PhotoURLs:     announcement.photoURLs,
+	}
+}
+
+/**
+ * formatAnnouncementMessage construit le texte de notification d'une annonce.
+ * Seules les informations exposées par l'agence apparaissent dans le message.
+ * @param {string} titleMessage - Le titre de la recherche.
+ * @param {string} headline - L'objet de la notification (ex : "Nouvelle annonce immobilière !").
+ * @param {Announcement} announcement - L'annonce à présenter.
+ * @return {string} - Le texte du message.
+ */
+func formatAnnouncementMessage(titleMessage string, headline string, announcement Announcement) string {
+	lines := []string{titleMessage, headline}
+
+	if announcement.title != "" {
+		lines = append(lines, announcement.title)
+	}
+
+	if announcement.rent != nil {
+		rent := fmt.Sprintf("Loyer : %s €", formatNumber(*announcement.rent))
+		if announcement.charges != nil {
+			rent += fmt.Sprintf(" (dont %s € de charges)", formatNumber(*announcement.charges))
+		}
+		lines = append(lines, rent)
+	}
+
+	var layout []string
+	if announcement.surface != nil {
+		layout = append(layout, fmt.Sprintf("%s m²", formatNumber(*announcement.surface)))
+	}
+	if announcement.rooms != nil {
+		layout = append(layout, fmt.Sprintf("%d pièce(s)", *announcement.rooms))
+	}
+	if announcement.bedrooms != nil {
+		layout = append(layout, fmt.Sprintf("%d chambre(s)", *announcement.bedrooms))
+	}
+	if len(layout) > 0 {
+		lines = append(lines, "Surface : "+strings.Join(layout, " - "))
+	}
+
+	if announcement.city != "" || announcement.postcode != "" {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("Ville : %s %s", announcement.postcode, announcement.city)))
+	}
+	if announcement.furnished != nil {
+		if *announcement.furnished {
+			lines = append(lines, "Meublé : oui")
+		} else {
+			lines = append(lines, "Meublé : non")
+		}
+	}
+	if announcement.energyClass != "" {
+		lines = append(lines, "DPE : "+announcement.energyClass)
+	}
+	if announcement.availableDate != "" {
+		lines = append(lines, "Disponible : "+announcement.availableDate)
+	}
+
+	lines = append(lines, "Référence : "+announcement.propertyReference)
+	if announcement.url != "" {
+		lines = append(lines, "URL : "+announcement.url)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+/**
+ * formatNumber affiche un nombre sans décimales inutiles (ex : 45 ou 45.5).
+ * @param {float64} value - Le nombre à afficher.
+ * @return {string} - Le nombre formaté.
+ */
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+/**
+ * detailSelectors décrit où trouver les informations d'une annonce sur une page de détail.
+ * Les sélecteurs vides sont ignorés ; les informations non trouvées par sélecteur sont recherchées
+ * dans le texte de la zone features, puis dans les balises meta Open Graph.
+ * @property {string} title - Sélecteur du titre.
+ * @property {string} description - Sélecteur de la description.
+ * @property {string} rent - Sélecteur du loyer.
+ * @property {string} charges - Sélecteur des charges.
+ * @property {string} surface - Sélecteur de la surface.
+ * @property {string} rooms - Sélecteur du nombre de pièces.
+ * @property {string} bedrooms - Sélecteur du nombre de chambres.
+ * @property {string} location - Sélecteur de la localisation (code postal et ville).
+ * @property {string} energyClass - Sélecteur de la classe énergie.
+ * @property {string} features - Sélecteur de la zone de caractéristiques analysée par expressions régulières.
+ * @property {string} photos - Sélecteur des balises <img> des photos.
+ */
+type detailSelectors struct {
+	title       string
+	description string
+	rent        string
+	charges     string
+	surface     string
+	rooms       string
+	bedrooms    string
+	location    string
+	energyClass string
+	features    string
+	photos      string
+}
+
+// Expressions régulières de repli pour extraire les informations du texte d'une page.
+// Un code postal n'est retenu que collé à un nom de ville, pour ne pas le confondre avec un prix ou une référence.
+var (
+	rentPattern          = regexp.MustCompile(`(?i)(?:loyer|prix)[^0-9€]{0,40}?(\d[\d\s.\x{a0}\x{202f}]*(?:,\d+)?)\s*€`)
+	chargesPattern       = regexp.MustCompile(`(?i)(?:charges|provisions? (?:sur|pour) charges)[^0-9€]{0,40}?(\d[\d\s.\x{a0}\x{202f}]*(?:,\d+)?)\s*€`)
+	surfacePattern       = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*m(?:²|2)`)
+	roomsPattern         = regexp.MustCompile(`(?i)(\d+)\s*pi[eè]ces?\b`)
+	roomsTypePattern     = regexp.MustCompile(`\bT(\d)\b`)
+	bedroomsPattern      = regexp.MustCompile(`(?i)(\d+)\s*chambres?\b`)
+	postcodeCityPattern  = regexp.MustCompile(`\b(\d{5})\s+([A-ZÀ-Ÿa-zà-ÿ][A-ZÀ-Ÿa-zà-ÿ' -]+)`)
+	cityPostcodePattern  = regexp.MustCompile(`([A-ZÀ-Ÿa-zà-ÿ][A-ZÀ-Ÿa-zà-ÿ' -]+?)\s*\(?\b(\d{5})\b\)?`)
+	energyClassPattern   = regexp.MustCompile(`(?i:DPE|classe (?:é|e)nerg(?:é|e)tique|classe (?:é|e)nergie|consommation (?:é|e)nerg(?:é|e)tique)\s*[:\-]?\s*\b([A-G])\b`)
+	notFurnishedPattern  = regexp.MustCompile(`(?i)\bnon[ -]meubl|\b(?:location|logement|appartement) vide\b`)
+	furnishedPattern     = regexp.MustCompile(`(?i)\bmeubl(?:é|e)`)
+	availableDatePattern = regexp.MustCompile(`(?i)disponib(?:le|ilit(?:é|e))\s*(?:le|à partir du|au|dès le)?\s*:?\s*(\d{1,2}/\d{1,2}/\d{2,4}|imm(?:é|e)diatement|de suite)`)
+	numberPattern        = regexp.MustCompile(`\d{1,3}(?:[ \x{a0}\x{202f}.]\d{3})+(?:,\d+)?|\d+(?:[.,]\d+)?`)
+	spacesPattern        = regexp.MustCompile(`\s+`)
+)
+
+/**
+ * extractListingDetails complète une annonce avec les informations trouvées sur sa page de détail.
+ * @param {Announcement} announcement - L'annonce à compléter.
+ * @param {colly.HTMLElement} element - L'élément de la page de détail ayant déclenché le callback.
+ * @param {detailSelectors} selectors - Les sélecteurs propres à l'agence.
+ * @return {void}
+ */
+func extractListingDetails(announcement *Announcement, element *colly.HTMLElement, selectors detailSelectors) {
+	// Travailler sur la page entière, pas seulement sur l'élément du callback
+	page := element.DOM.Parents().Last()
+	if page.Length() == 0 {
+		page = element.DOM
+	}
+
+	selectText := func(selector string) string {
+		if selector == "" {
+			return ""
+		}
+		return cleanText(page.Find(selector).First().Text())
+	}
+
+	// Texte analysé par expressions régulières quand un sélecteur ne donne rien
+	featuresText := selectText(selectors.features)
+	if featuresText == "" {
+		featuresText = cleanText(page.Find("body").Text())
+	}
+
+	announcement.title = firstNonEmpty(selectText(selectors.title), metaContent(page, "og:title"))
+	announcement.description = firstNonEmpty(selectText(selectors.description), metaContent(page, "og:description"))
+
+	announcement.rent = parseFirstFloat(selectText(selectors.rent), featuresText, rentPattern)
+	announcement.charges = parseFirstFloat(selectText(selectors.charges), featuresText, chargesPattern)
+	announcement.surface = parseFirstFloat(selectText(selectors.surface), featuresText, surfacePattern)
+	announcement.rooms = parseFirstInt(selectText(selectors.rooms), featuresText, roomsPattern)
+	if announcement.rooms == nil {
+		announcement.rooms = parseFirstInt("", announcement.title, roomsTypePattern)
+	}
+	announcement.bedrooms = parseFirstInt(selectText(selectors.bedrooms), featuresText, bedroomsPattern)
+
+	announcement.postcode, announcement.city = parseLocation(firstNonEmpty(selectText(selectors.location), announcement.title+" "+featuresText))
+
+	if energyClass := strings.ToUpper(selectText(selectors.energyClass)); len(energyClass) == 1 && energyClass >= "A" && energyClass <= "G" {
+		announcement.energyClass = energyClass
+	} else if match := energyClassPattern.FindStringSubmatch(featuresText); match != nil {
+		announcement.energyClass = strings.ToUpper(match[1])
+	}
+
+	announcement.furnished = parseFurnished(announcement.title + " " + featuresText)
+
+	if match := availableDatePattern.FindStringSubmatch(featuresText); match != nil {
+		announcement.availableDate = match[1]
+	}
+
+	announcement.photoURLs = extractPhotoURLs(page, element.Request, selectors.photos)
+}
+
+/**
+ * extractPhotoURLs retourne les URLs absolues et uniques des photos de l'annonce.
+ * @param {goquery.Selection} page - La page de détail.
+ * @param {colly.Request} request - La requête de la page, pour résoudre les URLs relatives.
+ * @param {string} selector - Sélecteur des balises <img>.
+ * @return {[]string} - Les URLs des photos.
+ */
+func extractPhotoURLs(page *goquery.Selection, request *colly.Request, selector string) []string {
+	var photoURLs []string
+	seen := make(map[string]struct{})
+
+	add := func(src string) {
+		src = strings.TrimSpace(src)
+		if src == "" || strings.HasPrefix(src, "data:") {
+			return
+		}
+		absoluteURL := request.AbsoluteURL(src)
+		if _, exists := seen[absoluteURL]; absoluteURL == "" || exists {
+			return
+		}
+		seen[absoluteURL] = struct{}{}
+		photoURLs = append(photoURLs, absoluteURL)
+	}
+
+	if selector != "" {
+		page.Find(selector).Each(func(_ int, img *goquery.Selection) {
+			// Les images chargées paresseusement gardent leur URL dans data-src ou data-lazy
+			for _, attr := range []string{"data-src", "data-lazy", "data-original", "src"} {
+				if src, exists := img.Attr(attr); exists && src != "" {
+					add(src)
+					return
+				}
+			}
+		})
+	}
+
+	// Repli sur l'image Open Graph
+	if len(photoURLs) == 0 {
+		add(metaContent(page, "og:image"))
+	}
+
+	return photoURLs
+}
+
+/**
+ * metaContent retourne le contenu d'une balise meta Open Graph.
+ * @param {goquery.Selection} page - La page.
+ * @param {string} property - La propriété recherchée (ex : og:title).
+ * @return {string} - Le contenu de la balise, ou une chaîne vide.
+ */
+func metaContent(page *goquery.Selection, property string) string {
+	content, _ := page.Find(fmt.Sprintf("meta[property='%s']", property)).First().Attr("content")
+	return cleanText(content)
+}
+
+/**
+ * parseFirstFloat extrait un nombre décimal du texte sélectionné, sinon du texte de repli via l'expression régulière.
+ * @param {string} selected - Texte issu du sélecteur de l'agence.
+ * @param {string} fallback - Texte de repli.
+ * @param {regexp.Regexp} pattern - Expression régulière dont le premier groupe contient le nombre.
+ * @return {*float64} - Le nombre, ou nil s'il est introuvable.
+ */
+func parseFirstFloat(selected string, fallback string, pattern *regexp.Regexp) *float64 {
+	if value := parseNumber(selected); value != nil {
+		return value
+	}
+	if match := pattern.FindStringSubmatch(fallback); match != nil {
+		return parseNumber(match[1])
+	}
+	return nil
+}
+
+/**
+ * parseFirstInt extrait un entier du texte sélectionné, sinon du texte de repli via l'expression régulière.
+ * @param {string} selected - Texte issu du sélecteur de l'agence.
+ * @param {string} fallback - Texte de repli.
+ * @param {regexp.Regexp} pattern - Expression régulière dont le premier groupe contient l'entier.
+ * @return {*int} - L'entier, ou nil s'il est introuvable.
+ */
+func parseFirstInt(selected string, fallback string, pattern *regexp.Regexp) *int {
+	value := parseFirstFloat(selected, fallback, pattern)
+	if value == nil {
+		return nil
+	}
+	integer := int(*value)
+	return &integer
+}
+
+/**
+ * parseNumber extrait le premier nombre d'un texte au format français (ex : "1 250,50 €" ou "1.250 €").
+ * @param {string} text - Le texte à analyser.
+ * @return {*float64} - Le nombre, ou nil s'il est introuvable.
+ */
+func parseNumber(text string) *float64 {
+	raw := numberPattern.FindString(text)
+	if raw == "" {
+		return nil
+	}
+
+	// Supprimer les espaces séparateurs de milliers
+	raw = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "").Replace(raw)
+
+	if strings.Contains(raw, ",") {
+		// La virgule est le séparateur décimal, les points sont des séparateurs de milliers
+		raw = strings.ReplaceAll(raw, ".", "")
+		raw = strings.Replace(raw, ",", ".", 1)
+		raw = strings.ReplaceAll(raw, ",", "")
+	} else if dot := strings.LastIndex(raw, "."); dot != -1 && len(raw)-dot-1 == 3 {
+		// Un point suivi de trois chiffres est un séparateur de milliers
+		raw = strings.ReplaceAll(raw, ".", "")
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// Mots de liaison d'un nom de ville écrit sans trait d'union (ex : "Saint Aubin du Cormier", "Pont l'Abbé")
+var cityConnectors = map[string]bool{
+	"sur": true, "sous": true, "lès": true, "les": true, "le": true, "la": true, "de": true, "du": true,
+	"des": true, "en": true, "au": true, "aux": true, "d'": true, "l'": true,
+}
+
+// Premiers mots des noms de villes composés écrits sans trait d'union (ex : "Saint Malo", "Le Rheu")
+var cityPrefixes = map[string]bool{
+	"saint": true, "sainte": true, "st": true, "ste": true, "le": true, "la": true, "les": true, "pont": true,
+	"mont": true, "bourg": true, "port": true, "val": true, "notre": true, "grand": true, "petit": true,
+	"vieux": true, "ile": true, "île": true, "chapelle": true, "plessis": true,
+}
+
+// Mots des annonces qui précèdent souvent la ville sans ponctuation (ex : "Location Maison Cesson Sévigné (35510)")
+var locationStopWords = map[string]bool{
+	"appartement": true, "maison": true, "studio": true, "duplex": true, "chambre": true, "chambres": true,
+	"pièce": true, "pièces": true, "location": true, "loyer": true, "charges": true, "surface": true,
+	"disponible": true, "meublé": true, "meublée": true, "parking": true, "garage": true, "terrain": true,
+	"local": true, "bureau": true, "référence": true, "réf": true, "ref": true, "agence": true,
+	"honoraires": true, "proche": true, "quartier": true, "centre": true, "exclusivité": true, "nouveauté": true,
+}
+
+/**
+ * parseLocation extrait le code postal et la ville d'un texte (ex : "35400 Saint-Malo" ou "Cesson Sévigné (35510)").
+ * Le code postal retenu est le premier suivi ou précédé d'un nom de ville.
+ * @param {string} text - Le texte à analyser.
+ * @return {string} - Le code postal.
+ * @return {string} - La ville.
+ */
+func parseLocation(text string) (string, string) {
+	for _, match := range postcodeCityPattern.FindAllStringSubmatch(text, -1) {
+		if city := cityName(strings.Fields(match[2]), false); city != "" {
+			return match[1], city
+		}
+	}
+	for _, match := range cityPostcodePattern.FindAllStringSubmatch(text, -1) {
+		if city := cityName(strings.Fields(match[1]), true); city != "" {
+			return match[2], city
+		}
+	}
+	return "", ""
+}
+
+/**
+ * cityName retient les mots du nom de la ville parmi les mots voisins du code postal. Avant le code postal, les mots
+ * commençant par une majuscule sont retenus jusqu'au texte de l'annonce (ex : "situé à Cesson Sévigné (35510)").
+ * Après le code postal, le texte qui suit commence souvent lui aussi par une majuscule (ex : "35000 Rennes Appartement") :
+ * un mot n'est ajouté qu'après un début de nom composé ("Saint Malo") ou un mot de liaison ("Saint Aubin du Cormier").
+ * Les noms écrits avec des traits d'union forment un seul mot.
+ * @param {[]string} words - Les mots qui suivent (ou précèdent) le code postal.
+ * @param {bool} beforePostcode - true si les mots précèdent le code postal : la ville est lue depuis la fin.
+ * @return {string} - La ville, ou une chaîne vide si le mot voisin du code postal n'en est pas une.
+ */
+func cityName(words []string, beforePostcode bool) string {
+	if beforePostcode {
+		words = append([]string(nil), words...)
+		slices.Reverse(words)
+	}
+
+	var city []string
+	pending := 0
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		if locationStopWords[lower] {
+			break
+		}
+		if i > 0 {
+			if word == lower && cityConnectors[lower] {
+				pending++
+				city = append(city, word)
+				continue
+			}
+			first, _ := utf8.DecodeRuneInString(word)
+			if !unicode.IsUpper(first) || (!beforePostcode && pending == 0 && !cityPrefixes[strings.ToLower(city[len(city)-1])]) {
+				break
+			}
+		}
+		city = append(city, word)
+		pending = 0
+	}
+	// Un mot de liaison n'est retenu que suivi d'un mot de la ville
+	city = city[:len(city)-pending]
+
+	if beforePostcode {
+		slices.Reverse(city)
+	}
+	return strings.Join(city, " ")
+}
+
+/**
+ * parseFurnished détecte si le bien est meublé.
+ * @param {string} text - Le texte à analyser.
+ * @return {*bool} - true si meublé, false si non meublé, nil si l'information est absente.
+ */
+func parseFurnished(text string) *bool {
+	var furnished bool
+	switch {
+	case notFurnishedPattern.MatchString(text):
+		furnished = false
+	case furnishedPattern.MatchString(text):
+		furnished = true
+	default:
+		return nil
+	}
+	return &furnished
+}
+
+/**
+ * cleanText normalise les espaces d'un texte.
+ * @param {string} text - Le texte à nettoyer.
+ * @return {string} - Le texte nettoyé.
+ */
+func cleanText(text string) string {
+	return strings.TrimSpace(spacesPattern.ReplaceAllString(text, " "))
+}
+
+/**
+ * firstNonEmpty retourne la première valeur non vide.
+ * @param {...string} values - Les valeurs candidates.
+ * @return {string} - La première valeur non vide, ou une chaîne vide.
+ */
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		text         string
+		wantPostcode string
+		wantCity     string
+	}{
+		{text: "35000 Rennes", wantPostcode: "35000", wantCity: "Rennes"},
+		{text: "Rennes (35000)", wantPostcode: "35000", wantCity: "Rennes"},
+		{text: "35400 Saint Malo", wantPostcode: "35400", wantCity: "Saint Malo"},
+		{text: "35400 Saint-Malo", wantPostcode: "35400", wantCity: "Saint-Malo"},
+		{text: "Cesson Sévigné (35510)", wantPostcode: "35510", wantCity: "Cesson Sévigné"},
+		{text: "Appartement situé à Cesson Sévigné (35510), proche métro", wantPostcode: "35510", wantCity: "Cesson Sévigné"},
+		{text: "35140 Saint Aubin du Cormier", wantPostcode: "35140", wantCity: "Saint Aubin du Cormier"},
+		{text: "Location Maison Le Rheu 35650", wantPostcode: "35650", wantCity: "Le Rheu"},
+		{text: "75011 Paris", wantPostcode: "75011", wantCity: "Paris"},
+		// Texte de l'annonce collé à la ville, sans ponctuation
+		{text: "Appartement T2 - 35000 Rennes Appartement lumineux au 3e étage", wantPostcode: "35000", wantCity: "Rennes"},
+		{text: "Appartement T2 Rennes 35000 Rennes Livraison 2026", wantPostcode: "35000", wantCity: "Rennes"},
+		// Un nombre de 5 chiffres sans ville n'est pas un code postal
+		{text: "Loyer 12500 € - Réf 35210 Appartement", wantPostcode: "", wantCity: ""},
+		{text: "Appartement lumineux", wantPostcode: "", wantCity: ""},
+	}
+
+	for _, test := range tests {
+		postcode, city := parseLocation(test.text)
+		if postcode != test.wantPostcode || city != test.wantCity {
+			t.Errorf("parseLocation(%q) = %q, %q, attendu %q, %q", test.text, postcode, city, test.wantPostcode, test.wantCity)
+		}
+	}
+}
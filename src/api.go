@@ -0,0 +1,50 @@
+package main
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+/**
+ * isJSONResponse indique si une réponse est un document JSON (API de recherche d'une agence).
+ * @param {colly.Response} response - La réponse reçue.
+ * @return {bool} - true si le type de contenu est JSON.
+ */
+func isJSONResponse(response *colly.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(response.Headers.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+/**
+ * absoluteURL convertit une URL relative d'une réponse d'API en URL absolue.
+ * @param {colly.Request} request - La requête de la réponse, servant de base.
+ * @param {string} u - L'URL à convertir.
+ * @return {string} - L'URL absolue, ou une chaîne vide si l'URL est vide ou invalide.
+ */
+func absoluteURL(request *colly.Request, u string) string {
+	if u = strings.TrimSpace(u); u == "" {
+		return ""
+	}
+	return request.AbsoluteURL(u)
+}
+
+/**
+ * absoluteURLs convertit des URLs relatives en URLs absolues, en ignorant les URLs vides.
+ * @param {colly.Request} request - La requête de la réponse, servant de base.
+ * @param {[]string} urls - Les URLs à convertir.
+ * @return {[]string} - Les URLs absolues.
+ */
+func absoluteURLs(request *colly.Request, urls []string) []string {
+	var result []string
+	for _, u := range urls {
+		if absolute := absoluteURL(request, u); absolute != "" {
+			result = append(result, absolute)
+		}
+	}
+	return result
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Nombre de cycles enregistrés conservés dans le dossier des cassettes : les plus anciens sont supprimés
+const cassetteMaxCycles = 50
+
+/**
+ * Cassette enregistre les requêtes HTTP des collecteurs et leurs réponses dans un dossier, ou les rejoue hors ligne
+ * pour reproduire un scraping à l'identique.
+ * @property {string} dir - Dossier de la cassette (en enregistrement : dossier contenant une cassette par cycle).
+ * @property {bool} replay - true pour rejouer les réponses enregistrées au lieu d'interroger les sites.
+ */
+type Cassette struct {
+	dir    string
+	replay bool
+}
+
+/**
+ * cassetteEntry décrit une réponse enregistrée ; le corps est écrit tel quel dans un fichier voisin (.body).
+ * @property {string} Method - Méthode de la requête.
+ * @property {string} URL - URL de la requête, sans le paramètre anti-cache.
+ * @property {int} Status - Code HTTP de la réponse.
+ * @property {http.Header} Header - En-têtes de la réponse.
+ * @property {time.Time} RecordedAt - Date de l'enregistrement.
+ */
+type cassetteEntry struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header"`
+	RecordedAt time.Time   `json:"recorded_at"`
+}
+
+/**
+ * RecordCassette crée une cassette enregistrant chaque cycle de scraping dans un sous-dossier de dir.
+ * @param {string} dir - Dossier des cassettes, créé si nécessaire.
+ * @return {Cassette} - La cassette.
+ * @return {error} - Erreur si le dossier ne peut pas être créé.
+ */
+func RecordCassette(dir string) (*Cassette, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("création du dossier des cassettes : %w", err)
+	}
+	return &Cassette{dir: dir}, nil
+}
+
+/**
+ * ReplayCassette ouvre une cassette enregistrée pour la rejouer : aucune requête ne sort vers les sites des agences.
+ * @param {string} dir - Dossier de la cassette d'un cycle.
+ * @return {Cassette} - La cassette.
+ * @return {error} - Erreur si le dossier n'existe pas.
+ */
+func ReplayCassette(dir string) (*Cassette, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ouverture de la cassette : %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("ouverture de la cassette : %s n'est pas un dossier", dir)
+	}
+	return &Cassette{dir: dir, replay: true}, nil
+}
+
+/**
+ * ForCycle retourne la cassette d'un cycle de scraping. En enregistrement, chaque cycle a son propre sous-dossier,
+ * nommé d'après la date et l'identifiant du cycle (champ cycle des journaux) ; les plus anciens sont supprimés.
+ * @param {string} cycleID - Identifiant du cycle.
+ * @return {Cassette} - La cassette du cycle.
+ */
+func (cassette *Cassette) ForCycle(cycleID string) *Cassette {
+	if cassette.replay {
+		return cassette
+	}
+	cassette.prune(cassetteMaxCycles - 1)
+	return &Cassette{dir: filepath.Join(cassette.dir, time.Now().Format("20060102-150405")+"-"+cycleID)}
+}
+
+/**
+ * prune supprime les cassettes des cycles les plus anciens.
+ * @param {int} keep - Nombre de cassettes conservées.
+ * @return {void}
+ */
+func (cassette *Cassette) prune(keep int) {
+	entries, err := os.ReadDir(cassette.dir)
+	if err != nil {
+		cassetteLog.Error("Erreur lors de la lecture du dossier des cassettes", "dir", cassette.dir, "error", err)
+		return
+	}
+
+	var cycles []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			cycles = append(cycles, entry.Name())
+		}
+	}
+	// Les noms commencent par la date du cycle : l'ordre alphabétique est l'ordre chronologique
+	sort.Strings(cycles)
+	for len(cycles) > keep {
+		if err := os.RemoveAll(filepath.Join(cassette.dir, cycles[0])); err != nil {
+			cassetteLog.Error("Erreur lors de la suppression d'une cassette", "cycle", cycles[0], "error", err)
+		}
+		cycles = cycles[1:]
+	}
+}
+
+/**
+ * wrap retourne un transport HTTP enregistrant les réponses de base, ou les rejouant sans appeler base.
+ * @param {http.RoundTripper} base - Le transport HTTP réel.
+ * @return {http.RoundTripper} - Le transport de la cassette.
+ */
+func (cassette *Cassette) wrap(base http.RoundTripper) http.RoundTripper {
+	return &cassetteTransport{cassette: cassette, base: base}
+}
+
+/**
+ * cassetteTransport est le transport HTTP d'une cassette.
+ * @property {Cassette} cassette - La cassette.
+ * @property {http.RoundTripper} base - Le transport HTTP réel (inutilisé en relecture).
+ */
+type cassetteTransport struct {
+	cassette *Cassette
+	base     http.RoundTripper
+}
+
+func (transport *cassetteTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	key, err := cassetteKey(request)
+	if err != nil {
+		return nil, err
+	}
+	if transport.cassette.replay {
+		return transport.cassette.load(request, key)
+	}
+
+	response, err := transport.base.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lire le corps pour l'enregistrer, puis le restituer au collecteur
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := cassetteEntry{
+		Method:     request.Method,
+		URL:        cassetteURL(request),
+		Status:     response.StatusCode,
+		Header:     response.Header,
+		RecordedAt: time.Now(),
+	}
+	if err := transport.cassette.save(key, entry, body); err != nil {
+		cassetteLog.ErrorContext(request.Context(), "Erreur lors de l'enregistrement de la réponse dans la cassette", "page", entry.URL, "error", err)
+	}
+	return response, nil
+}
+
+/**
+ * save écrit une réponse dans la cassette : description (.json) et corps (.body).
+ * @param {string} key - Clé de la requête.
+ * @param {cassetteEntry} entry - Description de la réponse.
+ * @param {[]byte} body - Corps de la réponse.
+ * @return {error} - Erreur lors de l'écriture.
+ */
+func (cassette *Cassette) save(key string, entry cassetteEntry, body []byte) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encodage de la réponse : %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(cassette.dir, key+".body"), body); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cassette.dir, key+".json"), data)
+}
+
+/**
+ * load relit une réponse enregistrée.
+ * @param {http.Request} request - La requête rejouée.
+ * @param {string} key - Clé de la requête.
+ * @return {http.Response} - La réponse enregistrée.
+ * @return {error} - Erreur si la requête n'a pas été enregistrée.
+ */
+func (cassette *Cassette) load(request *http.Request, key string) (*http.Response, error) {
+	data, err := os.ReadFile(filepath.Join(cassette.dir, key+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("requête absente de la cassette : %s %s", request.Method, cassetteURL(request))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lecture de la cassette : %w", err)
+	}
+	var entry cassetteEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("décodage de la cassette (%s) : %w", key, err)
+	}
+	body, err := os.ReadFile(filepath.Join(cassette.dir, key+".body"))
+	if err != nil {
+		return nil, fmt.Errorf("lecture de la cassette : %w", err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+/**
+ * cassetteURL retourne l'URL d'une requête sans le paramètre anti-cache (_) ajouté par les collecteurs,
+ * pour qu'une requête rejouée retrouve la réponse enregistrée.
+ * @param {http.Request} request - La requête.
+ * @return {string} - L'URL stable de la requête.
+ */
+func cassetteURL(request *http.Request) string {
+	stable := withoutCacheBuster(request.URL)
+	stable.Fragment = ""
+	return stable.String()
+}
+
+/**
+ * cassetteKey calcule le nom des fichiers d'une requête dans la cassette : empreinte de la méthode, de l'URL stable
+ * et du corps de la requête (API interrogées en POST).
+ * @param {http.Request} request - La requête ; son corps est lu puis restitué.
+ * @return {string} - La clé de la requête.
+ * @return {error} - Erreur lors de la lecture du corps de la requête.
+ */
+func cassetteKey(request *http.Request) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", request.Method, cassetteURL(request))
+	if request.Body != nil && request.Body != http.NoBody {
+		body, err := io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("lecture du corps de la requête : %w", err)
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		hash.Write(body)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:24], nil
+}
+
+// Clé du contexte portant la cassette des collecteurs
+type cassetteContextKey struct{}
+
+/**
+ * withCassette retourne un contexte dont les collecteurs enregistrent ou rejouent leurs requêtes avec la cassette.
+ * @param {context.Context} ctx - Le contexte parent.
+ * @param {Cassette} cassette - La cassette.
+ * @return {context.Context} - Le contexte portant la cassette.
+ */
+func withCassette(ctx context.Context, cassette *Cassette) context.Context {
+	return context.WithValue(ctx, cassetteContextKey{}, cassette)
+}
+
+/**
+ * cassetteFromContext retourne la cassette portée par le contexte.
+ * @param {context.Context} ctx - Le contexte.
+ * @return {Cassette} - La cassette, ou nil si les requêtes partent normalement vers les sites.
+ */
+func cassetteFromContext(ctx context.Context) *Cassette {
+	cassette, _ := ctx.Value(cassetteContextKey{}).(*Cassette)
+	return cassette
+}
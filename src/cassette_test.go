@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	body := readFixture(t, "squarehabitat_search.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(body)
+	}))
+	searchURL := server.URL + "/api/recherche?page=1"
+
+	scrape := func(cassette *Cassette) ([]Announcement, ScrapeStats) {
+		t.Helper()
+		collyService := NewCollyService()
+		announcements, err := collyService.ScrapeAnnouncement(withCassette(context.Background(), cassette), SquareHabitat, searchURL, 5)
+		if err != nil {
+			t.Fatalf("ScrapeAnnouncement : %v", err)
+		}
+		return announcements, collyService.Stats()
+	}
+
+	// Enregistrer le cycle, puis couper le site : la relecture ne doit plus l'interroger
+	dir := t.TempDir()
+	recorder, err := RecordCassette(dir)
+	if err != nil {
+		t.Fatalf("RecordCassette : %v", err)
+	}
+	recorded, _ := scrape(recorder.ForCycle("0000cafe"))
+	server.Close()
+
+	cycles, err := os.ReadDir(dir)
+	if err != nil || len(cycles) != 1 {
+		t.Fatalf("cassettes enregistrées = %v (%v), attendu une cassette", cycles, err)
+	}
+	player, err := ReplayCassette(filepath.Join(dir, cycles[0].Name()))
+	if err != nil {
+		t.Fatalf("ReplayCassette : %v", err)
+	}
+	replayed, stats := scrape(player)
+
+	if stats.Errors != 0 {
+		t.Errorf("erreurs en relecture = %d, attendu 0", stats.Errors)
+	}
+	if len(replayed) != len(recorded) || len(replayed) == 0 {
+		t.Fatalf("annonces rejouées = %d, enregistrées = %d", len(replayed), len(recorded))
+	}
+	for i := range recorded {
+		if got, want := replayed[i].Data(), recorded[i].Data(); !reflect.DeepEqual(got, want) {
+			t.Errorf("annonce %d :\nrejouée     %+v\nenregistrée %+v", i, got, want)
+		}
+	}
+
+	// Une requête absente de la cassette est une erreur du scraping, sans appel au site
+	searchURL = server.URL + "/api/recherche?page=2"
+	if _, stats := scrape(player); stats.Errors == 0 {
+		t.Error("une requête absente de la cassette doit être comptée en erreur")
+	}
+}
+
+func TestCassetteURL(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "https://www.afedim.fr/fr/location?ville=rennes&_=1731000000000000000#liste", nil)
+	if got, want := cassetteURL(request), "https://www.afedim.fr/fr/location?ville=rennes"; got != want {
+		t.Errorf("cassetteURL = %q, attendu %q", got, want)
+	}
+}
@@ -0,0 +1,713 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Erreur d'utilisation de la ligne de commande (commande, options ou arguments invalides) : code de sortie 2
+var errUsage = errors.New("utilisation invalide")
+
+/**
+ * cliOptions regroupe les options globales de la ligne de commande.
+ * @property {string} configPath - Chemin du fichier de configuration.
+ * @property {bool} dryRun - true pour simuler : notifications non envoyées, aucun état réécrit sur disque.
+ * @property {string} dryRunOutput - Fichier où écrire les notifications simulées, vide pour la sortie standard.
+ * @property {string} logLevel - Niveau des journaux, prioritaire sur settings.log.level.
+ * @property {io.Writer} stdout - Sortie des commandes (annonces, références, export).
+ * @property {io.Writer} stderr - Sortie de l'aide et des erreurs d'utilisation.
+ */
+type cliOptions struct {
+	configPath   string
+	dryRun       bool
+	dryRunOutput string
+	logLevel     string
+	stdout       io.Writer
+	stderr       io.Writer
+}
+
+/**
+ * cliCommand décrit une commande du scraper.
+ * @property {string} name - Nom de la commande.
+ * @property {string} args - Options et arguments, pour l'aide.
+ * @property {string} summary - Description de la commande, pour l'aide.
+ * @property {func} run - Exécution de la commande avec les arguments suivant son nom.
+ */
+type cliCommand struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, options *cliOptions, args []string) error
+}
+
+// Commandes du scraper, dans l'ordre de l'aide (renseignées dans init : l'aide de chaque commande lit cette liste)
+var cliCommands []cliCommand
+
+func init() {
+	cliCommands = []cliCommand{
+		{name: "run", args: "[-record <dossier>]", summary: "Scraper en continu selon le calendrier des recherches (commande par défaut)", run: runCommand},
+		{name: "once", args: "[-record <dossier>] [-replay <cassette>]", summary: "Scraper une fois toutes les recherches actives, puis s'arrêter (tâches cron)", run: onceCommand},
+		{name: "check", args: "[-url <url>] [-max-pages <n>] [-replay <cassette>] <agence>", summary: "Scraper une recherche et afficher les annonces extraites, sans notification ni enregistrement", run: checkCommand},
+		{name: "list-agencies", summary: "Lister les agences disponibles", run: listAgenciesCommand},
+		{name: "seen", args: "[-agency <agence>] [-forget <référence> | -clear]", summary: "Afficher ou supprimer les références déjà traitées", run: seenCommand},
+		{name: "export", args: "[-agency <agence>] [-format csv|json] [-output <fichier>]", summary: "Exporter les références déjà traitées", run: exportCommand},
+	}
+}
+
+/**
+ * runCLI analyse la ligne de commande et exécute la commande demandée (run sans commande).
+ * @param {context.Context} ctx - Contexte racine, annulé à la réception de SIGINT ou SIGTERM.
+ * @param {[]string} args - Les arguments, sans le nom du programme.
+ * @param {io.Writer} stdout - Sortie des commandes.
+ * @param {io.Writer} stderr - Sortie de l'aide et des erreurs d'utilisation.
+ * @return {error} - Erreur de la commande ; errUsage ou flag.ErrHelp si l'aide a été affichée.
+ */
+func runCLI(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+	options := &cliOptions{configPath: getEnv("SCRAPER_CONFIG", "config.yaml"), stdout: stdout, stderr: stderr}
+
+	flags := flag.NewFlagSet("agency-scraper", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	options.register(flags)
+	flags.Usage = func() {
+		output := flags.Output()
+		fmt.Fprintln(output, "Utilisation : agency-scraper [options] [commande] [arguments]")
+		fmt.Fprintln(output, "\nCommandes :")
+		table := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+		for _, command := range cliCommands {
+			fmt.Fprintf(table, "  %s\t%s\n", command.name, command.summary)
+		}
+		table.Flush()
+		fmt.Fprintln(output, "\nOptions globales (acceptées avant ou après la commande) :")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return flagError(err)
+	}
+
+	// Sans commande, le scraper tourne en continu (images et déploiements existants)
+	name, rest := "run", flags.Args()
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+	for _, command := range cliCommands {
+		if command.name == name {
+			return command.run(ctx, options, rest)
+		}
+	}
+	return usageFailure(flags, "Commande inconnue : %s", name)
+}
+
+/**
+ * register ajoute les options globales à un jeu d'options.
+ * @param {flag.FlagSet} flags - Le jeu d'options (programme ou commande).
+ * @return {void}
+ */
+func (options *cliOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&options.configPath, "config", options.configPath, "Chemin du fichier de configuration (variable SCRAPER_CONFIG)")
+	flags.BoolVar(&options.dryRun, "dry-run", options.dryRun, "Simuler : notifications affichées au lieu d'être envoyées, références et biens non enregistrés")
+	flags.StringVar(&options.dryRunOutput, "dry-run-output", options.dryRunOutput, "Fichier où ajouter les notifications simulées (sortie standard par défaut, avec -dry-run)")
+	flags.StringVar(&options.logLevel, "log-level", options.logLevel, "Niveau des journaux : debug, info, warn ou error (prioritaire sur settings.log.level)")
+}
+
+/**
+ * newCommandFlags crée le jeu d'options d'une commande, options globales comprises.
+ * @param {string} name - Nom de la commande.
+ * @param {cliOptions} options - Les options globales.
+ * @return {flag.FlagSet} - Le jeu d'options.
+ */
+func (options *cliOptions) newCommandFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("agency-scraper "+name, flag.ContinueOnError)
+	flags.SetOutput(options.stderr)
+	options.register(flags)
+	flags.Usage = func() {
+		for _, command := range cliCommands {
+			if command.name == name {
+				fmt.Fprintf(flags.Output(), "Utilisation : agency-scraper %s %s\n%s\n\nOptions :\n", name, command.args, command.summary)
+			}
+		}
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+/**
+ * parseCommandFlags analyse les options d'une commande, placées avant ou après ses arguments.
+ * @param {flag.FlagSet} flags - Le jeu d'options de la commande.
+ * @param {[]string} args - Les arguments de la commande.
+ * @return {[]string} - Les arguments hors options.
+ * @return {error} - errUsage ou flag.ErrHelp si l'aide a été affichée.
+ */
+func parseCommandFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, flagError(err)
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+/**
+ * flagError convertit une erreur d'analyse des options, déjà affichée avec l'aide par le paquet flag.
+ * @param {error} err - L'erreur d'analyse.
+ * @return {error} - flag.ErrHelp si l'aide a été demandée, sinon errUsage.
+ */
+func flagError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return errUsage
+}
+
+/**
+ * usageFailure affiche une erreur d'utilisation suivie de l'aide.
+ * @param {flag.FlagSet} flags - Le jeu d'options dont l'aide est affichée.
+ * @param {string} format - Le message d'erreur.
+ * @param {...any} args - Les valeurs du message.
+ * @return {error} - errUsage.
+ */
+func usageFailure(flags *flag.FlagSet, format string, args ...any) error {
+	fmt.Fprintf(flags.Output(), format+"\n\n", args...)
+	flags.Usage()
+	return errUsage
+}
+
+/**
+ * loadConfig charge la configuration et configure les journaux (niveau -log-level prioritaire).
+ * @return {Config} - La configuration validée.
+ * @return {error} - Erreur si la configuration ou le niveau des journaux est invalide.
+ */
+func (options *cliOptions) loadConfig() (*Config, error) {
+	config, err := LoadConfig(options.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("chargement de la configuration : %w", err)
+	}
+	if options.logLevel != "" {
+		config.Settings.Log.Level = options.logLevel
+	}
+	if err := ConfigureLogging(config.Settings.Log, os.Stderr); err != nil {
+		return nil, fmt.Errorf("configuration des journaux : %w", err)
+	}
+	return config, nil
+}
+
+/**
+ * scraperServices regroupe les services des commandes de scraping.
+ * @property {SeenStore} store - Stockage des références déjà traitées.
+ * @property {PropertyIndex} properties - Biens regroupés par annonces de plusieurs agences, nil si le regroupement est désactivé.
+ * @property {Notifier} notifier - Services de notification des nouvelles annonces.
+ * @property {HealthMonitor} health - Suivi de santé des recherches.
+ * @property {io.Closer} output - Fichier des notifications simulées, nil s'il n'y en a pas.
+ */
+type scraperServices struct {
+	store      *SeenStore
+	properties *PropertyIndex
+	notifier   Notifier
+	health     *HealthMonitor
+	output     io.Closer
+}
+
+/**
+ * Close ferme le fichier des notifications simulées.
+ * @return {error} - Erreur lors de la fermeture.
+ */
+func (services *scraperServices) Close() error {
+	if services.output == nil {
+		return nil
+	}
+	return services.output.Close()
+}
+
+/**
+ * openServices charge les états enregistrés et crée les services de notification.
+ * En simulation, les états ne sont jamais réécrits et aucun service n'est créé : les identifiants ne sont pas nécessaires,
+ * les notifications et les alertes des administrateurs sont écrites sur la sortie standard ou dans -dry-run-output.
+ * @param {Config} config - La configuration.
+ * @return {scraperServices} - Les services.
+ * @return {error} - Erreur si un état ne peut pas être chargé ou si un service ne peut pas être créé.
+ */
+func (options *cliOptions) openServices(config *Config) (*scraperServices, error) {
+	if options.dryRunOutput != "" && !options.dryRun {
+		return nil, errors.New("l'option -dry-run-output nécessite -dry-run")
+	}
+
+	// Charger les références déjà traitées depuis le disque
+	store, err := OpenSeenStore(config.Settings.StatePath)
+	if err != nil {
+		return nil, fmt.Errorf("chargement des références traitées : %w", err)
+	}
+	store.SetSearches(config.Targets)
+
+	// Charger les biens regroupés par annonces de plusieurs agences
+	var properties *PropertyIndex
+	if *config.Settings.Dedup.Enabled {
+		properties, err = OpenPropertyIndex(config.Settings.Dedup.StatePath)
+		if err != nil {
+			return nil, fmt.Errorf("chargement des biens regroupés : %w", err)
+		}
+	}
+
+	if options.dryRun {
+		store.SetReadOnly()
+		if properties != nil {
+			properties.SetReadOnly()
+		}
+		services := &scraperServices{store: store, properties: properties}
+
+		output := options.stdout
+		if options.dryRunOutput != "" {
+			file, err := os.OpenFile(options.dryRunOutput, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("ouverture du fichier des notifications simulées : %w", err)
+			}
+			output, services.output = file, file
+		}
+		services.notifier = NewDryRunNotifier(output, config.Notifiers)
+
+		// Alertes de santé simulées seulement si des services d'administration sont configurés
+		var admin Notifier
+		if len(config.AdminNotifiers) > 0 {
+			admin = NewDryRunNotifier(output, config.AdminNotifiers)
+		}
+		services.health = NewHealthMonitor(config.Settings.Health, store, admin)
+		return services, nil
+	}
+
+	// Les notifications ne démarrent pas sans identifiants valides pour chaque service configuré
+	notifier, err := NewNotifier(config.Notifiers)
+	if err != nil {
+		return nil, fmt.Errorf("initialisation des notifications :\n%w", err)
+	}
+
+	// Alertes de santé du scraper sur les services des administrateurs (journalisées seulement si aucun n'est configuré)
+	var admin Notifier
+	if len(config.AdminNotifiers) > 0 {
+		admin, err = NewNotifier(config.AdminNotifiers)
+		if err != nil {
+			return nil, fmt.Errorf("initialisation des notifications des administrateurs :\n%w", err)
+		}
+	}
+
+	return &scraperServices{
+		store:      store,
+		properties: properties,
+		notifier:   notifier,
+		health:     NewHealthMonitor(config.Settings.Health, store, admin),
+	}, nil
+}
+
+/**
+ * withCassetteFlags ajoute au contexte la cassette demandée par -record ou -replay.
+ * @param {context.Context} ctx - Le contexte des scrapings.
+ * @param {string} recordDir - Dossier des cassettes à enregistrer, vide si aucun.
+ * @param {string} replayDir - Cassette à rejouer, vide si aucune.
+ * @return {context.Context} - Le contexte portant la cassette.
+ * @return {error} - Erreur si la cassette ne peut pas être préparée.
+ */
+func withCassetteFlags(ctx context.Context, recordDir string, replayDir string) (context.Context, error) {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, errors.New("les options -record et -replay ne peuvent pas être utilisées ensemble")
+	case recordDir != "":
+		cassette, err := RecordCassette(recordDir)
+		if err != nil {
+			return nil, err
+		}
+		return withCassette(ctx, cassette), nil
+	case replayDir != "":
+		cassette, err := ReplayCassette(replayDir)
+		if err != nil {
+			return nil, err
+		}
+		return withCassette(ctx, cassette), nil
+	}
+	return ctx, nil
+}
+
+/**
+ * runCommand lance le scraper en continu, avec le serveur HTTP d'exploitation (métriques, sondes).
+ * @param {context.Context} ctx - Contexte racine.
+ * @param {cliOptions} options - Les options globales.
+ * @param {[]string} args - Les arguments de la commande.
+ * @return {error} - Erreur au démarrage ou à l'arrêt du scraper.
+ */
+func runCommand(ctx context.Context, options *cliOptions, args []string) error {
+	flags := options.newCommandFlags("run")
+	recordDir := flags.String("record", "", "Dossier où enregistrer les requêtes et réponses HTTP de chaque cycle (une cassette par cycle)")
+	if positional, err := parseCommandFlags(flags, args); err != nil {
+		return err
+	} else if len(positional) > 0 {
+		return usageFailure(flags, "Argument inattendu : %s", positional[0])
+	}
+
+	config, err := options.loadConfig()
+	if err != nil {
+		return err
+	}
+	services, err := options.openServices(config)
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+	if ctx, err = withCassetteFlags(ctx, *recordDir, ""); err != nil {
+		return err
+	}
+
+	// Serveur HTTP d'exploitation (métriques Prometheus, sondes Kubernetes)
+	probes := NewProbes(config.Settings, services.store, services.notifier)
+	if config.Settings.HTTPAddr != "" {
+		StartHTTPServer(ctx, config.Settings.HTTPAddr, newHTTPHandler(probes))
+	}
+
+	if err := RunScraper(ctx, config, services.store, services.notifier, services.properties, services.health, probes); err != nil {
+		return fmt.Errorf("planification des recherches :\n%w", err)
+	}
+	return nil
+}
+
+/**
+ * onceCommand scrape une fois toutes les recherches actives, éventuellement depuis une cassette, puis s'arrête.
+ * @param {context.Context} ctx - Contexte racine.
+ * @param {cliOptions} options - Les options globales.
+ * @param {[]string} args - Les arguments de la commande.
+ * @return {error} - Erreur du cycle.
+ */
+func onceCommand(ctx context.Context, options *cliOptions, args []string) error {
+	flags := options.newCommandFlags("once")
+	recordDir := flags.String("record", "", "Dossier où enregistrer les requêtes et réponses HTTP du cycle")
+	replayDir := flags.String("replay", "", "Cassette d'un cycle à rejouer hors ligne, à la place des sites des agences")
+	if positional, err := parseCommandFlags(flags, args); err != nil {
+		return err
+	} else if len(positional) > 0 {
+		return usageFailure(flags, "Argument inattendu : %s", positional[0])
+	}
+
+	config, err := options.loadConfig()
+	if err != nil {
+		return err
+	}
+	services, err := options.openServices(config)
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+	if ctx, err = withCassetteFlags(ctx, *recordDir, *replayDir); err != nil {
+		return err
+	}
+
+	return RunCycle(ctx, config, services.store, services.notifier, services.properties, services.health)
+}
+
+/**
+ * checkCommand scrape une recherche d'une agence et affiche les annonces extraites en JSON,
+ * sans notification ni lecture ou écriture des références traitées.
+ * @param {context.Context} ctx - Contexte racine.
+ * @param {cliOptions} options - Les options globales.
+ * @param {[]string} args - Les arguments de la commande : l'agence.
+ * @return {error} - Erreur si l'agence ou la recherche est inconnue, ou si le scraping a échoué.
+ */
+func checkCommand(ctx context.Context, options *cliOptions, args []string) error {
+	flags := options.newCommandFlags("check")
+	targetURL := flags.String("url", "", "URL de la recherche (par défaut, la première recherche configurée pour l'agence)")
+	maxPages := flags.Int("max-pages", 0, "Nombre maximal de pages de résultats visitées (par défaut, celui de la recherche)")
+	replayDir := flags.String("replay", "", "Cassette à rejouer hors ligne, à la place du site de l'agence")
+	positional, err := parseCommandFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageFailure(flags, "Une agence est attendue (voir list-agencies)")
+	}
+	agency := Agency(positional[0])
+
+	// La configuration déclare une partie des agences : elle est chargée avant de chercher le scraper
+	config, err := options.loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := GetScraper(agency); err != nil {
+		return err
+	}
+	target, err := checkTarget(config, agency, *targetURL)
+	if err != nil {
+		return err
+	}
+	if *maxPages > 0 {
+		target.MaxPages = *maxPages
+	}
+	if ctx, err = withCassetteFlags(ctx, "", *replayDir); err != nil {
+		return err
+	}
+
+	ctx = withLogAttrs(ctx, slog.String("agency", string(agency)), slog.String("url", target.URL))
+	collyService := NewCollyService()
+	announcements, err := collyService.ScrapeAnnouncement(ctx, agency, target.URL, target.MaxPages)
+	if err != nil {
+		return fmt.Errorf("scraping de %s : %w", target.URL, err)
+	}
+	stats := collyService.Stats()
+	collectorLog.InfoContext(ctx, "Scraping terminé", "pages", stats.Pages, "truncated", stats.Truncated, "items", stats.Items, "announcements", stats.Announcements, "errors", stats.Errors)
+
+	data := make([]AnnouncementData, 0, len(announcements))
+	for _, announcement := range announcements {
+		data = append(data, announcement.Data())
+	}
+	encoder := json.NewEncoder(options.stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(data)
+}
+
+/**
+ * checkTarget retourne la recherche vérifiée par la commande check.
+ * @param {Config} config - La configuration.
+ * @param {Agency} agency - L'agence.
+ * @param {string} targetURL - URL de la recherche, vide pour la première recherche configurée pour l'agence.
+ * @return {SearchTarget} - La recherche.
+ * @return {error} - Erreur si aucune recherche n'est configurée pour l'agence.
+ */
+func checkTarget(config *Config, agency Agency, targetURL string) (SearchTarget, error) {
+	if targetURL != "" {
+		return SearchTarget{Agency: agency, URL: targetURL, Title: targetURL, MaxPages: config.Settings.MaxPages}, nil
+	}
+	for _, target := range config.Targets {
+		if target.Agency == agency {
+			return target, nil
+		}
+	}
+	return SearchTarget{}, fmt.Errorf("aucune recherche configurée pour l'agence %s : préciser l'URL avec -url", agency)
+}
+
+/**
+ * listAgenciesCommand affiche les agences disponibles, une par ligne : agences intégrées et agences déclarées
+ * dans la configuration.
+ * @param {context.Context} ctx - Contexte racine.
+ * @param {cliOptions} options - Les options globales.
+ * @param {[]string} args - Les arguments de la commande.
+ * @return {error} - Erreur si la configuration est invalide, ou erreur d'écriture.
+ */
+func listAgenciesCommand(_ context.Context, options *cliOptions, args []string) error {
+	flags := options.newCommandFlags("list-agencies")
+	if positional, err := parseCommandFlags(flags, args); err != nil {
+		return err
+	} else if len(positional) > 0 {
+		return usageFailure(flags, "Argument inattendu : %s", positional[0])
+	}
+	if _, err := options.loadConfig(); err != nil {
+		return err
+	}
+
+	for _, agency := range RegisteredAgencies() {
+		if _, err := fmt.Fprintln(options.stdout, agency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * seenCommand affiche les références déjà traitées, ou en supprime : une référence oubliée est de nouveau notifiée,
+ * une agence vidée repasse par un premier scraping sans notification.
+ * @param {context.Context} ctx - Contexte racine.
+ * @param {cliOptions} options - Les options globales.
+ * @param {[]string} args - Les arguments de la commande.
+ * @return {error} - Erreur de lecture ou d'écriture du stockage.
+ */
+func seenCommand(_ context.Context, options *cliOptions, args []string) error {
+	flags := options.newCommandFlags("seen")
+	agency := flags.String("agency", "", "Agence dont les références sont affichées ou supprimées (toutes par défaut)")
+	forget := flags.String("forget", "", "Supprimer cette référence de l'agence : l'annonce sera de nouveau notifiée")
+	clear := flags.Bool("clear", false, "Supprimer toutes les références de l'agence : le prochain scraping les marque comme vues sans notification")
+	if positional, err := parseCommandFlags(flags, args); err != nil {
+		return err
+	} else if len(positional) > 0 {
+		return usageFailure(flags, "Argument inattendu : %s", positional[0])
+	}
+	if *forget != "" && *clear {
+		return usageFailure(flags, "Les options -forget et -clear ne peuvent pas être utilisées ensemble")
+	}
+	if (*forget != "" || *clear) && *agency == "" {
+		return usageFailure(flags, "Les options -forget et -clear nécessitent -agency")
+	}
+
+	config, err := options.loadConfig()
+	if err != nil {
+		return err
+	}
+	store, err := OpenSeenStore(config.Settings.StatePath)
+	if err != nil {
+		return fmt.Errorf("chargement des références traitées : %w", err)
+	}
+	if options.dryRun {
+		store.SetReadOnly()
+	}
+
+	switch {
+	case *forget != "":
+		found := false
+		for _, entry := range store.Entries(Agency(*agency)) {
+			found = found || entry.PropertyReference == *forget
+		}
+		if !found {
+			return fmt.Errorf("référence %s inconnue pour l'agence %s", *forget, *agency)
+		}
+		store.Forget(Agency(*agency), *forget)
+		fmt.Fprintf(options.stdout, "Référence %s de l'agence %s supprimée%s\n", *forget, *agency, dryRunSuffix(options))
+		return store.Save()
+	case *clear:
+		removed := store.Clear(Agency(*agency))
+		fmt.Fprintf(options.stdout, "%d références de l'agence %s supprimées%s\n", removed, *agency, dryRunSuffix(options))
+		return store.Save()
+	}
+
+	entries := store.Entries(Agency(*agency))
+	table := tabwriter.NewWriter(options.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "AGENCE\tRÉFÉRENCE\tPREMIÈRE DÉTECTION\tDERNIÈRE DÉTECTION\tNOTIFIÉE\tRETIRÉE\tURL")
+	for _, entry := range entries {
+		gone := "-"
+		if entry.GoneAt != nil {
+			gone = entry.GoneAt.Local().Format(time.DateTime)
+		}
+		notified := "non"
+		if entry.Notified {
+			notified = "oui"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Agency, entry.PropertyReference,
+			entry.FirstSeen.Local().Format(time.DateTime), entry.LastSeen.Local().Format(time.DateTime), notified, gone, entry.URL)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(options.stdout, "%d références\n", len(entries))
+	return err
+}
+
+/**
+ * dryRunSuffix retourne la précision ajoutée aux messages des modifications simulées.
+ * @param {cliOptions} options - Les options globales.
+ * @return {string} - La précision, vide hors simulation.
+ */
+func dryRunSuffix(options *cliOptions) string {
+	if options.dryRun {
+		return " (simulation : stockage non modifié)"
+	}
+	return ""
+}
+
+/**
+ * exportCommand exporte les références déjà traitées en CSV (une ligne par annonce, dernier état observé)
+ * ou en JSON (historique complet).
+ * @param {context.Context} ctx - Contexte racine.
+ * @param {cliOptions} options - Les options globales.
+ * @param {[]string} args - Les arguments de la commande.
+ * @return {error} - Erreur de lecture du stockage ou d'écriture de l'export.
+ */
+func exportCommand(_ context.Context, options *cliOptions, args []string) error {
+	flags := options.newCommandFlags("export")
+	agency := flags.String("agency", "", "Agence dont les références sont exportées (toutes par défaut)")
+	format := flags.String("format", "csv", "Format de l'export : csv ou json")
+	outputPath := flags.String("output", "", "Fichier de l'export (sortie standard par défaut)")
+	if positional, err := parseCommandFlags(flags, args); err != nil {
+		return err
+	} else if len(positional) > 0 {
+		return usageFailure(flags, "Argument inattendu : %s", positional[0])
+	}
+	if *format != "csv" && *format != "json" {
+		return usageFailure(flags, "Format inconnu : %s (csv ou json)", *format)
+	}
+
+	config, err := options.loadConfig()
+	if err != nil {
+		return err
+	}
+	store, err := OpenSeenStore(config.Settings.StatePath)
+	if err != nil {
+		return fmt.Errorf("chargement des références traitées : %w", err)
+	}
+	entries := store.Entries(Agency(*agency))
+
+	write := func(output io.Writer) error {
+		if *format == "json" {
+			encoder := json.NewEncoder(output)
+			encoder.SetIndent("", "  ")
+			encoder.SetEscapeHTML(false)
+			if entries == nil {
+				entries = []SeenEntry{}
+			}
+			return encoder.Encode(entries)
+		}
+		return writeSeenCSV(output, entries)
+	}
+	if *outputPath == "" {
+		return write(options.stdout)
+	}
+
+	file, err := os.Create(*outputPath)
+	if err != nil {
+		return fmt.Errorf("création de l'export : %w", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("écriture de l'export : %w", err)
+	}
+	return file.Close()
+}
+
+/**
+ * writeSeenCSV écrit les références traitées en CSV, avec le dernier loyer, les dernières charges et la dernière
+ * disponibilité observés.
+ * @param {io.Writer} output - La sortie de l'export.
+ * @param {[]SeenEntry} entries - Les références.
+ * @return {error} - Erreur d'écriture.
+ */
+func writeSeenCSV(output io.Writer, entries []SeenEntry) error {
+	writer := csv.NewWriter(output)
+	_ = writer.Write([]string{"agency", "reference", "url", "search", "first_seen", "last_seen", "notified", "gone_at", "rent", "charges", "available_date"})
+
+	formatAmount := func(amount *float64) string {
+		if amount == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*amount, 'f', -1, 64)
+	}
+	for _, entry := range entries {
+		var last Observation
+		if len(entry.History) > 0 {
+			last = entry.History[len(entry.History)-1]
+		}
+		goneAt := ""
+		if entry.GoneAt != nil {
+			goneAt = entry.GoneAt.Format(time.RFC3339)
+		}
+		_ = writer.Write([]string{
+			string(entry.Agency),
+			entry.PropertyReference,
+			entry.URL,
+			entry.Search,
+			entry.FirstSeen.Format(time.RFC3339),
+			entry.LastSeen.Format(time.RFC3339),
+			strconv.FormatBool(entry.Notified),
+			goneAt,
+			formatAmount(last.Rent),
+			formatAmount(last.Charges),
+			last.AvailableDate,
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
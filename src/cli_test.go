@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCommandFlags(t *testing.T) {
+	options := &cliOptions{configPath: "config.yaml", stderr: io.Discard}
+	flags := options.newCommandFlags("check")
+	targetURL := flags.String("url", "", "")
+
+	// Les options sont acceptées avant comme après l'agence, options globales comprises
+	positional, err := parseCommandFlags(flags, []string{"-url", "https://www.afedim.fr/", "Afedim", "-dry-run", "-config", "test.yaml"})
+	if err != nil {
+		t.Fatalf("parseCommandFlags : %v", err)
+	}
+	if want := []string{"Afedim"}; !reflect.DeepEqual(positional, want) {
+		t.Errorf("arguments = %v, attendu %v", positional, want)
+	}
+	if *targetURL != "https://www.afedim.fr/" || !options.dryRun || options.configPath != "test.yaml" {
+		t.Errorf("options = url %q, dry-run %v, config %q", *targetURL, options.dryRun, options.configPath)
+	}
+}
+
+func TestRunCLIUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "commande inconnue", args: []string{"scrape"}},
+		{name: "option inconnue", args: []string{"once", "-replay-dir", "cassettes"}},
+		{name: "agence manquante", args: []string{"check"}},
+		{name: "argument inattendu", args: []string{"list-agencies", "Afedim"}},
+		{name: "forget sans agence", args: []string{"seen", "-forget", "A1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := runCLI(context.Background(), test.args, io.Discard, io.Discard); !errors.Is(err, errUsage) {
+				t.Errorf("erreur = %v, attendu errUsage", err)
+			}
+		})
+	}
+}
+
+func TestListAgenciesCommand(t *testing.T) {
+	// Les agences déclarées dans la configuration sont listées avec les agences intégrées
+	configPath := writeConfig(t, guennoDefinitionYAML)
+	builtIn := len(RegisteredAgencies())
+
+	var output bytes.Buffer
+	if err := runCLI(context.Background(), []string{"list-agencies", "-config", configPath}, &output, io.Discard); err != nil {
+		t.Fatalf("list-agencies : %v", err)
+	}
+	if lines := bytes.Count(output.Bytes(), []byte("\n")); lines != builtIn+1 {
+		t.Errorf("%d agences affichées, attendu %d", lines, builtIn+1)
+	}
+	if !strings.Contains(output.String(), "Guenno déclarée\n") {
+		t.Errorf("agence déclarée absente de la liste :\n%s", output.String())
+	}
+}
+
+func TestOpenServicesDryRun(t *testing.T) {
+	dir := t.TempDir()
+	disabled := false
+	config := &Config{
+		Settings:  Settings{StatePath: filepath.Join(dir, "seen.json"), Dedup: DedupSettings{Enabled: &disabled}},
+		Notifiers: []NotifierConfig{{Type: "telegram"}},
+	}
+
+	// Aucun identifiant Telegram n'est nécessaire en simulation
+	var output bytes.Buffer
+	options := &cliOptions{dryRun: true, stdout: &output, stderr: io.Discard}
+	services, err := options.openServices(config)
+	if err != nil {
+		t.Fatalf("openServices : %v", err)
+	}
+	defer services.Close()
+
+	announcement := Announcement{propertyReference: "A1", url: "https://www.afedim.fr/fr/location/a1"}
+	services.store.Touch(Afedim, "https://www.afedim.fr/fr/location", announcement, time.Now())
+	if err := services.notifier.Notify(context.Background(), newAnnouncementMessage(SearchTarget{Agency: Afedim, Title: "AFEDIM"}, announcement)); err != nil {
+		t.Fatalf("Notify : %v", err)
+	}
+	if err := services.store.Save(); err != nil {
+		t.Fatalf("Save : %v", err)
+	}
+
+	if _, err := os.Stat(config.Settings.StatePath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("le stockage ne doit pas être écrit en simulation (%v)", err)
+	}
+	if !strings.Contains(output.String(), "Notification simulée n°1 : AFEDIM") {
+		t.Errorf("notification simulée absente de la sortie :\n%s", output.String())
+	}
+
+	// La sortie des notifications simulées n'a de sens qu'en simulation
+	options = &cliOptions{dryRunOutput: filepath.Join(dir, "notifications.txt"), stdout: io.Discard, stderr: io.Discard}
+	if _, err := options.openServices(config); err == nil {
+		t.Error("-dry-run-output sans -dry-run doit être refusé")
+	}
+}
+
+func TestWriteSeenCSV(t *testing.T) {
+	seen := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	entries := []SeenEntry{
+		{
+			Agency:            Afedim,
+			PropertyReference: "A1",
+			URL:               "https://www.afedim.fr/fr/location/a1",
+			FirstSeen:         seen,
+			LastSeen:          seen.Add(24 * time.Hour),
+			Notified:          true,
+			History: []Observation{
+				{Rent: pointer(700.0), FirstSeen: seen, LastSeen: seen},
+				{Rent: pointer(650.0), Charges: pointer(40.5), AvailableDate: "immédiate", FirstSeen: seen, LastSeen: seen},
+			},
+		},
+		{Agency: Giboire, PropertyReference: "G, 2", FirstSeen: seen, LastSeen: seen, GoneAt: &seen},
+	}
+
+	var output bytes.Buffer
+	if err := writeSeenCSV(&output, entries); err != nil {
+		t.Fatalf("writeSeenCSV : %v", err)
+	}
+	want := "agency,reference,url,search,first_seen,last_seen,notified,gone_at,rent,charges,available_date\n" +
+		"Afedim,A1,https://www.afedim.fr/fr/location/a1,,2026-10-01T10:00:00Z,2026-10-02T10:00:00Z,true,,650,40.5,immédiate\n" +
+		"Giboire,\"G, 2\",,,2026-10-01T10:00:00Z,2026-10-01T10:00:00Z,false,2026-10-01T10:00:00Z,,,\n"
+	if got := output.String(); got != want {
+		t.Errorf("export :\n%s\nattendu :\n%s", got, want)
+	}
+}
@@ -1,190 +1,232 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
-	"log"
 	"net/http"
+	neturl "net/url"
 	"strings"
 	"time"
 
 	"github.com/gocolly/colly/v2"
 )
 
-/**
- * Announcement est une structure pour stocker les informations sur les annonces de bien immobilier.
- * @property {string} propertyReference - Référence du bien immobilier.
- * @property {string} url - URL de la page de détails de l'annonce.
- */
-type Announcement struct {
-	propertyReference string
-	url               string
-}
-
 /**
  * ScrapeAnnouncement lance le scraping des annonces immobilières à partir de la page spécifiée.
- * @param {string} url - L'URL de la page à scraper.
+ * @param {context.Context} ctx - Contexte d'annulation : les requêtes en cours sont interrompues à son annulation.
+ * @param {Agency} agency - L'agence à scraper.
+ * @param {string} url - L'URL de la première page de résultats.
+ * @param {int} maxPages - Nombre maximal de pages de résultats visitées.
  * @return {[]Announcement} - Slice contenant les annonces.
+ * @return {error} - Erreur si l'agence est inconnue ou si le scraping a été annulé.
  */
-func (collyService *CollyService) ScrapeAnnouncement(agency Agency, url string) []Announcement {
-	// Slice pour stocker les URLs des pages de détails
-	var detailPageURLs []string
+func (collyService *CollyService) ScrapeAnnouncement(ctx context.Context, agency Agency, url string, maxPages int) ([]Announcement, error) {
+	// Récupérer le scraper enregistré pour l'agence
+	scraper, err := GetScraper(agency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Slice pour stocker les éléments de la page de résultats (URLs des pages de détails ou identifiants)
+	var listingItems []string
 
 	// Afficher un message de démarrage
-	fmt.Println("Démarrage du scraping des annonces immobilières de l'agence :", agency)
+	collectorLog.InfoContext(ctx, "Démarrage du scraping des annonces immobilières")
 
-	// Ignorer les erreurs de certificat TLS
-	collyService.collector.WithTransport(&http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	})
+	// Ignorer les erreurs de certificat TLS et interrompre les requêtes à l'annulation du contexte
+	collyService.collector.WithTransport(newContextTransport(ctx))
 
-	// Ajouter un paramètre unique à chaque requête pour invalider le cache
+	// Ajouter un paramètre unique à chaque requête pour invalider le cache, sauf après annulation ;
+	// le contexte de la requête porte les champs des journaux des scrapers d'agence
 	collyService.collector.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
+		r.Ctx.Put(logContextKey, ctx)
 		r.URL.RawQuery += "&_=" + fmt.Sprintf("%d", time.Now().UnixNano())
 	})
 
-	// Utiliser un switch pour configurer les callbacks spécifiques à l'agence
-	switch agency {
-	case Afedim:
-		setupMainPageAfedim(collyService.collector, &detailPageURLs)
-	case Giboire:
-		setupMainPageGiboire(collyService.collector, &detailPageURLs)
-	case Foncia:
-		setupMainPageFoncia(collyService.collector, &detailPageURLs)
-	case AgenceDuColombier:
-		setupMainPageAgenceDuColombier(collyService.collector, &detailPageURLs)
-	case LaFrancaiseImmobiliere:
-		setupMainPageLaFrancaiseImmobiliere(collyService.collector, &detailPageURLs)
-	case Guenno:
-		setupMainPageGuenno(collyService.collector, &detailPageURLs)
-	case LaMotte:
-		setupMainPageLaMotte(collyService.collector, &detailPageURLs)
-	case Kermarrec:
-		setupMainPageKermarrec(collyService.collector, &detailPageURLs)
-	case Nestenn:
-		setupMainPageNestenn(collyService.collector, &detailPageURLs)
-	case SquareHabitat:
-		setupMainPageSquareHabitat(collyService.collector, &detailPageURLs)
-	case CAImmobilier:
-		setupMainPageCAImmobilier(collyService.collector, &detailPageURLs)
-	case PigeaultImmobilier:
-		setupMainPagePigeaultImmobilier(collyService.collector, &detailPageURLs)
-	case LaForetImmobilier:
-		setupMainPageLaForetImmobilier(collyService.collector, &detailPageURLs)
-	case Cogir:
-		setupMainPageCogir(collyService.collector, &detailPageURLs)
-	default:
-		log.Fatalf("Agence inconnue : %s", agency)
-	}
+	// Configurer les callbacks spécifiques à l'agence
+	scraper.SetupMainPage(collyService.collector, &listingItems)
 
 	// Gestion des erreurs pour la page principale
-	collyService.collector.OnError(func(_ *colly.Response, err error) {
-		log.Printf("Erreur pendant le scraping de la page principale : %v", err)
+	collyService.collector.OnError(func(r *colly.Response, err error) {
+		collectorLog.ErrorContext(ctx, "Erreur pendant le scraping de la page principale", "page", r.Request.URL.String(), "status", r.StatusCode, "error", err)
+		collyService.countError()
+		httpResponses.WithLabelValues(string(agency), statusLabel(r.StatusCode)).Inc()
+	})
+
+	// Métriques des pages de résultats téléchargées
+	collyService.collector.OnResponse(func(r *colly.Response) {
+		pagesFetched.WithLabelValues(string(agency), "results").Inc()
+		httpResponses.WithLabelValues(string(agency), statusLabel(r.StatusCode)).Inc()
 	})
 
-	// Démarrer le scraping de la page principale
-	if err := collyService.collector.Visit(url); err != nil {
-		log.Printf("Erreur lors de la visite de l'URL principale : %v", err)
+	// Lire les informations de pagination de chaque page de résultats
+	pagination := scraper.Pagination()
+	var state paginationState
+	if pagination != nil {
+		pagination.register(collyService.collector, &state)
 	}
 
-	// Attendre la fin des requêtes asynchrones
-	collyService.collector.Wait()
+	// URL de recherche pointant vers l'API JSON de l'agence : les annonces sont décodées directement, sans page de détail
+	apiAnnouncements := make(map[string]Announcement)
+	if scraper.HasAPI() {
+		collyService.collector.OnResponse(func(r *colly.Response) {
+			if !isJSONResponse(r) {
+				return
+			}
+			announcements, total, err := scraper.DecodeAPIResponse(r.Body, r.Request)
+			if err != nil {
+				// Les annonces de la page manquent : le scraping n'est pas complet
+				collectorLog.ErrorContext(ctx, "Erreur lors du décodage de la réponse de l'API de l'agence", "page", r.Request.URL.String(), "error", err)
+				collyService.countError()
+				return
+			}
+			state.total = total
+			for _, announcement := range announcements {
+				if _, exists := apiAnnouncements[announcement.propertyReference]; !exists {
+					apiAnnouncements[announcement.propertyReference] = announcement
+				}
+				listingItems = append(listingItems, announcement.propertyReference)
+			}
+		})
+	}
 
-	if agency == SquareHabitat {
-		// La référence devient la description pour Square Habitat
-		var announcements []Announcement
-		for _, ref := range detailPageURLs {
-			announcements = append(announcements, Announcement{propertyReference: ref, url: ""})
+	// Visiter les pages de résultats une par une : chaque page est une visite de premier niveau,
+	// MaxDepth(1) continue donc d'empêcher le suivi des liens externes
+	seenItems := make(map[string]bool)
+	pageURL := url
+	for page := 1; pageURL != "" && page <= maxPages; page++ {
+		state = paginationState{}
+		before := len(listingItems)
+		if err := collyService.collector.Visit(pageURL); err != nil {
+			collectorLog.ErrorContext(ctx, "Erreur lors de la visite de la page de résultats", "page", pageURL, "number", page, "error", err)
+			collyService.countError()
+			break
 		}
-		return announcements
-	} else if agency == CAImmobilier {
-		// La référence devient la description pour CA Immobilier
-		var announcements []Announcement
-		for _, ref := range detailPageURLs {
-			// Extraire la partie après le dernier "/" pour le propertyReference
-			lastSlashIndex := strings.LastIndex(ref, "/")
-			var propertyReference string
-			if lastSlashIndex != -1 && lastSlashIndex+1 < len(ref) {
-				propertyReference = ref[lastSlashIndex+1:]
-			} else {
-				propertyReference = "unknown" // Valeur par défaut si la référence est mal formée
+
+		// Attendre la fin des requêtes asynchrones
+		collyService.collector.Wait()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Ignorer les annonces déjà trouvées sur une page précédente
+		unique := listingItems[:before]
+		for _, listingItem := range listingItems[before:] {
+			if !seenItems[listingItem] {
+				seenItems[listingItem] = true
+				unique = append(unique, listingItem)
 			}
+		}
+		newItems := len(unique) - before
+		listingItems = unique
+		collyService.stats.Pages = page
 
-			// Ajouter à la liste des annonces
-			announcements = append(announcements, Announcement{
-				propertyReference: propertyReference,
-				url:               ref,
-			})
+		if pagination == nil {
+			break
+		}
+		pageURL = pagination.next(pageURL, page, newItems, len(listingItems), &state)
+		if pageURL != "" && page == maxPages {
+			collectorLog.WarnContext(ctx, "Nombre maximal de pages de résultats atteint", "max_pages", maxPages)
+			collyService.stats.Truncated = true
+		}
+	}
+	collyService.stats.Items = len(listingItems)
+
+	// Annonces décodées depuis l'API, dans l'ordre des résultats
+	if len(apiAnnouncements) > 0 {
+		announcements := make([]Announcement, 0, len(listingItems))
+		for _, reference := range listingItems {
+			announcements = append(announcements, apiAnnouncements[reference])
+		}
+		collyService.stats.Announcements = len(announcements)
+		return announcements, nil
+	}
+
+	// Sans pages de détail, la référence est dérivée directement de la page de résultats
+	if !scraper.HasDetailPages() {
+		var announcements []Announcement
+		for _, listingItem := range listingItems {
+			announcements = append(announcements, scraper.DeriveAnnouncement(listingItem))
 		}
-		return announcements
+		collyService.stats.Announcements = len(announcements)
+		return announcements, nil
 	}
 
 	// Récupérer les annonces complètes (références et URLs)
-	return collyService.processDetailPages(detailPageURLs, agency)
+	announcements := collyService.processDetailPages(ctx, agency, listingItems, scraper)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// L'URL d'une page de détail est celle de la requête : retirer le paramètre anti-cache des notifications et du stockage
+	for i := range announcements {
+		if parsedURL, err := neturl.Parse(announcements[i].url); err == nil {
+			announcements[i].url = withoutCacheBuster(parsedURL).String()
+		}
+	}
+	collyService.stats.Announcements = len(announcements)
+	return announcements, nil
 }
 
 /**
  * processDetailPages traite les pages de détails des annonces immobilières.
+ * @param {context.Context} ctx - Contexte d'annulation : les pages restantes ne sont pas visitées après son annulation.
+ * @param {Agency} agency - L'agence scrapée.
  * @param {[]string} detailPageURLs - Slice contenant les URLs des pages de détails.
+ * @param {Scraper} scraper - Le scraper de l'agence.
  * @return {[]Announcement} - Slice contenant les annonces.
  */
-func (collyService *CollyService) processDetailPages(detailPageURLs []string, agency Agency) []Announcement {
+func (collyService *CollyService) processDetailPages(ctx context.Context, agency Agency, detailPageURLs []string, scraper Scraper) []Announcement {
 	// Slice pour stocker les annonces
 	var announcements []Announcement
 
 	// Créer un nouveau collector pour les pages de détails
 	detailCollector := colly.NewCollector()
 
-	// Ignorer les erreurs de certificat TLS
-	detailCollector.WithTransport(&http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	})
+	// Ignorer les erreurs de certificat TLS et interrompre les requêtes à l'annulation du contexte
+	detailCollector.WithTransport(newContextTransport(ctx))
 
-	// Ajouter un paramètre unique à chaque requête pour invalider le cache
+	// Ajouter un paramètre unique à chaque requête pour invalider le cache, sauf après annulation ;
+	// le contexte de la requête porte les champs des journaux des scrapers d'agence
 	detailCollector.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
+		r.Ctx.Put(logContextKey, ctx)
 		r.URL.RawQuery += "&_=" + fmt.Sprintf("%d", time.Now().UnixNano())
 	})
 
-	// Utiliser un switch pour appeler la fonction spécifique à l'agence
-	switch agency {
-	case Afedim:
-		processDetailPagesAfedim(detailCollector, &announcements)
-	case Giboire:
-		processDetailPagesGiboire(detailCollector, &announcements)
-	case Foncia:
-		processDetailPagesFoncia(detailCollector, &announcements)
-	case AgenceDuColombier:
-		processDetailPagesAgenceDuColombier(detailCollector, &announcements)
-	case LaFrancaiseImmobiliere:
-		processDetailPagesLaFrancaiseImmobiliere(detailCollector, &announcements)
-	case Guenno:
-		processDetailPagesGuenno(detailCollector, &announcements)
-	case LaMotte:
-		processDetailPagesLaMotte(detailCollector, &announcements)
-	case Kermarrec:
-		processDetailPagesKermarrec(detailCollector, &announcements)
-	case Nestenn:
-		processDetailPagesNestenn(detailCollector, &announcements)
-	case PigeaultImmobilier:
-		processDetailPagesPigeaultImmobilier(detailCollector, &announcements)
-	case LaForetImmobilier:
-		processDetailPagesLaForetImmobilier(detailCollector, &announcements)
-	case Cogir:
-		processDetailPagesCogir(detailCollector, &announcements)
-	default:
-		log.Fatalf("Agence inconnue : %s", agency)
-	}
+	// Configurer les callbacks spécifiques à l'agence
+	scraper.ProcessDetailPages(detailCollector, &announcements)
 
 	// Gestion des erreurs pour les détails
-	detailCollector.OnError(func(_ *colly.Response, err error) {
-		log.Printf("Erreur pendant le scraping de la page de détails : %v", err)
+	detailCollector.OnError(func(r *colly.Response, err error) {
+		collectorLog.ErrorContext(ctx, "Erreur pendant le scraping de la page de détails", "page", r.Request.URL.String(), "status", r.StatusCode, "error", err)
+		collyService.countError()
+		httpResponses.WithLabelValues(string(agency), statusLabel(r.StatusCode)).Inc()
+	})
+
+	// Métriques des pages de détails téléchargées
+	detailCollector.OnResponse(func(r *colly.Response) {
+		pagesFetched.WithLabelValues(string(agency), "detail").Inc()
+		httpResponses.WithLabelValues(string(agency), statusLabel(r.StatusCode)).Inc()
 	})
 
 	// Visiter chaque URL dans la slice
 	for _, url := range detailPageURLs {
-		fmt.Println("Visite de la page de détails :", url)
+		if ctx.Err() != nil {
+			break
+		}
+		collectorLog.DebugContext(ctx, "Visite de la page de détails", "page", url)
 		if err := detailCollector.Visit(url); err != nil {
-			log.Printf("Erreur lors de la visite de la page de détails : %v", err)
+			collectorLog.ErrorContext(ctx, "Erreur lors de la visite de la page de détails", "page", url, "error", err)
+			collyService.countError()
 		}
 	}
 
@@ -194,3 +236,52 @@ func (collyService *CollyService) processDetailPages(detailPageURLs []string, ag
 	// Retourner toutes les annonces trouvées
 	return announcements
 }
+
+/**
+ * contextTransport rattache un contexte à chaque requête HTTP de colly, qui ne gère pas les contextes :
+ * les requêtes en cours sont interrompues à l'annulation du contexte.
+ * @property {context.Context} ctx - Le contexte d'annulation.
+ * @property {http.RoundTripper} base - Le transport HTTP sous-jacent.
+ */
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+/**
+ * newContextTransport crée le transport HTTP des collecteurs : certificats TLS ignorés et requêtes liées au contexte.
+ * Si le contexte porte une cassette, les requêtes sont enregistrées ou rejouées par celle-ci.
+ * @param {context.Context} ctx - Le contexte d'annulation.
+ * @return {contextTransport} - Le transport HTTP.
+ */
+func newContextTransport(ctx context.Context) *contextTransport {
+	var base http.RoundTripper = &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	if cassette := cassetteFromContext(ctx); cassette != nil {
+		base = cassette.wrap(base)
+	}
+
+	return &contextTransport{ctx: ctx, base: base}
+}
+
+func (transport *contextTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return transport.base.RoundTrip(request.WithContext(transport.ctx))
+}
+
+/**
+ * withoutCacheBuster retourne une copie de l'URL sans le paramètre anti-cache (_) ajouté à chaque requête des collecteurs.
+ * @param {neturl.URL} requestURL - L'URL de la requête.
+ * @return {neturl.URL} - L'URL sans le paramètre anti-cache.
+ */
+func withoutCacheBuster(requestURL *neturl.URL) *neturl.URL {
+	stable := *requestURL
+	var params []string
+	for _, param := range strings.Split(stable.RawQuery, "&") {
+		if param != "" && param != "_" && !strings.HasPrefix(param, "_=") {
+			params = append(params, param)
+		}
+	}
+	stable.RawQuery = strings.Join(params, "&")
+	return &stable
+}
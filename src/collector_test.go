@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScrapeAnnouncementURLWithoutCacheBuster(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if strings.HasPrefix(r.URL.Path, "/location/") {
+			_, _ = w.Write([]byte(`<html><body><h1>Appartement T2 - 35000 Rennes</h1></body></html>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<html><body><article><a href="/location/t2-rennes-4521?ville=rennes">T2</a></article></body></html>`))
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { _ = RegisterAgencyDefinitions(nil) })
+
+	err := RegisterAgencyDefinitions([]AgencyDefinition{{
+		Name:    "Exemple",
+		Listing: ListingDefinition{Item: "article", Link: "a"},
+		Detail:  &DetailDefinition{Fields: FieldSelectors{Title: "h1"}},
+	}})
+	if err != nil {
+		t.Fatalf("RegisterAgencyDefinitions : %v", err)
+	}
+
+	// Le paramètre anti-cache des requêtes ne doit pas apparaître dans l'URL des annonces (stockage, boutons des notifications)
+	announcements, err := NewCollyService().ScrapeAnnouncement(context.Background(), "Exemple", server.URL+"/location?ville=rennes", 1)
+	if err != nil {
+		t.Fatalf("ScrapeAnnouncement : %v", err)
+	}
+	if len(announcements) != 1 {
+		t.Fatalf("annonces = %d, attendu 1", len(announcements))
+	}
+	if got, want := announcements[0].url, server.URL+"/location/t2-rennes-4521?ville=rennes"; got != want {
+		t.Errorf("url = %q, attendu %q", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"sync"
 	"time"
 
 	"github.com/gocolly/colly/v2"
@@ -10,10 +11,61 @@ import (
  * CollyService est une structure qui encapsule le collecteur Colly pour le scraping de données.
  * @property {colly.Collector} collector - Instance du collecteur Colly pour le scraping.
  * @property {chan error} errChan - Canal pour signaler les erreurs pendant le scraping.
+ * @property {sync.Mutex} statsMutex - Verrou protégeant les statistiques (callbacks asynchrones).
+ * @property {ScrapeStats} stats - Statistiques du dernier scraping.
  */
 type CollyService struct {
-	collector *colly.Collector
-	errChan   chan error
+	collector  *colly.Collector
+	errChan    chan error
+	statsMutex sync.Mutex
+	stats      ScrapeStats
+}
+
+/**
+ * ScrapeStats décrit le déroulement d'un scraping.
+ * @property {int} Pages - Nombre de pages de résultats visitées.
+ * @property {bool} Truncated - true si le nombre maximal de pages de résultats a été atteint avant la dernière page.
+ * @property {int} Errors - Nombre de requêtes en erreur (pages de résultats et de détails).
+ * @property {int} Items - Nombre d'annonces trouvées sur les pages de résultats.
+ * @property {int} Announcements - Nombre d'annonces extraites.
+ */
+type ScrapeStats struct {
+	Pages         int
+	Truncated     bool
+	Errors        int
+	Items         int
+	Announcements int
+}
+
+/**
+ * Complete indique si le scraping a vu toutes les annonces de la recherche : sans erreur, sans troncature,
+ * sans page de détail dont l'annonce n'a pas pu être extraite et avec au moins une annonce (une page vide
+ * signale plus souvent un site modifié qu'une recherche vide).
+ * @return {bool} - true si l'absence d'une annonce peut être considérée comme un retrait.
+ */
+func (stats ScrapeStats) Complete() bool {
+	return stats.Errors == 0 && !stats.Truncated && stats.Announcements > 0 && stats.Announcements >= stats.Items
+}
+
+/**
+ * Stats retourne les statistiques du dernier scraping.
+ * @return {ScrapeStats} - Les statistiques.
+ */
+func (collyService *CollyService) Stats() ScrapeStats {
+	collyService.statsMutex.Lock()
+	defer collyService.statsMutex.Unlock()
+
+	return collyService.stats
+}
+
+/**
+ * countError comptabilise une requête en erreur.
+ * @return {void}
+ */
+func (collyService *CollyService) countError() {
+	collyService.statsMutex.Lock()
+	collyService.stats.Errors++
+	collyService.statsMutex.Unlock()
 }
 
 // Liste des User-Agents pour éviter le blocage
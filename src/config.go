@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+/**
+ * Duration est une durée lisible dans le fichier de configuration (ex : "90s", "5m", "1h").
+ */
+type Duration time.Duration
+
+/**
+ * UnmarshalYAML décode une durée au format time.ParseDuration.
+ * @param {yaml.Node} node - Le noeud YAML à décoder.
+ * @return {error} - Erreur si la durée est invalide.
+ */
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("ligne %d : durée invalide %q", node.Line, value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+/**
+ * Config est la configuration complète du scraper, chargée depuis un fichier YAML.
+ * @property {Settings} Settings - Paramètres globaux.
+ * @property {FilterRules} Filters - Critères appliqués aux annonces de toutes les recherches.
+ * @property {[]NotifierConfig} Notifiers - Services de notification (Telegram seul par défaut).
+ * @property {[]NotifierConfig} AdminNotifiers - Services de notification des administrateurs (alertes de santé du scraper).
+ * @property {[]AgencyDefinition} Agencies - Agences décrites par sélecteurs CSS, en plus des agences intégrées.
+ * @property {[]SearchTarget} Targets - Recherches à scraper.
+ */
+type Config struct {
+	Settings       Settings           `yaml:"settings"`
+	Filters        FilterRules        `yaml:"filters"`
+	Notifiers      []NotifierConfig   `yaml:"notifiers"`
+	AdminNotifiers []NotifierConfig   `yaml:"admin_notifiers"`
+	Agencies       []AgencyDefinition `yaml:"agencies"`
+	Targets        []SearchTarget     `yaml:"targets"`
+}
+
+/**
+ * Settings regroupe les paramètres globaux du scraper.
+ * @property {Duration} Interval - Intervalle par défaut entre deux scrapings d'une recherche.
+ * @property {string} StatePath - Chemin du fichier des références déjà traitées.
+ * @property {bool} Warmup - Si true, le premier scraping d'une agence marque ses annonces comme vues sans notifier.
+ * @property {int} Workers - Nombre de recherches scrapées en parallèle.
+ * @property {Duration} Jitter - Décalage aléatoire maximal par défaut ajouté à chaque scraping.
+ * @property {ActiveHours} ActiveHours - Plage horaire active par défaut (toute la journée si absente).
+ * @property {Duration} ShutdownTimeout - Délai laissé aux scrapings en cours pour se terminer à l'arrêt.
+ * @property {int} MaxPages - Nombre maximal de pages de résultats visitées par défaut.
+ * @property {DedupSettings} Dedup - Regroupement des annonces d'un même bien publiées par plusieurs agences.
+ * @property {ChangeSettings} Changes - Suivi du loyer et des republications des annonces déjà notifiées.
+ * @property {GoneSettings} Gone - Détection des annonces retirées.
+ * @property {HealthSettings} Health - Règles d'alerte sur la santé du scraping.
+ * @property {string} HTTPAddr - Adresse d'écoute du serveur HTTP d'exploitation (/metrics, /healthz, /readyz), vide pour le désactiver.
+ * @property {float64} LivenessFactor - Multiple de l'intervalle toléré sans cycle terminé avant l'échec de /healthz.
+ * @property {LogSettings} Log - Niveau et format des journaux.
+ */
+type Settings struct {
+	Interval        Duration       `yaml:"interval"`
+	StatePath       string         `yaml:"state_path"`
+	Warmup          *bool          `yaml:"warmup"`
+	Workers         int            `yaml:"workers"`
+	Jitter          Duration       `yaml:"jitter"`
+	ActiveHours     *ActiveHours   `yaml:"active_hours"`
+	ShutdownTimeout Duration       `yaml:"shutdown_timeout"`
+	MaxPages        int            `yaml:"max_pages"`
+	Dedup           DedupSettings  `yaml:"dedup"`
+	Changes         ChangeSettings `yaml:"changes"`
+	Gone            GoneSettings   `yaml:"gone"`
+	Health          HealthSettings `yaml:"health"`
+	HTTPAddr        string         `yaml:"http_addr"`
+	LivenessFactor  float64        `yaml:"liveness_factor"`
+	Log             LogSettings    `yaml:"log"`
+}
+
+/**
+ * SearchTarget est une recherche à scraper sur le site d'une agence.
+ * @property {Agency} Agency - L'agence à scraper.
+ * @property {string} URL - L'URL de la page de résultats.
+ * @property {string} Title - Le titre affiché dans les notifications.
+ * @property {bool} Enabled - Si false, la recherche est ignorée (true par défaut).
+ * @property {Duration} Interval - Intervalle propre à la recherche (intervalle global par défaut).
+ * @property {string} Cron - Expression cron à 5 champs, prioritaire sur l'intervalle (ex : "0,30 8-20 * * 1-5").
+ * @property {Duration} Jitter - Décalage aléatoire maximal ajouté à chaque scraping (valeur globale par défaut).
+ * @property {ActiveHours} ActiveHours - Plage horaire active (valeur globale par défaut).
+ * @property {int} MaxPages - Nombre maximal de pages de résultats visitées (valeur globale par défaut).
+ * @property {FilterRules} Filters - Critères propres à la recherche, prioritaires sur les critères globaux.
+ */
+type SearchTarget struct {
+	Agency      Agency       `yaml:"agency"`
+	URL         string       `yaml:"url"`
+	Title       string       `yaml:"title"`
+	Enabled     *bool        `yaml:"enabled"`
+	Interval    Duration     `yaml:"interval"`
+	Cron        string       `yaml:"cron"`
+	Jitter      Duration     `yaml:"jitter"`
+	ActiveHours *ActiveHours `yaml:"active_hours"`
+	MaxPages    int          `yaml:"max_pages"`
+	Filters     FilterRules  `yaml:"filters"`
+}
+
+/**
+ * IsEnabled indique si la recherche doit être scrapée.
+ * @return {bool} - true si la recherche est active.
+ */
+func (target SearchTarget) IsEnabled() bool {
+	return target.Enabled == nil || *target.Enabled
+}
+
+/**
+ * LoadConfig lit, complète et valide le fichier de configuration.
+ * @param {string} path - Chemin du fichier de configuration.
+ * @return {Config} - La configuration chargée.
+ * @return {error} - Erreur de lecture, de décodage ou de validation.
+ */
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("lecture de la configuration : %w", err)
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("décodage de la configuration %s : %w", path, err)
+	}
+
+	config.applyDefaults()
+	// Les agences déclarées sont enregistrées avant la validation des recherches qui les utilisent
+	if err := errors.Join(RegisterAgencyDefinitions(config.Agencies), config.Validate()); err != nil {
+		return nil, fmt.Errorf("configuration %s invalide :\n%w", path, err)
+	}
+
+	return &config, nil
+}
+
+/**
+ * applyDefaults complète les valeurs absentes du fichier de configuration.
+ * @return {void}
+ */
+func (config *Config) applyDefaults() {
+	if config.Settings.Interval == 0 {
+		config.Settings.Interval = Duration(time.Minute)
+	}
+	if config.Settings.StatePath == "" {
+		config.Settings.StatePath = "data/seen.json"
+	}
+	if config.Settings.ShutdownTimeout == 0 {
+		config.Settings.ShutdownTimeout = Duration(20 * time.Second)
+	}
+	if config.Settings.MaxPages == 0 {
+		config.Settings.MaxPages = 5
+	}
+	if config.Settings.Workers == 0 {
+		config.Settings.Workers = 4
+	}
+	if config.Settings.Dedup.Enabled == nil {
+		enabled := true
+		config.Settings.Dedup.Enabled = &enabled
+	}
+	if config.Settings.Dedup.PhotoHash == nil {
+		photoHash := true
+		config.Settings.Dedup.PhotoHash = &photoHash
+	}
+	if config.Settings.Dedup.StatePath == "" {
+		config.Settings.Dedup.StatePath = "data/properties.json"
+	}
+	if config.Settings.Dedup.Retention == 0 {
+		config.Settings.Dedup.Retention = Duration(30 * 24 * time.Hour)
+	}
+	if config.Settings.Changes.NotifyPrice == nil {
+		notifyPrice := true
+		config.Settings.Changes.NotifyPrice = &notifyPrice
+	}
+	if config.Settings.Changes.NotifyRelisting == nil {
+		notifyRelisting := true
+		config.Settings.Changes.NotifyRelisting = &notifyRelisting
+	}
+	if config.Settings.Changes.RelistAfter == 0 {
+		config.Settings.Changes.RelistAfter = Duration(48 * time.Hour)
+	}
+	if config.Settings.Gone.MissedCycles == 0 {
+		config.Settings.Gone.MissedCycles = 3
+	}
+	if config.Settings.Gone.Notify == nil {
+		notifyGone := false
+		config.Settings.Gone.Notify = &notifyGone
+	}
+	if config.Settings.Health.Cycles == 0 {
+		config.Settings.Health.Cycles = 3
+	}
+	if config.Settings.Health.MinExtractionRatio == 0 {
+		config.Settings.Health.MinExtractionRatio = 0.5
+	}
+	if config.Settings.LivenessFactor == 0 {
+		config.Settings.LivenessFactor = 5
+	}
+	if config.Settings.Warmup == nil {
+		warmup := true
+		config.Settings.Warmup = &warmup
+	}
+	if len(config.Notifiers) == 0 {
+		config.Notifiers = []NotifierConfig{{Type: "telegram"}}
+	}
+	for i := range config.Targets {
+		if config.Targets[i].Interval == 0 {
+			config.Targets[i].Interval = config.Settings.Interval
+		}
+		if config.Targets[i].MaxPages == 0 {
+			config.Targets[i].MaxPages = config.Settings.MaxPages
+		}
+		if config.Targets[i].Jitter == 0 {
+			config.Targets[i].Jitter = config.Settings.Jitter
+		}
+		if config.Targets[i].ActiveHours == nil {
+			config.Targets[i].ActiveHours = config.Settings.ActiveHours
+		}
+		config.Targets[i].Filters = MergeFilterRules(config.Filters, config.Targets[i].Filters)
+	}
+}
+
+/**
+ * Validate vérifie la cohérence de la configuration et retourne toutes les erreurs trouvées.
+ * @return {error} - Les erreurs de validation, ou nil.
+ */
+func (config *Config) Validate() error {
+	var errs []error
+
+	if config.Settings.Interval < 0 {
+		errs = append(errs, errors.New("settings.interval doit être positif"))
+	}
+	if config.Settings.Workers < 0 {
+		errs = append(errs, errors.New("settings.workers doit être positif"))
+	}
+	if config.Settings.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("settings.shutdown_timeout doit être positif"))
+	}
+	if config.Settings.MaxPages < 0 {
+		errs = append(errs, errors.New("settings.max_pages doit être positif"))
+	}
+	if config.Settings.Jitter < 0 {
+		errs = append(errs, errors.New("settings.jitter doit être positif"))
+	}
+	if config.Settings.Dedup.Retention < 0 {
+		errs = append(errs, errors.New("settings.dedup.retention doit être positif"))
+	}
+	if config.Settings.Changes.RelistAfter < 0 {
+		errs = append(errs, errors.New("settings.changes.relist_after doit être positif"))
+	}
+	if config.Settings.Gone.MissedCycles < 0 {
+		errs = append(errs, errors.New("settings.gone.missed_cycles doit être positif"))
+	}
+	if config.Settings.Health.Cycles < 0 {
+		errs = append(errs, errors.New("settings.health.cycles doit être positif"))
+	}
+	if ratio := config.Settings.Health.MinExtractionRatio; ratio < 0 || ratio > 1 {
+		errs = append(errs, errors.New("settings.health.min_extraction_ratio doit être compris entre 0 et 1"))
+	}
+	if err := config.Settings.Log.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("settings.log : %w", err))
+	}
+	if config.Settings.LivenessFactor < 1 {
+		errs = append(errs, errors.New("settings.liveness_factor doit être supérieur ou égal à 1"))
+	}
+	if config.Settings.ActiveHours != nil {
+		if _, err := config.Settings.ActiveHours.parse(); err != nil {
+			errs = append(errs, fmt.Errorf("settings.active_hours : %w", err))
+		}
+	}
+
+	if err := config.Filters.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("filters : %w", err))
+	}
+
+	for i, notifier := range config.Notifiers {
+		if err := notifier.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("notifiers[%d] (%s) : %w", i, notifier.DisplayName(), err))
+		}
+	}
+	for i, notifier := range config.AdminNotifiers {
+		if err := notifier.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("admin_notifiers[%d] (%s) : %w", i, notifier.DisplayName(), err))
+		}
+	}
+
+	enabledTargets := 0
+	for i, target := range config.Targets {
+		prefix := fmt.Sprintf("targets[%d]", i)
+		if target.Agency != "" {
+			prefix += fmt.Sprintf(" (%s)", target.Agency)
+		}
+
+		if target.Agency == "" {
+			errs = append(errs, fmt.Errorf("%s : agency est obligatoire", prefix))
+		} else if _, err := GetScraper(target.Agency); err != nil {
+			errs = append(errs, fmt.Errorf("%s : %w", prefix, err))
+		}
+
+		if parsedURL, err := url.Parse(target.URL); err != nil || parsedURL.Host == "" || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("%s : url invalide %q", prefix, target.URL))
+		}
+
+		if target.Title == "" {
+			errs = append(errs, fmt.Errorf("%s : title est obligatoire", prefix))
+		}
+
+		if target.MaxPages < 0 {
+			errs = append(errs, fmt.Errorf("%s : max_pages doit être positif", prefix))
+		}
+
+		if _, err := newTargetSchedule(target); err != nil {
+			errs = append(errs, fmt.Errorf("%s : %w", prefix, err))
+		}
+
+		if err := target.Filters.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s : filters : %w", prefix, err))
+		}
+
+		if target.IsEnabled() {
+			enabledTargets++
+		}
+	}
+
+	if enabledTargets == 0 {
+		errs = append(errs, errors.New("aucune recherche active dans targets"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,625 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version du format du fichier des biens regroupés
+const propertyIndexVersion = 1
+
+// Longueur maximale de la description normalisée conservée pour chaque bien
+const propertyDescriptionMaxLength = 2000
+
+// Similarité minimale (indice de Jaccard des suites de 3 mots) entre deux descriptions d'un même bien
+const descriptionSimilarityThreshold = 0.5
+
+// Nombre minimal de suites de 3 mots pour comparer deux descriptions
+const descriptionMinShingles = 5
+
+/**
+ * DedupSettings regroupe les paramètres du regroupement des annonces d'un même bien publiées par plusieurs agences.
+ * @property {bool} Enabled - Si true (défaut), les annonces d'un même bien sont notifiées en un seul message.
+ * @property {string} StatePath - Chemin du fichier des biens regroupés.
+ * @property {bool} PhotoHash - Si true (défaut), la première photo de chaque annonce est téléchargée pour comparer les biens.
+ * @property {Duration} Retention - Durée de conservation d'un bien après sa dernière annonce rattachée.
+ */
+type DedupSettings struct {
+	Enabled   *bool    `yaml:"enabled"`
+	StatePath string   `yaml:"state_path"`
+	PhotoHash *bool    `yaml:"photo_hash"`
+	Retention Duration `yaml:"retention"`
+}
+
+/**
+ * PropertyListing est l'annonce d'un bien chez une agence.
+ * @property {Agency} Agency - L'agence.
+ * @property {string} Reference - Référence de l'annonce chez l'agence.
+ * @property {string} URL - URL de l'annonce.
+ */
+type PropertyListing struct {
+	Agency    Agency `json:"agency"`
+	Reference string `json:"reference"`
+	URL       string `json:"url,omitempty"`
+}
+
+/**
+ * Property est un bien immobilier regroupant les annonces de plusieurs agences.
+ * @property {string} ID - Identifiant du bien (agence et référence de sa première annonce).
+ * @property {[]PropertyListing} Listings - Les annonces du bien.
+ * @property {*float64} Rent - Loyer mensuel en euros.
+ * @property {*float64} Surface - Surface habitable en m².
+ * @property {*int} Rooms - Nombre de pièces.
+ * @property {string} Postcode - Code postal.
+ * @property {string} Description - Description normalisée (tronquée).
+ * @property {string} PhotoHash - Empreinte perceptuelle de la première photo, en hexadécimal.
+ * @property {time.Time} FirstSeen - Date de la première annonce.
+ * @property {time.Time} LastSeen - Date de la dernière annonce rattachée.
+ */
+type Property struct {
+	ID          string            `json:"id"`
+	Listings    []PropertyListing `json:"listings"`
+	Rent        *float64          `json:"rent,omitempty"`
+	Surface     *float64          `json:"surface,omitempty"`
+	Rooms       *int              `json:"rooms,omitempty"`
+	Postcode    string            `json:"postcode,omitempty"`
+	Description string            `json:"description,omitempty"`
+	PhotoHash   string            `json:"photoHash,omitempty"`
+	FirstSeen   time.Time         `json:"firstSeen"`
+	LastSeen    time.Time         `json:"lastSeen"`
+
+	fingerprint propertyFingerprint
+}
+
+/**
+ * hasListing indique si le bien a déjà l'annonce : une même annonce vue deux fois n'est pas un doublon.
+ * Les agences d'un même réseau (Nestenn, Laforêt) partagent leur nom : seule la référence distingue leurs annonces.
+ * @param {Agency} agency - L'agence de l'annonce.
+ * @param {string} reference - La référence de l'annonce.
+ * @return {bool} - true si l'annonce est déjà rattachée au bien.
+ */
+func (property *Property) hasListing(agency Agency, reference string) bool {
+	for _, listing := range property.Listings {
+		if listing.Agency == agency && listing.Reference == reference {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * propertyFingerprint regroupe les attributs comparés pour reconnaître un même bien.
+ * @property {*float64} rent - Loyer mensuel.
+ * @property {*float64} surface - Surface habitable.
+ * @property {*int} rooms - Nombre de pièces.
+ * @property {string} postcode - Code postal.
+ * @property {map[string]struct{}} shingles - Suites de 3 mots de la description normalisée.
+ * @property {*uint64} photoHash - Empreinte de la première photo, nil si inconnue.
+ */
+type propertyFingerprint struct {
+	rent      *float64
+	surface   *float64
+	rooms     *int
+	postcode  string
+	shingles  map[string]struct{}
+	photoHash *uint64
+}
+
+/**
+ * newPropertyFingerprint calcule l'empreinte d'un bien à partir de ses attributs.
+ * @param {*float64} rent - Loyer mensuel.
+ * @param {*float64} surface - Surface habitable.
+ * @param {*int} rooms - Nombre de pièces.
+ * @param {string} postcode - Code postal.
+ * @param {string} description - Description normalisée.
+ * @param {*uint64} photoHash - Empreinte de la première photo.
+ * @return {propertyFingerprint} - L'empreinte.
+ */
+func newPropertyFingerprint(rent *float64, surface *float64, rooms *int, postcode string, description string, photoHash *uint64) propertyFingerprint {
+	return propertyFingerprint{
+		rent:      rent,
+		surface:   surface,
+		rooms:     rooms,
+		postcode:  postcode,
+		shingles:  textShingles(description),
+		photoHash: photoHash,
+	}
+}
+
+/**
+ * matches indique si deux empreintes désignent le même bien. Un attribut connu des deux côtés et différent
+ * (loyer, surface, pièces, code postal) exclut le rapprochement, de même qu'une photo ou une description connue
+ * des deux côtés et différente (logements identiques d'un même immeuble) ; sinon une photo quasi identique ou une
+ * description similaire suffit. Sans photo ni description comparable, tous les attributs structurés doivent concorder.
+ * @param {propertyFingerprint} other - L'autre empreinte.
+ * @return {bool} - true si les deux empreintes désignent le même bien.
+ */
+func (fingerprint propertyFingerprint) matches(other propertyFingerprint) bool {
+	if fingerprint.rent != nil && other.rent != nil && !closeEnough(*fingerprint.rent, *other.rent, 10, 0.03) {
+		return false
+	}
+	if fingerprint.surface != nil && other.surface != nil && !closeEnough(*fingerprint.surface, *other.surface, 1.5, 0.03) {
+		return false
+	}
+	if fingerprint.rooms != nil && other.rooms != nil && *fingerprint.rooms != *other.rooms {
+		return false
+	}
+	if fingerprint.postcode != "" && other.postcode != "" && fingerprint.postcode != other.postcode {
+		return false
+	}
+
+	photoComparable := fingerprint.photoHash != nil && other.photoHash != nil
+	if photoComparable && photoHashDistance(*fingerprint.photoHash, *other.photoHash) > photoHashMaxDistance {
+		return false
+	}
+	descriptionComparable := len(fingerprint.shingles) >= descriptionMinShingles && len(other.shingles) >= descriptionMinShingles
+	if descriptionComparable && jaccardSimilarity(fingerprint.shingles, other.shingles) < descriptionSimilarityThreshold {
+		return false
+	}
+	if photoComparable || descriptionComparable {
+		return true
+	}
+
+	// Sans photo ni description comparable, tous les attributs structurés doivent être connus et concorder
+	return fingerprint.rent != nil && other.rent != nil &&
+		fingerprint.surface != nil && other.surface != nil &&
+		fingerprint.rooms != nil && other.rooms != nil &&
+		fingerprint.postcode != "" && other.postcode != ""
+}
+
+/**
+ * closeEnough indique si deux valeurs sont égales à une tolérance près (la plus grande des deux tolérances).
+ * @param {float64} a - Première valeur.
+ * @param {float64} b - Seconde valeur.
+ * @param {float64} absolute - Tolérance absolue.
+ * @param {float64} relative - Tolérance relative à la plus grande valeur.
+ * @return {bool} - true si l'écart est dans la tolérance.
+ */
+func closeEnough(a float64, b float64, absolute float64, relative float64) bool {
+	return math.Abs(a-b) <= max(absolute, relative*max(a, b))
+}
+
+/**
+ * textShingles découpe un texte normalisé en suites de 3 mots consécutifs.
+ * @param {string} text - Le texte normalisé.
+ * @return {map[string]struct{}} - Les suites de mots, nil si le texte est trop court.
+ */
+func textShingles(text string) map[string]struct{} {
+	words := strings.Fields(text)
+	if len(words) < 3 {
+		return nil
+	}
+	shingles := make(map[string]struct{}, len(words)-2)
+	for i := 0; i+3 <= len(words); i++ {
+		shingles[strings.Join(words[i:i+3], " ")] = struct{}{}
+	}
+	return shingles
+}
+
+/**
+ * jaccardSimilarity calcule l'indice de Jaccard de deux ensembles (taille de l'intersection sur taille de l'union).
+ * @param {map[string]struct{}} a - Premier ensemble.
+ * @param {map[string]struct{}} b - Second ensemble.
+ * @return {float64} - L'indice, entre 0 et 1.
+ */
+func jaccardSimilarity(a map[string]struct{}, b map[string]struct{}) float64 {
+	common := 0
+	for shingle := range a {
+		if _, exists := b[shingle]; exists {
+			common++
+		}
+	}
+	union := len(a) + len(b) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+/**
+ * propertyDescription retourne la description normalisée d'une annonce, tronquée.
+ * @param {Announcement} announcement - L'annonce.
+ * @return {string} - La description normalisée.
+ */
+func propertyDescription(announcement Announcement) string {
+	description := normalizeText(announcement.description)
+	if runes := []rune(description); len(runes) > propertyDescriptionMaxLength {
+		description = string(runes[:propertyDescriptionMaxLength])
+	}
+	return description
+}
+
+/**
+ * propertyIndexFile est le contenu sérialisé du fichier des biens regroupés.
+ * @property {int} Version - Version du format du fichier.
+ * @property {[]Property} Properties - Les biens.
+ */
+type propertyIndexFile struct {
+	Version    int         `json:"version"`
+	Properties []*Property `json:"properties"`
+}
+
+/**
+ * PropertyIndex est le stockage sur disque des biens regroupés, pour reconnaître une annonce
+ * d'un bien déjà publié par une autre agence.
+ * @property {sync.Mutex} mutex - Verrou protégeant les biens.
+ * @property {string} path - Chemin du fichier.
+ * @property {[]Property} properties - Les biens.
+ * @property {bool} readOnly - true si les biens ne sont jamais réécrits sur disque (simulation).
+ */
+type PropertyIndex struct {
+	mutex      sync.Mutex
+	path       string
+	properties []*Property
+	readOnly   bool
+}
+
+/**
+ * OpenPropertyIndex charge les biens depuis le fichier indiqué, ou crée un index vide si le fichier n'existe pas.
+ * @param {string} path - Chemin du fichier.
+ * @return {PropertyIndex} - L'index chargé.
+ * @return {error} - Erreur si le fichier existe mais ne peut pas être lu.
+ */
+func OpenPropertyIndex(path string) (*PropertyIndex, error) {
+	index := &PropertyIndex{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lecture des biens %s : %w", path, err)
+	}
+
+	var file propertyIndexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("décodage des biens %s : %w", path, err)
+	}
+	if file.Version > propertyIndexVersion {
+		return nil, fmt.Errorf("version des biens %s non supportée : %d", path, file.Version)
+	}
+
+	for _, property := range file.Properties {
+		var photoHash *uint64
+		if hash, err := strconv.ParseUint(property.PhotoHash, 16, 64); err == nil {
+			photoHash = &hash
+		}
+		property.fingerprint = newPropertyFingerprint(property.Rent, property.Surface, property.Rooms, property.Postcode, property.Description, photoHash)
+		index.properties = append(index.properties, property)
+	}
+
+	return index, nil
+}
+
+/**
+ * SetReadOnly empêche toute réécriture des biens sur disque (simulation).
+ * @return {void}
+ */
+func (index *PropertyIndex) SetReadOnly() {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.readOnly = true
+}
+
+/**
+ * Save écrit les biens sur disque, après suppression des biens sans annonce rattachée depuis la durée de conservation.
+ * @param {time.Duration} retention - Durée de conservation d'un bien.
+ * @return {error} - Erreur lors de l'écriture.
+ */
+func (index *PropertyIndex) Save(retention time.Duration) error {
+	index.mutex.Lock()
+	if index.readOnly {
+		index.mutex.Unlock()
+		return nil
+	}
+	limit := time.Now().Add(-retention)
+	kept := index.properties[:0]
+	for _, property := range index.properties {
+		if property.LastSeen.After(limit) {
+			kept = append(kept, property)
+		}
+	}
+	index.properties = kept
+
+	file := propertyIndexFile{Version: propertyIndexVersion}
+	for _, property := range index.properties {
+		copied := *property
+		copied.Listings = append([]PropertyListing(nil), property.Listings...)
+		file.Properties = append(file.Properties, &copied)
+	}
+	index.mutex.Unlock()
+
+	sort.Slice(file.Properties, func(i, j int) bool { return file.Properties[i].ID < file.Properties[j].ID })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encodage des biens : %w", err)
+	}
+
+	return writeFileAtomic(index.path, data)
+}
+
+/**
+ * dedupCandidate est une nouvelle annonce en attente de regroupement.
+ * @property {SearchTarget} target - La recherche ayant trouvé l'annonce.
+ * @property {Announcement} announcement - L'annonce.
+ * @property {bool} notify - false pour une annonce marquée comme vue sans notification (premier scraping).
+ * @property {propertyFingerprint} fingerprint - L'empreinte de l'annonce.
+ * @property {string} photoHash - L'empreinte de la première photo, en hexadécimal.
+ */
+type dedupCandidate struct {
+	target       SearchTarget
+	announcement Announcement
+	notify       bool
+	fingerprint  propertyFingerprint
+	photoHash    string
+}
+
+/**
+ * propertyGroup regroupe les nouvelles annonces d'un bien apparu pendant le cycle.
+ * @property {Property} property - Le bien créé pendant le cycle.
+ * @property {[]dedupCandidate} candidates - Les annonces du bien trouvées pendant le cycle.
+ */
+type propertyGroup struct {
+	property   *Property
+	candidates []dedupCandidate
+}
+
+/**
+ * Deduplicator regroupe les nouvelles annonces d'un cycle de scraping par bien : un bien publié par plusieurs agences
+ * donne une seule notification listant toutes ses annonces, et un bien déjà notifié n'est pas notifié de nouveau
+ * quand une autre agence le publie.
+ * @property {PropertyIndex} index - Les biens connus.
+ * @property {http.Client} client - Le client HTTP de téléchargement des photos, nil pour ne pas comparer les photos.
+ * @property {sync.Mutex} mutex - Verrou protégeant les annonces en attente.
+ * @property {[]dedupCandidate} pending - Les nouvelles annonces du cycle en cours.
+ */
+type Deduplicator struct {
+	index   *PropertyIndex
+	client  *http.Client
+	mutex   sync.Mutex
+	pending []dedupCandidate
+}
+
+/**
+ * NewDeduplicator crée le regroupement des annonces par bien.
+ * @param {PropertyIndex} index - Les biens connus.
+ * @param {bool} photoHash - Si true, les photos sont téléchargées pour comparer les biens.
+ * @return {Deduplicator} - Le regroupement.
+ */
+func NewDeduplicator(index *PropertyIndex, photoHash bool) *Deduplicator {
+	dedup := &Deduplicator{index: index}
+	if photoHash {
+		dedup.client = &http.Client{Timeout: notifierHTTPTimeout}
+	}
+	return dedup
+}
+
+/**
+ * Add met une nouvelle annonce en attente de regroupement jusqu'à la fin du cycle.
+ * La première photo est téléchargée ici, en parallèle des autres recherches.
+ * @param {context.Context} ctx - Contexte d'annulation du téléchargement de la photo.
+ * @param {SearchTarget} target - La recherche ayant trouvé l'annonce.
+ * @param {Announcement} announcement - L'annonce.
+ * @param {bool} notify - false pour une annonce marquée comme vue sans notification.
+ * @return {void}
+ */
+func (dedup *Deduplicator) Add(ctx context.Context, target SearchTarget, announcement Announcement, notify bool) {
+	candidate := dedupCandidate{target: target, announcement: announcement, notify: notify}
+
+	var photoHash *uint64
+	if dedup.client != nil && len(announcement.photoURLs) > 0 {
+		hash, err := fetchPhotoHash(ctx, dedup.photoClient(ctx), announcement.photoURLs[0])
+		if err != nil {
+			dedupLog.WarnContext(ctx, "Empreinte de la photo de l'annonce indisponible", "reference", announcement.propertyReference, "error", err)
+		} else {
+			photoHash = &hash
+			candidate.photoHash = fmt.Sprintf("%016x", hash)
+		}
+	}
+	candidate.fingerprint = newPropertyFingerprint(announcement.rent, announcement.surface, announcement.rooms, announcement.postcode, propertyDescription(announcement), photoHash)
+
+	dedup.mutex.Lock()
+	dedup.pending = append(dedup.pending, candidate)
+	dedup.mutex.Unlock()
+}
+
+/**
+ * photoClient retourne le client HTTP de téléchargement des photos : si le contexte porte une cassette,
+ * les photos sont enregistrées ou rejouées avec les pages des agences.
+ * @param {context.Context} ctx - Le contexte du scraping.
+ * @return {http.Client} - Le client HTTP.
+ */
+func (dedup *Deduplicator) photoClient(ctx context.Context) *http.Client {
+	cassette := cassetteFromContext(ctx)
+	if cassette == nil {
+		return dedup.client
+	}
+	return &http.Client{Timeout: dedup.client.Timeout, Transport: cassette.wrap(http.DefaultTransport)}
+}
+
+/**
+ * Flush regroupe les annonces en attente par bien et envoie une notification par nouveau bien.
+ * Une annonce d'un bien déjà connu est rattachée au bien sans notification. Après interruption, ou si aucun service
+ * n'a reçu la notification, les nouvelles annonces des biens non notifiés sont oubliées du stockage : elles seront notifiées
+ * au prochain cycle. Les services en échec alors que d'autres ont reçu la notification reçoivent l'annonce principale
+ * du bien au scraping suivant.
+ * @param {context.Context} ctx - Contexte d'annulation des notifications.
+ * @param {SeenStore} store - Stockage des références déjà traitées.
+ * @param {Notifier} notifier - Services de notification.
+ * @return {void}
+ */
+func (dedup *Deduplicator) Flush(ctx context.Context, store *SeenStore, notifier Notifier) {
+	dedup.mutex.Lock()
+	pending := dedup.pending
+	dedup.pending = nil
+	dedup.mutex.Unlock()
+
+	groups := dedup.group(pending, time.Now())
+
+	for _, group := range groups {
+		if ctx.Err() != nil {
+			dedupLog.WarnContext(ctx, "Notification du bien interrompue par l'arrêt du scraper", "property", group.property.ID)
+			dedup.forget(store, group)
+			continue
+		}
+
+		receipts, err := notifyWithReceipts(ctx, notifier, newPropertyMessage(group))
+		failed, delivered := deliveryFailures(notifier, err)
+		if !delivered {
+			// Aucun service n'a reçu la notification : oublier le bien et ses annonces pour la retenter au prochain cycle
+			dedupLog.ErrorContext(ctx, "Erreur lors de l'envoi de la notification du bien", "property", group.property.ID, "error", err)
+			dedup.forget(store, group)
+			continue
+		}
+		if err != nil {
+			dedupLog.ErrorContext(ctx, "Notification du bien non reçue par certains services : nouvel essai au prochain scraping", "property", group.property.ID, "services", failed, "error", err)
+		}
+
+		// Les services en échec sont retentés sur l'annonce principale du bien, une seule fois par bien
+		pending := failed
+		for _, candidate := range group.candidates {
+			if !candidate.notify {
+				store.MarkNotified(candidate.target.Agency, candidate.announcement.propertyReference, receipts, nil)
+				continue
+			}
+			store.MarkNotified(candidate.target.Agency, candidate.announcement.propertyReference, receipts, pending)
+			pending = nil
+		}
+	}
+}
+
+/**
+ * group rattache chaque annonce en attente à un bien connu, ou à un nouveau bien.
+ * @param {[]dedupCandidate} pending - Les annonces en attente.
+ * @param {time.Time} now - La date du cycle.
+ * @return {[]propertyGroup} - Les nouveaux biens ayant au moins une annonce à notifier.
+ */
+func (dedup *Deduplicator) group(pending []dedupCandidate, now time.Time) []*propertyGroup {
+	dedup.index.mutex.Lock()
+	defer dedup.index.mutex.Unlock()
+
+	groups := make(map[*Property]*propertyGroup)
+	var order []*propertyGroup
+
+	for _, candidate := range pending {
+		agency := candidate.target.Agency
+		listing := PropertyListing{Agency: agency, Reference: candidate.announcement.propertyReference, URL: candidate.announcement.url}
+
+		// Rechercher un bien publié par une autre agence, ou une autre agence du même réseau
+		var property *Property
+		for _, known := range dedup.index.properties {
+			if !known.hasListing(agency, listing.Reference) && known.fingerprint.matches(candidate.fingerprint) {
+				property = known
+				break
+			}
+		}
+
+		if property == nil {
+			property = &Property{
+				ID:          string(agency) + ":" + listing.Reference,
+				Rent:        candidate.announcement.rent,
+				Surface:     candidate.announcement.surface,
+				Rooms:       candidate.announcement.rooms,
+				Postcode:    candidate.announcement.postcode,
+				Description: propertyDescription(candidate.announcement),
+				PhotoHash:   candidate.photoHash,
+				FirstSeen:   now,
+				fingerprint: candidate.fingerprint,
+			}
+			dedup.index.properties = append(dedup.index.properties, property)
+			groups[property] = &propertyGroup{property: property}
+			order = append(order, groups[property])
+		} else if groups[property] == nil {
+			dedupLog.Info("Annonce rattachée à un bien déjà connu : pas de nouvelle notification", "agency", agency, "reference", listing.Reference, "property", property.ID)
+		}
+
+		property.Listings = append(property.Listings, listing)
+		property.LastSeen = now
+		if group := groups[property]; group != nil {
+			group.candidates = append(group.candidates, candidate)
+		}
+	}
+
+	// Seuls les biens ayant une annonce à notifier donnent un message
+	var notified []*propertyGroup
+	for _, group := range order {
+		for _, candidate := range group.candidates {
+			if candidate.notify {
+				notified = append(notified, group)
+				break
+			}
+		}
+	}
+	return notified
+}
+
+/**
+ * forget retire un bien non notifié de l'index et ses nouvelles annonces du stockage. Les annonces du premier scraping
+ * d'une agence restent enregistrées : elles ne sont jamais notifiées.
+ * @param {SeenStore} store - Stockage des références déjà traitées.
+ * @param {propertyGroup} group - Le bien non notifié.
+ * @return {void}
+ */
+func (dedup *Deduplicator) forget(store *SeenStore, group *propertyGroup) {
+	dedup.index.mutex.Lock()
+	for i, property := range dedup.index.properties {
+		if property == group.property {
+			dedup.index.properties = append(dedup.index.properties[:i], dedup.index.properties[i+1:]...)
+			break
+		}
+	}
+	dedup.index.mutex.Unlock()
+
+	for _, candidate := range group.candidates {
+		if candidate.notify {
+			store.Forget(candidate.target.Agency, candidate.announcement.propertyReference)
+		}
+	}
+}
+
+/**
+ * newPropertyMessage construit la notification d'un nouveau bien : le détail de la première annonce notifiée,
+ * suivi des autres agences publiant le bien, avec un bouton par annonce.
+ * @param {propertyGroup} group - Le nouveau bien.
+ * @return {Message} - Le message à envoyer.
+ */
+func newPropertyMessage(group *propertyGroup) Message {
+	var main dedupCandidate
+	for _, candidate := range group.candidates {
+		if candidate.notify {
+			main = candidate
+			break
+		}
+	}
+
+	message := newAnnouncementMessage(main.target, main.announcement)
+	if len(group.property.Listings) < 2 {
+		return message
+	}
+
+	message.Listings = append([]PropertyListing(nil), group.property.Listings...)
+	message.Buttons = nil
+	var others []string
+	for _, listing := range message.Listings {
+		if listing.URL != "" {
+			message.Buttons = append(message.Buttons, MessageButton{Label: fmt.Sprintf("Voir chez %s", listing.Agency), URL: listing.URL})
+		}
+		if listing.Agency != main.target.Agency || listing.Reference != main.announcement.propertyReference {
+			others = append(others, strings.TrimSpace(fmt.Sprintf("- %s : %s", listing.Agency, listing.URL)))
+		}
+	}
+	message.Text += "\nAussi chez :\n" + strings.Join(others, "\n")
+	return message
+}
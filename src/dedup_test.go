@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubNotifier enregistre les messages envoyés, ou échoue si err est renseignée
+type stubNotifier struct {
+	name     string
+	err      error
+	messages []Message
+}
+
+func (stub *stubNotifier) Name() string {
+	if stub.name == "" {
+		return "stub"
+	}
+	return stub.name
+}
+
+func (stub *stubNotifier) Notify(_ context.Context, message Message) error {
+	if stub.err != nil {
+		return stub.err
+	}
+	stub.messages = append(stub.messages, message)
+	return nil
+}
+
+// Descriptions normalisées de deux logements voisins
+const (
+	dedupDescriptionT2       = "appartement t2 lumineux au troisieme etage avec balcon proche metro sainte anne"
+	dedupDescriptionT2Agency = "appartement t2 lumineux au troisieme etage avec balcon proche metro et commerces"
+	dedupDescriptionOther    = "studio renove en rez de chaussee sur cour calme quartier gare"
+)
+
+func TestPropertyFingerprintMatches(t *testing.T) {
+	structured := func(rent float64, surface float64, rooms int, postcode string) propertyFingerprint {
+		return newPropertyFingerprint(pointer(rent), pointer(surface), pointer(rooms), postcode, "", nil)
+	}
+	withSignals := func(fingerprint propertyFingerprint, description string, photoHash *uint64) propertyFingerprint {
+		fingerprint.shingles = textShingles(description)
+		fingerprint.photoHash = photoHash
+		return fingerprint
+	}
+	t2 := structured(650, 45, 2, "35000")
+
+	tests := []struct {
+		name string
+		a, b propertyFingerprint
+		want bool
+	}{
+		{name: "attributs structurés concordants", a: t2, b: structured(655, 45.8, 2, "35000"), want: true},
+		{name: "loyer différent", a: t2, b: structured(700, 45, 2, "35000"), want: false},
+		{name: "surface différente", a: t2, b: structured(650, 48, 2, "35000"), want: false},
+		{name: "pièces différentes", a: t2, b: structured(650, 45, 3, "35000"), want: false},
+		{name: "code postal différent", a: t2, b: structured(650, 45, 2, "35700"), want: false},
+		{name: "attributs structurés incomplets", a: t2, b: newPropertyFingerprint(pointer(650.0), pointer(45.0), pointer(2), "", "", nil), want: false},
+		{
+			name: "photo quasi identique",
+			a:    withSignals(t2, "", pointer(uint64(0xff00ff00ff00ff00))),
+			b:    withSignals(t2, "", pointer(uint64(0xff00ff00ff00ff03))),
+			want: true,
+		},
+		{
+			// Logements identiques d'un même immeuble : les photos départagent les annonces
+			name: "photos différentes",
+			a:    withSignals(t2, "", pointer(uint64(0xff00ff00ff00ff00))),
+			b:    withSignals(t2, "", pointer(uint64(0x00ff00ff00ff00ff))),
+			want: false,
+		},
+		{
+			name: "descriptions similaires sans loyer",
+			a:    newPropertyFingerprint(nil, pointer(45.0), nil, "35000", dedupDescriptionT2, nil),
+			b:    newPropertyFingerprint(pointer(650.0), nil, pointer(2), "", dedupDescriptionT2Agency, nil),
+			want: true,
+		},
+		{name: "descriptions différentes", a: withSignals(t2, dedupDescriptionT2, nil), b: withSignals(t2, dedupDescriptionOther, nil), want: false},
+		{
+			name: "photo identique mais descriptions différentes",
+			a:    withSignals(t2, dedupDescriptionT2, pointer(uint64(42))),
+			b:    withSignals(t2, dedupDescriptionOther, pointer(uint64(42))),
+			want: false,
+		},
+		{
+			// Une description trop courte n'est pas comparable : elle ne départage pas les annonces
+			name: "description trop courte",
+			a:    withSignals(t2, "t2 rennes centre", nil),
+			b:    withSignals(t2, dedupDescriptionOther, nil),
+			want: true,
+		},
+		{
+			name: "photo identique mais loyer différent",
+			a:    withSignals(t2, "", pointer(uint64(42))),
+			b:    withSignals(structured(800, 45, 2, "35000"), "", pointer(uint64(42))),
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.a.matches(test.b); got != test.want {
+				t.Errorf("matches = %v, attendu %v", got, test.want)
+			}
+			if got := test.b.matches(test.a); got != test.want {
+				t.Errorf("matches (inversé) = %v, attendu %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestJaccardSimilarity(t *testing.T) {
+	a := textShingles("un deux trois quatre")
+	b := textShingles("deux trois quatre cinq")
+	if got := jaccardSimilarity(a, b); got != 1.0/3 {
+		t.Errorf("jaccardSimilarity = %v, attendu 1/3", got)
+	}
+	if got := jaccardSimilarity(a, a); got != 1 {
+		t.Errorf("jaccardSimilarity identique = %v, attendu 1", got)
+	}
+	if got := jaccardSimilarity(nil, nil); got != 0 {
+		t.Errorf("jaccardSimilarity vide = %v, attendu 0", got)
+	}
+}
+
+// dedupTestCandidate construit une nouvelle annonce d'un T2 à Rennes
+func dedupTestCandidate(agency Agency, reference string, notify bool) dedupCandidate {
+	announcement := Announcement{
+		propertyReference: reference,
+		url:               "https://www.example.fr/" + reference,
+		rent:              pointer(650.0),
+		surface:           pointer(45.0),
+		rooms:             pointer(2),
+		postcode:          "35000",
+	}
+	return dedupCandidate{
+		target:       SearchTarget{Agency: agency, Title: string(agency)},
+		announcement: announcement,
+		notify:       notify,
+		fingerprint:  newPropertyFingerprint(announcement.rent, announcement.surface, announcement.rooms, announcement.postcode, "", nil),
+	}
+}
+
+func TestDeduplicatorGroup(t *testing.T) {
+	now := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("même cycle, plusieurs agences", func(t *testing.T) {
+		dedup := NewDeduplicator(&PropertyIndex{}, false)
+		groups := dedup.group([]dedupCandidate{
+			dedupTestCandidate(Afedim, "A1", true),
+			dedupTestCandidate(Giboire, "G1", true),
+		}, now)
+
+		if len(groups) != 1 || len(groups[0].candidates) != 2 {
+			t.Fatalf("groupes = %+v, attendu un bien avec deux annonces", groups)
+		}
+		if property := groups[0].property; property.ID != "Afedim:A1" || len(property.Listings) != 2 || !property.LastSeen.Equal(now) {
+			t.Errorf("bien = %+v", property)
+		}
+	})
+
+	t.Run("agences d'un même réseau", func(t *testing.T) {
+		// Deux agences Nestenn publient le même bien sous deux références
+		dedup := NewDeduplicator(&PropertyIndex{}, false)
+		groups := dedup.group([]dedupCandidate{
+			dedupTestCandidate(Nestenn, "N1", true),
+			dedupTestCandidate(Nestenn, "N2", true),
+		}, now)
+		if len(groups) != 1 || len(groups[0].candidates) != 2 {
+			t.Fatalf("groupes = %+v, attendu un bien avec deux annonces", groups)
+		}
+		if message := newPropertyMessage(groups[0]); !strings.Contains(message.Text, "Aussi chez :\n- Nestenn : https://www.example.fr/N2") {
+			t.Errorf("message = %q, attendu l'annonce de l'autre agence du réseau", message.Text)
+		}
+	})
+
+	t.Run("même annonce vue deux fois", func(t *testing.T) {
+		// Une annonce déjà rattachée au bien n'est pas un doublon d'elle-même
+		known := dedupTestCandidate(Afedim, "A1", true)
+		property := &Property{ID: "Afedim:A1", Listings: []PropertyListing{{Agency: Afedim, Reference: "A1"}}, fingerprint: known.fingerprint}
+		dedup := NewDeduplicator(&PropertyIndex{properties: []*Property{property}}, false)
+
+		if groups := dedup.group([]dedupCandidate{dedupTestCandidate(Afedim, "A1", true)}, now); len(groups) != 1 || groups[0].property == property {
+			t.Errorf("groupes = %+v, attendu un nouveau bien", groups)
+		}
+		if len(property.Listings) != 1 {
+			t.Errorf("annonces du bien connu = %+v, attendu inchangées", property.Listings)
+		}
+	})
+
+	t.Run("bien déjà connu", func(t *testing.T) {
+		known := dedupTestCandidate(Giboire, "G1", true)
+		property := &Property{ID: "Giboire:G1", Listings: []PropertyListing{{Agency: Giboire, Reference: "G1"}}, fingerprint: known.fingerprint}
+		dedup := NewDeduplicator(&PropertyIndex{properties: []*Property{property}}, false)
+
+		if groups := dedup.group([]dedupCandidate{dedupTestCandidate(Afedim, "A1", true)}, now); len(groups) != 0 {
+			t.Errorf("groupes = %d, un bien déjà connu ne doit pas être notifié de nouveau", len(groups))
+		}
+		if len(property.Listings) != 2 || property.Listings[1].Reference != "A1" || !property.LastSeen.Equal(now) {
+			t.Errorf("annonce non rattachée au bien connu : %+v", property)
+		}
+		if len(dedup.index.properties) != 1 {
+			t.Errorf("biens = %d, attendu 1", len(dedup.index.properties))
+		}
+	})
+
+	t.Run("premier scraping sans notification", func(t *testing.T) {
+		dedup := NewDeduplicator(&PropertyIndex{}, false)
+		if groups := dedup.group([]dedupCandidate{dedupTestCandidate(Afedim, "A1", false)}, now); len(groups) != 0 {
+			t.Errorf("groupes = %d, une annonce du premier scraping ne doit pas être notifiée", len(groups))
+		}
+		// Le bien est tout de même connu : une autre agence le publiant ensuite n'est pas notifiée
+		if len(dedup.index.properties) != 1 {
+			t.Fatalf("biens = %d, attendu 1", len(dedup.index.properties))
+		}
+		if groups := dedup.group([]dedupCandidate{dedupTestCandidate(Giboire, "G1", true)}, now); len(groups) != 0 {
+			t.Errorf("groupes = %d, attendu 0", len(groups))
+		}
+	})
+
+	t.Run("premier scraping d'une agence et nouvelle annonce d'une autre", func(t *testing.T) {
+		dedup := NewDeduplicator(&PropertyIndex{}, false)
+		groups := dedup.group([]dedupCandidate{
+			dedupTestCandidate(Afedim, "A1", false),
+			dedupTestCandidate(Giboire, "G1", true),
+		}, now)
+		if len(groups) != 1 || len(groups[0].candidates) != 2 {
+			t.Fatalf("groupes = %+v, attendu un bien avec deux annonces", groups)
+		}
+		if message := newPropertyMessage(groups[0]); message.Agency != Giboire || len(message.Buttons) != 2 {
+			t.Errorf("message = %+v, attendu l'annonce Giboire avec un bouton par agence", message)
+		}
+	})
+}
+
+func TestDeduplicatorFlush(t *testing.T) {
+	now := time.Now()
+	newStore := func(t *testing.T, candidates ...dedupCandidate) *SeenStore {
+		t.Helper()
+		store, err := OpenSeenStore(filepath.Join(t.TempDir(), "seen.json"))
+		if err != nil {
+			t.Fatalf("OpenSeenStore : %v", err)
+		}
+		for _, candidate := range candidates {
+			store.Touch(candidate.target.Agency, "", candidate.announcement, now)
+		}
+		return store
+	}
+	candidates := []dedupCandidate{dedupTestCandidate(Afedim, "A1", true), dedupTestCandidate(Giboire, "G1", true)}
+
+	t.Run("notification envoyée", func(t *testing.T) {
+		store := newStore(t, candidates...)
+		dedup := NewDeduplicator(&PropertyIndex{}, false)
+		dedup.pending = append([]dedupCandidate(nil), candidates...)
+		notifier := &stubNotifier{}
+
+		dedup.Flush(context.Background(), store, notifier)
+		if len(notifier.messages) != 1 {
+			t.Fatalf("messages = %d, attendu 1", len(notifier.messages))
+		}
+		for _, entry := range append(store.Entries(Afedim), store.Entries(Giboire)...) {
+			if !entry.Notified {
+				t.Errorf("annonce %s %s non marquée notifiée", entry.Agency, entry.PropertyReference)
+			}
+		}
+	})
+
+	t.Run("échec de l'envoi", func(t *testing.T) {
+		// Le bien et ses nouvelles annonces sont oubliés : la notification est retentée au prochain cycle
+		warmup := dedupTestCandidate(Guenno, "W1", false)
+		store := newStore(t, append(candidates, warmup)...)
+		dedup := NewDeduplicator(&PropertyIndex{}, false)
+		dedup.pending = append([]dedupCandidate{warmup}, candidates...)
+
+		failing := &MultiNotifier{notifiers: []Notifier{
+			&stubNotifier{name: "telegram", err: errors.New("Telegram indisponible")},
+			&stubNotifier{name: "email", err: errors.New("SMTP indisponible")},
+		}}
+		dedup.Flush(context.Background(), store, failing)
+		if entries := append(store.Entries(Afedim), store.Entries(Giboire)...); len(entries) != 0 {
+			t.Errorf("références conservées après l'échec : %+v", entries)
+		}
+		if len(dedup.index.properties) != 0 {
+			t.Errorf("biens = %d, attendu 0", len(dedup.index.properties))
+		}
+		// L'annonce du premier scraping reste vue, sans notification
+		if entries := store.Entries(Guenno); len(entries) != 1 || entries[0].Notified {
+			t.Errorf("annonce du premier scraping = %+v, attendu conservée et non notifiée", entries)
+		}
+	})
+
+	t.Run("échec d'un service", func(t *testing.T) {
+		// Le bien est notifié : seul le service en échec est retenté, sur l'annonce principale
+		store := newStore(t, candidates...)
+		dedup := NewDeduplicator(&PropertyIndex{}, false)
+		dedup.pending = append([]dedupCandidate(nil), candidates...)
+		telegram := &stubNotifier{name: "telegram"}
+
+		dedup.Flush(context.Background(), store, &MultiNotifier{notifiers: []Notifier{telegram, &stubNotifier{name: "email", err: errors.New("SMTP indisponible")}}})
+		if len(telegram.messages) != 1 || len(dedup.index.properties) != 1 {
+			t.Fatalf("messages = %d, biens = %d, attendu 1 et 1", len(telegram.messages), len(dedup.index.properties))
+		}
+		afedim, giboire := store.Entries(Afedim), store.Entries(Giboire)
+		if len(afedim) != 1 || !afedim[0].Notified || !slices.Equal(afedim[0].Pending, []string{"email"}) {
+			t.Errorf("annonce principale = %+v, attendu notifiée avec l'e-mail à retenter", afedim)
+		}
+		if len(giboire) != 1 || !giboire[0].Notified || len(giboire[0].Pending) != 0 {
+			t.Errorf("annonce rattachée = %+v, attendu notifiée sans service à retenter", giboire)
+		}
+	})
+}
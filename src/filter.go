@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+/**
+ * FilterRules est l'ensemble des critères qu'une annonce doit respecter pour être notifiée.
+ * Chaque critère absent de la configuration est ignoré.
+ * @property {*float64} MaxRent - Loyer maximal en euros.
+ * @property {*float64} MinSurface - Surface minimale en m².
+ * @property {*int} MinRooms - Nombre minimal de pièces.
+ * @property {[]string} Postcodes - Codes postaux acceptés.
+ * @property {[]string} Cities - Villes acceptées.
+ * @property {*bool} Furnished - true pour les biens meublés uniquement, false pour les biens vides uniquement.
+ * @property {[]string} IncludeKeywords - Au moins un de ces mots-clés doit apparaître dans le titre ou la description.
+ * @property {[]string} ExcludeKeywords - Aucun de ces mots-clés ne doit apparaître dans le titre ou la description.
+ * @property {bool} RejectUnknown - Si true, une annonce dont l'agence n'expose pas l'information filtrée est rejetée.
+ */
+type FilterRules struct {
+	MaxRent         *float64 `yaml:"max_rent"`
+	MinSurface      *float64 `yaml:"min_surface"`
+	MinRooms        *int     `yaml:"min_rooms"`
+	Postcodes       []string `yaml:"postcodes"`
+	Cities          []string `yaml:"cities"`
+	Furnished       *bool    `yaml:"furnished"`
+	IncludeKeywords []string `yaml:"include_keywords"`
+	ExcludeKeywords []string `yaml:"exclude_keywords"`
+	RejectUnknown   *bool    `yaml:"reject_unknown"`
+}
+
+/**
+ * MergeFilterRules complète les critères d'une recherche avec les critères globaux.
+ * Un critère défini sur la recherche remplace le critère global.
+ * @param {FilterRules} global - Les critères globaux.
+ * @param {FilterRules} target - Les critères propres à la recherche.
+ * @return {FilterRules} - Les critères effectifs.
+ */
+func MergeFilterRules(global FilterRules, target FilterRules) FilterRules {
+	merged := global
+	if target.MaxRent != nil {
+		merged.MaxRent = target.MaxRent
+	}
+	if target.MinSurface != nil {
+		merged.MinSurface = target.MinSurface
+	}
+	if target.MinRooms != nil {
+		merged.MinRooms = target.MinRooms
+	}
+	if target.Postcodes != nil {
+		merged.Postcodes = target.Postcodes
+	}
+	if target.Cities != nil {
+		merged.Cities = target.Cities
+	}
+	if target.Furnished != nil {
+		merged.Furnished = target.Furnished
+	}
+	if target.IncludeKeywords != nil {
+		merged.IncludeKeywords = target.IncludeKeywords
+	}
+	if target.ExcludeKeywords != nil {
+		merged.ExcludeKeywords = target.ExcludeKeywords
+	}
+	if target.RejectUnknown != nil {
+		merged.RejectUnknown = target.RejectUnknown
+	}
+	return merged
+}
+
+/**
+ * Validate vérifie la cohérence des critères.
+ * @return {error} - Les erreurs de validation, ou nil.
+ */
+func (rules FilterRules) Validate() error {
+	var errs []error
+	if rules.MaxRent != nil && *rules.MaxRent <= 0 {
+		errs = append(errs, errors.New("max_rent doit être positif"))
+	}
+	if rules.MinSurface != nil && *rules.MinSurface < 0 {
+		errs = append(errs, errors.New("min_surface doit être positif"))
+	}
+	if rules.MinRooms != nil && *rules.MinRooms < 0 {
+		errs = append(errs, errors.New("min_rooms doit être positif"))
+	}
+	for _, postcode := range rules.Postcodes {
+		if len(postcode) != 5 || strings.Trim(postcode, "0123456789") != "" {
+			errs = append(errs, fmt.Errorf("code postal invalide : %q", postcode))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/**
+ * Evaluate applique les critères à une annonce.
+ * @param {Announcement} announcement - L'annonce à évaluer.
+ * @return {bool} - true si l'annonce respecte tous les critères.
+ * @return {string} - La raison du rejet, vide si l'annonce est acceptée.
+ */
+func (rules FilterRules) Evaluate(announcement Announcement) (bool, string) {
+	rejectUnknown := rules.RejectUnknown != nil && *rules.RejectUnknown
+
+	// unknown retourne le rejet à appliquer quand l'information filtrée est absente de l'annonce
+	unknown := func(field string) (bool, string) {
+		if rejectUnknown {
+			return false, field + " inconnu(e)"
+		}
+		return true, ""
+	}
+
+	if rules.MaxRent != nil {
+		if announcement.rent == nil {
+			if ok, reason := unknown("loyer"); !ok {
+				return ok, reason
+			}
+		} else if *announcement.rent > *rules.MaxRent {
+			return false, fmt.Sprintf("loyer %s € > %s €", formatNumber(*announcement.rent), formatNumber(*rules.MaxRent))
+		}
+	}
+
+	if rules.MinSurface != nil {
+		if announcement.surface == nil {
+			if ok, reason := unknown("surface"); !ok {
+				return ok, reason
+			}
+		} else if *announcement.surface < *rules.MinSurface {
+			return false, fmt.Sprintf("surface %s m² < %s m²", formatNumber(*announcement.surface), formatNumber(*rules.MinSurface))
+		}
+	}
+
+	if rules.MinRooms != nil {
+		if announcement.rooms == nil {
+			if ok, reason := unknown("nombre de pièces"); !ok {
+				return ok, reason
+			}
+		} else if *announcement.rooms < *rules.MinRooms {
+			return false, fmt.Sprintf("%d pièce(s) < %d", *announcement.rooms, *rules.MinRooms)
+		}
+	}
+
+	if len(rules.Postcodes) > 0 {
+		if announcement.postcode == "" {
+			if ok, reason := unknown("code postal"); !ok {
+				return ok, reason
+			}
+		} else if !containsNormalized(rules.Postcodes, announcement.postcode) {
+			return false, fmt.Sprintf("code postal %s non accepté", announcement.postcode)
+		}
+	}
+
+	if len(rules.Cities) > 0 {
+		if announcement.city == "" {
+			if ok, reason := unknown("ville"); !ok {
+				return ok, reason
+			}
+		} else if !containsNormalized(rules.Cities, announcement.city) {
+			return false, fmt.Sprintf("ville %s non acceptée", announcement.city)
+		}
+	}
+
+	if rules.Furnished != nil {
+		if announcement.furnished == nil {
+			if ok, reason := unknown("ameublement"); !ok {
+				return ok, reason
+			}
+		} else if *announcement.furnished != *rules.Furnished {
+			if *announcement.furnished {
+				return false, "bien meublé"
+			}
+			return false, "bien non meublé"
+		}
+	}
+
+	words := textWords(announcement.title + " " + announcement.description)
+
+	for _, keyword := range rules.ExcludeKeywords {
+		if containsKeyword(words, keyword) {
+			return false, fmt.Sprintf("mot-clé exclu %q", keyword)
+		}
+	}
+
+	if len(rules.IncludeKeywords) > 0 {
+		if len(words) == 0 {
+			if ok, reason := unknown("description"); !ok {
+				return ok, reason
+			}
+		} else if !containsAnyKeyword(words, rules.IncludeKeywords) {
+			return false, "aucun mot-clé requis trouvé"
+		}
+	}
+
+	return true, ""
+}
+
+/**
+ * textWords découpe un texte normalisé en mots (lettres et chiffres).
+ * @param {string} text - Le texte.
+ * @return {[]string} - Les mots normalisés.
+ */
+func textWords(text string) []string {
+	return strings.FieldsFunc(normalizeText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+/**
+ * containsKeyword indique si les mots contiennent un mot-clé entier (ou une suite de mots) : "cave" ne trouve pas "caves".
+ * @param {[]string} words - Les mots normalisés du texte.
+ * @param {string} keyword - Le mot-clé.
+ * @return {bool} - true si le mot-clé est trouvé.
+ */
+func containsKeyword(words []string, keyword string) bool {
+	keywordWords := textWords(keyword)
+	if len(keywordWords) == 0 {
+		return false
+	}
+	for i := 0; i+len(keywordWords) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(keywordWords)], keywordWords) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * containsAnyKeyword indique si les mots contiennent au moins un des mots-clés.
+ * @param {[]string} words - Les mots normalisés du texte.
+ * @param {[]string} keywords - Les mots-clés.
+ * @return {bool} - true si un mot-clé est trouvé.
+ */
+func containsAnyKeyword(words []string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if containsKeyword(words, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * containsNormalized indique si la valeur fait partie de la liste, sans tenir compte de la casse, des accents ni des tirets.
+ * @param {[]string} values - La liste de référence.
+ * @param {string} value - La valeur recherchée.
+ * @return {bool} - true si la valeur est dans la liste.
+ */
+func containsNormalized(values []string, value string) bool {
+	normalized := normalizeText(value)
+	for _, candidate := range values {
+		if normalizeText(candidate) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// Remplacement des caractères accentués et des séparateurs pour comparer des textes saisis différemment
+var normalizeReplacer = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i",
+	"ô", "o", "ö", "o",
+	"ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "œ", "oe", "æ", "ae",
+	"-", " ", "'", " ", "’", " ",
+)
+
+/**
+ * normalizeText met un texte en minuscules, sans accents et avec des espaces normalisés.
+ * @param {string} text - Le texte à normaliser.
+ * @return {string} - Le texte normalisé.
+ */
+func normalizeText(text string) string {
+	return cleanText(normalizeReplacer.Replace(strings.ToLower(text)))
+}
@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestFilterRulesEvaluate(t *testing.T) {
+	t2 := Announcement{
+		title:       "Appartement T2 Saint-Hélier",
+		description: "T2 meublé au 1er étage, avec cave et parking, proche métro.",
+		rent:        pointer(650.0),
+		surface:     pointer(45.0),
+		rooms:       pointer(2),
+		city:        "Rennes",
+		postcode:    "35000",
+		furnished:   pointer(true),
+	}
+	unknownT2 := Announcement{title: "Appartement T2"}
+
+	tests := []struct {
+		name         string
+		rules        FilterRules
+		announcement Announcement
+		want         bool
+		reason       string
+	}{
+		{name: "aucun critère", announcement: t2, want: true},
+		{name: "loyer accepté", rules: FilterRules{MaxRent: pointer(650.0)}, announcement: t2, want: true},
+		{name: "loyer trop élevé", rules: FilterRules{MaxRent: pointer(600.0)}, announcement: t2, reason: "loyer 650 € > 600 €"},
+		{name: "surface trop petite", rules: FilterRules{MinSurface: pointer(50.0)}, announcement: t2, reason: "surface 45 m² < 50 m²"},
+		{name: "pièces insuffisantes", rules: FilterRules{MinRooms: pointer(3)}, announcement: t2, reason: "2 pièce(s) < 3"},
+		{name: "code postal non accepté", rules: FilterRules{Postcodes: []string{"35700"}}, announcement: t2, reason: "code postal 35000 non accepté"},
+		{name: "bien meublé", rules: FilterRules{Furnished: pointer(false)}, announcement: t2, reason: "bien meublé"},
+
+		// Villes comparées sans casse, accents ni tirets
+		{name: "ville en majuscules", rules: FilterRules{Cities: []string{"rennes"}}, announcement: Announcement{city: "RENNES"}, want: true},
+		{name: "ville avec tirets", rules: FilterRules{Cities: []string{"Cesson Sevigne"}}, announcement: Announcement{city: "Cesson-Sévigné"}, want: true},
+		{name: "ville non acceptée", rules: FilterRules{Cities: []string{"Rennes"}}, announcement: Announcement{city: "Saint-Grégoire"}, reason: "ville Saint-Grégoire non acceptée"},
+
+		// Informations absentes : acceptées par défaut, rejetées avec reject_unknown
+		{name: "loyer inconnu accepté", rules: FilterRules{MaxRent: pointer(600.0)}, announcement: unknownT2, want: true},
+		{name: "loyer inconnu rejeté", rules: FilterRules{MaxRent: pointer(600.0), RejectUnknown: pointer(true)}, announcement: unknownT2, reason: "loyer inconnu(e)"},
+		{name: "surface inconnue rejetée", rules: FilterRules{MinSurface: pointer(30.0), RejectUnknown: pointer(true)}, announcement: unknownT2, reason: "surface inconnu(e)"},
+		{name: "code postal inconnu rejeté", rules: FilterRules{Postcodes: []string{"35000"}, RejectUnknown: pointer(true)}, announcement: unknownT2, reason: "code postal inconnu(e)"},
+		{name: "ameublement inconnu rejeté", rules: FilterRules{Furnished: pointer(true), RejectUnknown: pointer(true)}, announcement: unknownT2, reason: "ameublement inconnu(e)"},
+		{name: "reject_unknown désactivé", rules: FilterRules{Cities: []string{"Rennes"}, RejectUnknown: pointer(false)}, announcement: unknownT2, want: true},
+		{name: "description inconnue acceptée", rules: FilterRules{IncludeKeywords: []string{"balcon"}}, announcement: Announcement{}, want: true},
+		{name: "description inconnue rejetée", rules: FilterRules{IncludeKeywords: []string{"balcon"}, RejectUnknown: pointer(true)}, announcement: Announcement{}, reason: "description inconnu(e)"},
+
+		// Mots-clés : mots entiers, sans casse, accents ni tirets
+		{name: "mot-clé exclu", rules: FilterRules{ExcludeKeywords: []string{"Cave"}}, announcement: t2, reason: `mot-clé exclu "Cave"`},
+		{name: "mot-clé exclu dans un autre mot", rules: FilterRules{ExcludeKeywords: []string{"cave"}}, announcement: Announcement{description: "Grandes caves voûtées."}, want: true},
+		{name: "mot-clé exclu en début de mot", rules: FilterRules{ExcludeKeywords: []string{"park"}}, announcement: t2, want: true},
+		{name: "mot-clé accentué", rules: FilterRules{IncludeKeywords: []string{"metro"}}, announcement: t2, want: true},
+		{name: "mot-clé avec tiret", rules: FilterRules{IncludeKeywords: []string{"saint helier"}}, announcement: t2, want: true},
+		{name: "mot-clé de plusieurs mots", rules: FilterRules{IncludeKeywords: []string{"1er étage"}}, announcement: t2, want: true},
+		{name: "mots-clés non consécutifs", rules: FilterRules{IncludeKeywords: []string{"cave parking"}}, announcement: t2, reason: "aucun mot-clé requis trouvé"},
+		{name: "un des mots-clés requis", rules: FilterRules{IncludeKeywords: []string{"balcon", "parking"}}, announcement: t2, want: true},
+		{name: "mot-clé requis absent", rules: FilterRules{IncludeKeywords: []string{"balcon", "jardin"}}, announcement: t2, reason: "aucun mot-clé requis trouvé"},
+		{name: "mot-clé vide", rules: FilterRules{ExcludeKeywords: []string{" - "}}, announcement: t2, want: true},
+
+		// Un mot-clé exclu l'emporte sur un mot-clé requis
+		{name: "exclusion prioritaire", rules: FilterRules{IncludeKeywords: []string{"parking"}, ExcludeKeywords: []string{"cave"}}, announcement: t2, reason: `mot-clé exclu "cave"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, reason := test.rules.Evaluate(test.announcement)
+			if got != test.want || reason != test.reason {
+				t.Errorf("Evaluate = %v (%q), attendu %v (%q)", got, reason, test.want, test.reason)
+			}
+		})
+	}
+}
+
+func TestMergeFilterRules(t *testing.T) {
+	global := FilterRules{MaxRent: pointer(800.0), Cities: []string{"Rennes"}, ExcludeKeywords: []string{"colocation"}}
+	target := FilterRules{MaxRent: pointer(650.0), ExcludeKeywords: []string{}}
+
+	merged := MergeFilterRules(global, target)
+	if *merged.MaxRent != 650 || len(merged.Cities) != 1 || merged.ExcludeKeywords == nil || len(merged.ExcludeKeywords) != 0 {
+		t.Errorf("critères = %+v, attendu le loyer et les mots-clés de la recherche, les villes globales", merged)
+	}
+}
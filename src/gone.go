@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+/**
+ * GoneSettings regroupe les paramètres de détection des annonces retirées (biens loués).
+ * @property {int} MissedCycles - Nombre de scrapings complets consécutifs sans l'annonce avant de la considérer retirée.
+ * @property {bool} Notify - Si true, le retrait d'une annonce notifiée est signalé (en réponse à la notification d'origine sur Telegram).
+ */
+type GoneSettings struct {
+	MissedCycles int   `yaml:"missed_cycles"`
+	Notify       *bool `yaml:"notify"`
+}
+
+/**
+ * Sweep compte l'absence des annonces d'une recherche après un scraping complet, et retourne celles qui viennent
+ * d'être considérées retirées. Seules les annonces détectées en dernier par cette recherche sont concernées ;
+ * les annonces de l'agence dont la recherche n'est plus configurée (URL modifiée) sont reprises par cette recherche.
+ * @param {SearchTarget} target - La recherche scrapée.
+ * @param {map[string]bool} found - Les références trouvées par le scraping.
+ * @param {int} missedCycles - Nombre de scrapings complets consécutifs sans l'annonce avant de la considérer retirée.
+ * @param {time.Time} now - Date du scraping.
+ * @return {[]SeenEntry} - Copie des annonces retirées.
+ */
+func (store *SeenStore) Sweep(target SearchTarget, found map[string]bool, missedCycles int, now time.Time) []SeenEntry {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var gone []SeenEntry
+	for _, entry := range store.entries {
+		if entry.Agency != target.Agency || entry.GoneAt != nil || found[entry.PropertyReference] {
+			continue
+		}
+		if entry.Search != target.URL {
+			if !store.orphaned(entry) {
+				continue
+			}
+			entry.Search = target.URL
+		}
+
+		entry.Missed++
+		if entry.Missed >= missedCycles {
+			goneAt := now
+			entry.GoneAt = &goneAt
+			gone = append(gone, *entry)
+		}
+	}
+	return gone
+}
+
+/**
+ * orphaned indique si l'annonce a été détectée en dernier par une recherche qui n'est plus configurée.
+ * Les annonces enregistrées avant le suivi des recherches (sans recherche) ne sont pas reprises.
+ * @param {SeenEntry} entry - L'annonce.
+ * @return {bool} - true si la recherche de l'annonce n'existe plus.
+ */
+func (store *SeenStore) orphaned(entry *SeenEntry) bool {
+	return store.searches != nil && entry.Search != "" && !store.searches[seenKey(entry.Agency, entry.Search)]
+}
+
+/**
+ * timeOnMarket retourne la durée de mise en ligne d'une annonce retirée.
+ * @return {time.Duration} - Durée entre la première détection et le retrait, 0 si l'annonce est en ligne.
+ */
+func (entry *SeenEntry) timeOnMarket() time.Duration {
+	if entry.GoneAt == nil {
+		return 0
+	}
+	return entry.GoneAt.Sub(entry.FirstSeen)
+}
+
+/**
+ * MarketStats résume la durée de mise en ligne des annonces retirées d'une agence.
+ * @property {int} Count - Nombre d'annonces retirées.
+ * @property {time.Duration} Median - Durée médiane de mise en ligne.
+ * @property {time.Duration} Average - Durée moyenne de mise en ligne.
+ */
+type MarketStats struct {
+	Count   int
+	Median  time.Duration
+	Average time.Duration
+}
+
+/**
+ * TimeOnMarket calcule la durée de mise en ligne des annonces retirées de chaque agence.
+ * Les annonces trouvées par le premier scraping de l'agence sont exclues : leur date de mise en ligne est inconnue.
+ * @return {map[Agency]MarketStats} - Les statistiques par agence.
+ */
+func (store *SeenStore) TimeOnMarket() map[Agency]MarketStats {
+	store.mutex.Lock()
+	// Date du premier scraping de chaque agence : toutes ses annonces partagent la même date de première détection
+	firstScrape := make(map[Agency]time.Time)
+	for _, entry := range store.entries {
+		if first, exists := firstScrape[entry.Agency]; !exists || entry.FirstSeen.Before(first) {
+			firstScrape[entry.Agency] = entry.FirstSeen
+		}
+	}
+	durations := make(map[Agency][]time.Duration)
+	for _, entry := range store.entries {
+		if entry.GoneAt != nil && !entry.FirstSeen.Equal(firstScrape[entry.Agency]) {
+			durations[entry.Agency] = append(durations[entry.Agency], entry.timeOnMarket())
+		}
+	}
+	store.mutex.Unlock()
+
+	stats := make(map[Agency]MarketStats, len(durations))
+	for agency, values := range durations {
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		var total time.Duration
+		for _, value := range values {
+			total += value
+		}
+		stats[agency] = MarketStats{
+			Count:   len(values),
+			Median:  values[len(values)/2],
+			Average: total / time.Duration(len(values)),
+		}
+	}
+	return stats
+}
+
+/**
+ * newGoneMessage construit la notification du retrait d'une annonce, en réponse à sa notification d'origine.
+ * @param {SearchTarget} target - La recherche ayant détecté l'annonce.
+ * @param {SeenEntry} entry - L'annonce retirée.
+ * @return {Message} - Le message à envoyer.
+ */
+func newGoneMessage(target SearchTarget, entry SeenEntry) Message {
+	lines := []string{
+		target.Title,
+		"Loué / retiré",
+		"Référence : " + entry.PropertyReference,
+		"En ligne pendant " + formatAbsence(entry.timeOnMarket()),
+	}
+	if entry.URL != "" {
+		lines = append(lines, "URL : "+entry.URL)
+	}
+
+	return Message{
+		Title:   fmt.Sprintf("%s : annonce retirée", target.Title),
+		Text:    strings.Join(lines, "\n"),
+		URL:     entry.URL,
+		Agency:  target.Agency,
+		ReplyTo: entry.Receipts,
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+// goneReferences retourne les références triées des annonces retirées
+func goneReferences(entries []SeenEntry) []string {
+	references := []string{}
+	for _, entry := range entries {
+		references = append(references, entry.PropertyReference)
+	}
+	sort.Strings(references)
+	return references
+}
+
+func TestSeenStoreSweep(t *testing.T) {
+	const (
+		rennes = "https://www.afedim.fr/location?ville=rennes"
+		brest  = "https://www.afedim.fr/location?ville=brest"
+		// Ancienne URL de la recherche de Rennes, modifiée depuis dans la configuration
+		edited = "https://www.afedim.fr/location?ville=rennes&tri=date"
+	)
+	store, err := OpenSeenStore(filepath.Join(t.TempDir(), "seen.json"))
+	if err != nil {
+		t.Fatalf("OpenSeenStore : %v", err)
+	}
+	store.SetSearches([]SearchTarget{{Agency: Afedim, URL: rennes}, {Agency: Afedim, URL: brest}})
+
+	seenAt := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	for _, entry := range []struct {
+		agency    Agency
+		search    string
+		reference string
+	}{
+		{Afedim, rennes, "AFD-1"},
+		{Afedim, rennes, "AFD-2"},
+		{Afedim, brest, "AFD-3"},
+		{Afedim, edited, "AFD-4"},
+		{Afedim, "", "AFD-5"},
+		{Giboire, rennes, "GIB-1"},
+	} {
+		store.Touch(entry.agency, entry.search, Announcement{propertyReference: entry.reference}, seenAt)
+	}
+
+	target := SearchTarget{Agency: Afedim, URL: rennes}
+	found := map[string]bool{"AFD-2": true}
+	first, second := seenAt.Add(time.Hour), seenAt.Add(2*time.Hour)
+
+	// Une seule absence : en dessous du seuil, aucune annonce retirée
+	if gone := store.Sweep(target, found, 2, first); len(gone) != 0 {
+		t.Errorf("annonces retirées après une absence = %q, attendu aucune", goneReferences(gone))
+	}
+	// Deuxième absence : l'annonce de la recherche et celle de l'ancienne URL sont retirées
+	gone := store.Sweep(target, found, 2, second)
+	if got, want := goneReferences(gone), []string{"AFD-1", "AFD-4"}; !slices.Equal(got, want) {
+		t.Errorf("annonces retirées = %q, attendu %q", got, want)
+	}
+	// Déjà retirées : ni retournées de nouveau, ni datées de nouveau
+	if gone := store.Sweep(target, found, 2, second.Add(time.Hour)); len(gone) != 0 {
+		t.Errorf("annonces retirées de nouveau = %q, attendu aucune", goneReferences(gone))
+	}
+
+	for _, entry := range store.Entries(Afedim) {
+		switch entry.PropertyReference {
+		case "AFD-1", "AFD-4":
+			if entry.GoneAt == nil || !entry.GoneAt.Equal(second) {
+				t.Errorf("date de retrait de %s = %v, attendu %s", entry.PropertyReference, entry.GoneAt, second)
+			}
+			if entry.Search != rennes {
+				t.Errorf("recherche de %s = %q, attendu %q", entry.PropertyReference, entry.Search, rennes)
+			}
+		default:
+			// Annonce trouvée, d'une autre recherche ou enregistrée sans recherche : jamais comptée absente
+			if entry.GoneAt != nil || entry.Missed != 0 {
+				t.Errorf("annonce %s comptée absente (%d, %v)", entry.PropertyReference, entry.Missed, entry.GoneAt)
+			}
+		}
+	}
+	if entries := store.Entries(Giboire); len(entries) != 1 || entries[0].Missed != 0 || entries[0].GoneAt != nil {
+		t.Errorf("annonce d'une autre agence comptée absente : %+v", entries)
+	}
+}
+
+func TestSeenStoreSweepUnknownSearches(t *testing.T) {
+	store, err := OpenSeenStore(filepath.Join(t.TempDir(), "seen.json"))
+	if err != nil {
+		t.Fatalf("OpenSeenStore : %v", err)
+	}
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	store.Touch(Afedim, "https://www.afedim.fr/location?ville=brest", Announcement{propertyReference: "AFD-3"}, now)
+
+	// Recherches configurées inconnues : les annonces des autres recherches ne sont pas reprises
+	target := SearchTarget{Agency: Afedim, URL: "https://www.afedim.fr/location?ville=rennes"}
+	if gone := store.Sweep(target, map[string]bool{}, 1, now.Add(time.Hour)); len(gone) != 0 {
+		t.Errorf("annonces retirées = %q, attendu aucune", goneReferences(gone))
+	}
+}
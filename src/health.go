@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/**
+ * HealthSettings regroupe les règles de détection des anomalies de scraping (site d'agence modifié, blocage...).
+ * @property {int} Cycles - Nombre de scrapings consécutifs en anomalie avant d'alerter.
+ * @property {float64} MinExtractionRatio - Part minimale des annonces de la page de résultats dont le détail est extrait.
+ */
+type HealthSettings struct {
+	Cycles             int     `yaml:"cycles"`
+	MinExtractionRatio float64 `yaml:"min_extraction_ratio"`
+}
+
+// Anomalies détectées sur une recherche
+const (
+	anomalyEmpty      = "empty"      // Plus aucune annonce alors que la recherche en trouvait
+	anomalyExtraction = "extraction" // Détail des annonces non extrait (sélecteurs des pages de détail)
+	anomalyErrors     = "errors"     // Requêtes HTTP en erreur à chaque scraping
+)
+
+/**
+ * targetHealth est l'état de santé d'une recherche.
+ * @property {bool} everFound - true si la recherche a déjà trouvé des annonces.
+ * @property {map[string]int} streaks - Nombre de scrapings consécutifs en anomalie, par anomalie.
+ * @property {map[string]bool} alerted - Anomalies signalées et non résolues.
+ */
+type targetHealth struct {
+	everFound bool
+	streaks   map[string]int
+	alerted   map[string]bool
+}
+
+/**
+ * HealthMonitor suit la santé du scraping de chaque recherche et alerte les administrateurs
+ * quand une anomalie persiste, puis quand elle est résolue.
+ * @property {sync.Mutex} mutex - Verrou protégeant les états (recherches scrapées en parallèle).
+ * @property {HealthSettings} settings - Les règles de détection.
+ * @property {SeenStore} store - Stockage des références traitées, pour reprendre l'état des recherches après un redémarrage.
+ * @property {Notifier} notifier - Services de notification des administrateurs, nil pour journaliser seulement.
+ * @property {map[string]*targetHealth} targets - État de chaque recherche, par agence et URL.
+ */
+type HealthMonitor struct {
+	mutex    sync.Mutex
+	settings HealthSettings
+	store    *SeenStore
+	notifier Notifier
+	targets  map[string]*targetHealth
+}
+
+/**
+ * NewHealthMonitor crée le suivi de santé des recherches.
+ * @param {HealthSettings} settings - Les règles de détection.
+ * @param {SeenStore} store - Stockage des références traitées, nil si aucun.
+ * @param {Notifier} notifier - Services de notification des administrateurs, nil pour journaliser seulement.
+ * @return {HealthMonitor} - Le suivi de santé.
+ */
+func NewHealthMonitor(settings HealthSettings, store *SeenStore, notifier Notifier) *HealthMonitor {
+	return &HealthMonitor{
+		settings: settings,
+		store:    store,
+		notifier: notifier,
+		targets:  make(map[string]*targetHealth),
+	}
+}
+
+/**
+ * Record enregistre le résultat d'un scraping et alerte si une anomalie persiste ou est résolue.
+ * @param {context.Context} ctx - Contexte d'annulation des alertes.
+ * @param {SearchTarget} target - La recherche scrapée.
+ * @param {ScrapeStats} stats - Les statistiques du scraping.
+ * @param {error} err - L'erreur du scraping, nil s'il a abouti.
+ * @return {void}
+ */
+func (monitor *HealthMonitor) Record(ctx context.Context, target SearchTarget, stats ScrapeStats, err error) {
+	monitor.mutex.Lock()
+	key := seenKey(target.Agency, target.URL)
+	health, exists := monitor.targets[key]
+	if !exists {
+		health = &targetHealth{streaks: make(map[string]int), alerted: make(map[string]bool)}
+		// Une agence dont le premier scraping a déjà trouvé des annonces les trouvait avant le redémarrage
+		health.everFound = monitor.store != nil && monitor.store.IsWarmedUp(target.Agency)
+		monitor.targets[key] = health
+	}
+
+	anomalies := map[string]bool{
+		anomalyEmpty:      err == nil && stats.Items == 0 && health.everFound,
+		anomalyExtraction: stats.Items > 0 && float64(stats.Announcements) < monitor.settings.MinExtractionRatio*float64(stats.Items),
+		anomalyErrors:     err != nil || stats.Errors > 0,
+	}
+	if stats.Items > 0 {
+		health.everFound = true
+	}
+
+	var messages []Message
+	for _, anomaly := range []string{anomalyEmpty, anomalyExtraction, anomalyErrors} {
+		if !anomalies[anomaly] {
+			health.streaks[anomaly] = 0
+			if health.alerted[anomaly] {
+				delete(health.alerted, anomaly)
+				messages = append(messages, newHealthMessage(target, "Rétabli : "+describeAnomaly(anomaly, stats, err, 0)))
+			}
+			continue
+		}
+
+		health.streaks[anomaly]++
+		if health.streaks[anomaly] >= monitor.settings.Cycles && !health.alerted[anomaly] {
+			health.alerted[anomaly] = true
+			messages = append(messages, newHealthMessage(target, "Alerte : "+describeAnomaly(anomaly, stats, err, health.streaks[anomaly])))
+		}
+	}
+	monitor.mutex.Unlock()
+
+	// Envoyer les alertes hors du verrou : une attente imposée par un service ne bloque pas les autres recherches
+	for _, message := range messages {
+		healthLog.WarnContext(ctx, "Santé de la recherche", "title", target.Title, "status", strings.SplitN(message.Text, "\n", 3)[1])
+		if monitor.notifier == nil {
+			continue
+		}
+		if err := monitor.notifier.Notify(ctx, message); err != nil {
+			healthLog.ErrorContext(ctx, "Erreur lors de l'envoi de l'alerte aux administrateurs", "error", err)
+		}
+	}
+}
+
+/**
+ * describeAnomaly décrit une anomalie pour les administrateurs.
+ * @param {string} anomaly - L'anomalie.
+ * @param {ScrapeStats} stats - Les statistiques du dernier scraping.
+ * @param {error} err - L'erreur du dernier scraping.
+ * @param {int} cycles - Nombre de scrapings consécutifs en anomalie, 0 pour une anomalie résolue.
+ * @return {string} - La description.
+ */
+func describeAnomaly(anomaly string, stats ScrapeStats, err error, cycles int) string {
+	if cycles == 0 {
+		switch anomaly {
+		case anomalyEmpty:
+			return fmt.Sprintf("la recherche trouve de nouveau des annonces (%d)", stats.Items)
+		case anomalyExtraction:
+			return fmt.Sprintf("le détail des annonces est de nouveau extrait (%d sur %d)", stats.Announcements, stats.Items)
+		default:
+			return "plus d'erreur HTTP"
+		}
+	}
+
+	switch anomaly {
+	case anomalyEmpty:
+		return fmt.Sprintf("aucune annonce trouvée depuis %d scrapings alors que la recherche en trouvait (sélecteurs de la page de résultats ?)", cycles)
+	case anomalyExtraction:
+		return fmt.Sprintf("détail extrait pour %d annonces sur %d depuis %d scrapings (sélecteurs des pages de détail ?)", stats.Announcements, stats.Items, cycles)
+	default:
+		description := fmt.Sprintf("%d requêtes en erreur au dernier scraping, erreurs depuis %d scrapings", stats.Errors, cycles)
+		if err != nil {
+			description += fmt.Sprintf(" (%v)", err)
+		}
+		return description
+	}
+}
+
+/**
+ * newHealthMessage construit une alerte de santé pour les administrateurs.
+ * @param {SearchTarget} target - La recherche concernée.
+ * @param {string} text - La description de l'anomalie.
+ * @return {Message} - Le message à envoyer.
+ */
+func newHealthMessage(target SearchTarget, text string) Message {
+	return Message{
+		Title:  fmt.Sprintf("Scraper : %s (%s)", target.Title, target.Agency),
+		Text:   strings.Join([]string{fmt.Sprintf("Scraper : agence %s, recherche %s", target.Agency, target.Title), text, "URL : " + target.URL}, "\n"),
+		URL:    target.URL,
+		Agency: target.Agency,
+	}
+}
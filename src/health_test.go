@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHealthMonitorEmptyAfterRestart(t *testing.T) {
+	store, err := OpenSeenStore(filepath.Join(t.TempDir(), "seen.json"))
+	if err != nil {
+		t.Fatalf("OpenSeenStore : %v", err)
+	}
+	target := SearchTarget{Agency: Afedim, URL: "https://www.afedim.fr/location", Title: "AFEDIM"}
+	settings := HealthSettings{Cycles: 1, MinExtractionRatio: 0.5}
+
+	// Agence jamais scrapée avec succès : une recherche vide n'est pas une anomalie
+	notifier := &stubNotifier{}
+	NewHealthMonitor(settings, store, notifier).Record(context.Background(), target, ScrapeStats{Pages: 1}, nil)
+	if len(notifier.messages) != 0 {
+		t.Fatalf("alertes = %d, attendu 0 pour une agence sans annonce enregistrée", len(notifier.messages))
+	}
+
+	// Après un redémarrage, l'agence qui trouvait des annonces est alertée dès le premier scraping vide
+	store.Touch(Afedim, target.URL, Announcement{propertyReference: "REF-1"}, time.Now())
+	store.MarkWarmedUp(Afedim)
+	notifier = &stubNotifier{}
+	NewHealthMonitor(settings, store, notifier).Record(context.Background(), target, ScrapeStats{Pages: 1}, nil)
+	if len(notifier.messages) != 1 {
+		t.Errorf("alertes = %d, attendu 1 pour une recherche de nouveau vide", len(notifier.messages))
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Nombre maximal d'observations conservées par annonce (les plus anciennes sont supprimées)
+const historyMaxObservations = 50
+
+/**
+ * ChangeSettings regroupe les paramètres du suivi des annonces déjà notifiées.
+ * @property {bool} NotifyPrice - Si true (défaut), une baisse ou une hausse de loyer est notifiée.
+ * @property {bool} NotifyRelisting - Si true (défaut), une annonce republiée après une absence est notifiée.
+ * @property {Duration} RelistAfter - Durée d'absence au-delà de laquelle une annonce qui réapparaît est considérée republiée
+ * (une annonce considérée retirée qui réapparaît est toujours republiée).
+ */
+type ChangeSettings struct {
+	NotifyPrice     *bool    `yaml:"notify_price"`
+	NotifyRelisting *bool    `yaml:"notify_relisting"`
+	RelistAfter     Duration `yaml:"relist_after"`
+}
+
+/**
+ * Observation est l'état d'une annonce pendant une période : une nouvelle observation commence à chaque changement
+ * du loyer, des charges ou de la disponibilité.
+ * @property {*float64} Rent - Loyer mensuel observé.
+ * @property {*float64} Charges - Charges mensuelles observées.
+ * @property {string} AvailableDate - Disponibilité observée.
+ * @property {time.Time} FirstSeen - Date du premier scraping ayant observé cet état.
+ * @property {time.Time} LastSeen - Date du dernier scraping ayant observé cet état.
+ */
+type Observation struct {
+	Rent          *float64  `json:"rent,omitempty"`
+	Charges       *float64  `json:"charges,omitempty"`
+	AvailableDate string    `json:"availableDate,omitempty"`
+	FirstSeen     time.Time `json:"firstSeen"`
+	LastSeen      time.Time `json:"lastSeen"`
+}
+
+/**
+ * sameState indique si l'observation a les mêmes valeurs que l'annonce.
+ * @param {Announcement} announcement - L'annonce scrapée.
+ * @return {bool} - true si le loyer, les charges et la disponibilité sont inchangés.
+ */
+func (observation Observation) sameState(announcement Announcement) bool {
+	return sameAmount(observation.Rent, announcement.rent) &&
+		sameAmount(observation.Charges, announcement.charges) &&
+		observation.AvailableDate == announcement.availableDate
+}
+
+/**
+ * sameAmount compare deux montants optionnels au centime près.
+ * @param {*float64} a - Premier montant.
+ * @param {*float64} b - Second montant.
+ * @return {bool} - true si les deux montants sont absents ou égaux.
+ */
+func sameAmount(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return math.Round(*a*100) == math.Round(*b*100)
+}
+
+/**
+ * recordObservation ajoute l'état scrapé d'une annonce à son historique.
+ * @param {SeenEntry} entry - La référence de l'annonce.
+ * @param {Announcement} announcement - L'annonce scrapée.
+ * @param {time.Time} now - Date du scraping.
+ * @return {void}
+ */
+func (entry *SeenEntry) recordObservation(announcement Announcement, now time.Time) {
+	if last := len(entry.History) - 1; last >= 0 && entry.History[last].sameState(announcement) {
+		entry.History[last].LastSeen = now
+		return
+	}
+
+	entry.History = append(entry.History, Observation{
+		Rent:          announcement.rent,
+		Charges:       announcement.charges,
+		AvailableDate: announcement.availableDate,
+		FirstSeen:     now,
+		LastSeen:      now,
+	})
+	if len(entry.History) > historyMaxObservations {
+		entry.History = entry.History[len(entry.History)-historyMaxObservations:]
+	}
+}
+
+/**
+ * lastKnownRent retourne le dernier loyer connu de l'annonce : une page où le loyer n'a pas été trouvé ne compte pas.
+ * @return {*float64} - Le dernier loyer observé, ou nil.
+ */
+func (entry *SeenEntry) lastKnownRent() *float64 {
+	for i := len(entry.History) - 1; i >= 0; i-- {
+		if entry.History[i].Rent != nil {
+			return entry.History[i].Rent
+		}
+	}
+	return nil
+}
+
+/**
+ * SeenUpdate décrit l'annonce enregistrée avant sa dernière détection.
+ * @property {bool} New - true si l'annonce n'avait jamais été vue.
+ * @property {bool} Notified - true si l'annonce a déjà été notifiée.
+ * @property {[]string} Pending - Services de notification n'ayant pas encore reçu la notification de l'annonce.
+ * @property {time.Time} PreviousSeen - Date de la détection précédente.
+ * @property {*float64} PreviousRent - Dernier loyer connu avant cette détection.
+ * @property {bool} WasGone - true si l'annonce avait été considérée retirée.
+ */
+type SeenUpdate struct {
+	New          bool
+	Notified     bool
+	Pending      []string
+	PreviousSeen time.Time
+	PreviousRent *float64
+	WasGone      bool
+}
+
+/**
+ * listingChanges retourne les changements à notifier pour une annonce déjà notifiée : variation du loyer
+ * et republication après une absence de plus de RelistAfter, même si l'annonce avait été considérée retirée
+ * (une page instable peut faire disparaître une annonce quelques cycles).
+ * @param {SeenUpdate} update - L'annonce enregistrée avant cette détection.
+ * @param {Announcement} announcement - L'annonce scrapée.
+ * @param {time.Time} now - Date du scraping.
+ * @param {ChangeSettings} settings - Les changements à notifier.
+ * @return {[]string} - Une ligne par changement, vide si rien n'est à notifier.
+ */
+func listingChanges(update SeenUpdate, announcement Announcement, now time.Time, settings ChangeSettings) []string {
+	if update.New || !update.Notified {
+		return nil
+	}
+
+	var changes []string
+	if absence := now.Sub(update.PreviousSeen); *settings.NotifyRelisting && absence > time.Duration(settings.RelistAfter) {
+		changes = append(changes, "Annonce republiée après "+formatAbsence(absence)+" d'absence")
+	}
+	if previous, current := update.PreviousRent, announcement.rent; *settings.NotifyPrice && previous != nil && current != nil && !sameAmount(previous, current) {
+		direction := "baissé"
+		if *current > *previous {
+			direction = "augmenté"
+		}
+		changes = append(changes, fmt.Sprintf("Loyer %s de %s € à %s €", direction, formatNumber(*previous), formatNumber(*current)))
+	}
+	return changes
+}
+
+/**
+ * formatAbsence affiche une durée d'absence en jours, ou en heures en dessous de deux jours.
+ * @param {time.Duration} absence - La durée.
+ * @return {string} - La durée formatée (ex : "3 jours").
+ */
+func formatAbsence(absence time.Duration) string {
+	if hours := int(absence.Hours()); hours < 48 {
+		return fmt.Sprintf("%d heures", hours)
+	}
+	return fmt.Sprintf("%d jours", int(absence.Hours()/24))
+}
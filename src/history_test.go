@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListingChanges(t *testing.T) {
+	now := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	config := &Config{}
+	config.applyDefaults()
+	settings := config.Settings.Changes
+	announcement := Announcement{propertyReference: "A1", rent: pointer(680.0)}
+
+	tests := []struct {
+		name   string
+		update SeenUpdate
+		want   []string
+	}{
+		{name: "nouvelle annonce", update: SeenUpdate{New: true}, want: nil},
+		{name: "annonce non notifiée", update: SeenUpdate{PreviousSeen: now.Add(-72 * time.Hour), PreviousRent: pointer(720.0)}, want: nil},
+		{name: "sans changement", update: SeenUpdate{Notified: true, PreviousSeen: now.Add(-time.Minute), PreviousRent: pointer(680.0)}, want: nil},
+		{name: "baisse du loyer", update: SeenUpdate{Notified: true, PreviousSeen: now.Add(-time.Minute), PreviousRent: pointer(720.0)}, want: []string{"Loyer baissé de 720 € à 680 €"}},
+		{name: "republication", update: SeenUpdate{Notified: true, PreviousSeen: now.Add(-72 * time.Hour)}, want: []string{"Annonce republiée après 3 jours d'absence"}},
+		// Une annonce considérée retirée après quelques cycles manqués (page instable) n'est pas republiée
+		{name: "retrait bref", update: SeenUpdate{Notified: true, PreviousSeen: now.Add(-3 * time.Minute), WasGone: true}, want: nil},
+		{name: "retrait long", update: SeenUpdate{Notified: true, PreviousSeen: now.Add(-50 * time.Hour), WasGone: true}, want: []string{"Annonce republiée après 2 jours d'absence"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := listingChanges(test.update, announcement, now, settings)
+			if len(got) != len(test.want) {
+				t.Fatalf("changements = %q, attendu %q", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("changement %d = %q, attendu %q", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Identifiant numérique d'annonce dans une URL (au moins 4 chiffres)
+var urlNumericIDPattern = regexp.MustCompile(`\d{4,}`)
+
+// Ancienne référence composite des annonces La Forêt ("Web: X, Agence: Y")
+var laForetLegacyReferencePattern = regexp.MustCompile(`^Web: (.*), Agence: (.*)$`)
+
+/**
+ * idFromURL extrait l'identifiant stable d'une annonce de son URL : le dernier nombre d'au moins 4 chiffres
+ * du dernier segment, sinon le segment lui-même (sans extension ni paramètres).
+ * @param {string} rawURL - L'URL de l'annonce.
+ * @return {string} - L'identifiant, ou une chaîne vide si l'URL n'en contient pas.
+ */
+func idFromURL(rawURL string) string {
+	parsedURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	segment := path.Base(strings.TrimSuffix(parsedURL.Path, "/"))
+	if segment == "." || segment == "/" {
+		return ""
+	}
+	segment = strings.TrimSuffix(segment, path.Ext(segment))
+
+	if matches := urlNumericIDPattern.FindAllString(segment, -1); len(matches) > 0 {
+		return matches[len(matches)-1]
+	}
+	return strings.ToLower(segment)
+}
+
+/**
+ * idFromJSONLD extrait l'identifiant d'une annonce des données structurées JSON-LD de la page
+ * (propriétés sku, productID ou identifier, y compris dans @graph).
+ * @param {goquery.Selection} page - La page de l'annonce.
+ * @return {string} - L'identifiant, ou une chaîne vide s'il est absent.
+ */
+func idFromJSONLD(page *goquery.Selection) string {
+	var id string
+	page.Find("script[type='application/ld+json']").EachWithBreak(func(_ int, script *goquery.Selection) bool {
+		var document any
+		if err := json.Unmarshal([]byte(script.Text()), &document); err != nil {
+			return true
+		}
+		id = findJSONLDIdentifier(document)
+		return id == ""
+	})
+	return id
+}
+
+/**
+ * findJSONLDIdentifier parcourt un document JSON-LD à la recherche d'un identifiant.
+ * @param {any} document - Le document décodé.
+ * @return {string} - L'identifiant, ou une chaîne vide.
+ */
+func findJSONLDIdentifier(document any) string {
+	switch value := document.(type) {
+	case []any:
+		for _, item := range value {
+			if id := findJSONLDIdentifier(item); id != "" {
+				return id
+			}
+		}
+	case map[string]any:
+		for _, key := range []string{"sku", "productID", "identifier"} {
+			switch id := value[key].(type) {
+			case string:
+				if id = strings.TrimSpace(id); id != "" {
+					return id
+				}
+			case float64:
+				return strconv.FormatFloat(id, 'f', -1, 64)
+			case map[string]any:
+				if propertyValue, ok := id["value"].(string); ok && propertyValue != "" {
+					return propertyValue
+				}
+			}
+		}
+		if graph, ok := value["@graph"]; ok {
+			return findJSONLDIdentifier(graph)
+		}
+	}
+	return ""
+}
+
+/**
+ * hashReference calcule une référence de repli à partir d'un texte, insensible à la casse, aux accents et aux espaces.
+ * @param {string} text - Le texte identifiant l'annonce (description, titre...).
+ * @return {string} - La référence "h-" suivie de 16 caractères hexadécimaux, ou une chaîne vide si le texte est vide.
+ */
+func hashReference(text string) string {
+	normalized := strings.Join(strings.Fields(normalizeText(text)), " ")
+	if normalized == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return "h-" + hex.EncodeToString(sum[:8])
+}
+
+/**
+ * migrateReference convertit une référence enregistrée par une version précédente vers l'identité canonique de l'agence.
+ * Seules les conversions exactes sont faites ici ; les autres anciennes références sont reprises
+ * à la détection suivante de l'annonce (Announcement.legacyReferences).
+ * @param {SeenEntry} entry - La référence enregistrée.
+ * @return {string} - La nouvelle référence, ou la référence inchangée.
+ */
+func migrateReference(entry *SeenEntry) string {
+	switch entry.Agency {
+	case SquareHabitat:
+		// L'ancienne référence était la description de l'annonce, sans URL
+		if entry.URL == "" {
+			if reference := hashReference(entry.PropertyReference); reference != "" {
+				return reference
+			}
+		}
+	case CAImmobilier:
+		if id := idFromURL(entry.URL); id != "" {
+			return id
+		}
+	case LaForetImmobilier:
+		if matches := laForetLegacyReferencePattern.FindStringSubmatch(entry.PropertyReference); matches != nil && matches[1] != "" {
+			return matches[1]
+		}
+	}
+	return entry.PropertyReference
+}
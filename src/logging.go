@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gocolly/colly/v2"
+)
+
+/**
+ * LogSettings regroupe les paramètres des journaux.
+ * @property {string} Level - Niveau minimal par défaut : debug, info, warn ou error (info par défaut).
+ * @property {string} Format - Format des lignes : text (logfmt, par défaut) ou json.
+ * @property {map[string]string} Components - Niveau propre à un composant (collector, processor, agency, telegram...).
+ */
+type LogSettings struct {
+	Level      string            `yaml:"level"`
+	Format     string            `yaml:"format"`
+	Components map[string]string `yaml:"components"`
+}
+
+/**
+ * Validate vérifie les niveaux, le format et les composants des journaux.
+ * @return {error} - Erreur décrivant chaque valeur invalide.
+ */
+func (settings LogSettings) Validate() error {
+	var errs []error
+	if _, err := parseLogLevel(settings.Level); err != nil {
+		errs = append(errs, err)
+	}
+	if settings.Format != "" && settings.Format != "text" && settings.Format != "json" {
+		errs = append(errs, fmt.Errorf("format %q inconnu (text ou json)", settings.Format))
+	}
+	for component, level := range settings.Components {
+		if _, exists := logComponents[component]; !exists {
+			errs = append(errs, fmt.Errorf("composant %q inconnu (%s)", component, strings.Join(logComponentNames(), ", ")))
+		}
+		if _, err := parseLogLevel(level); err != nil {
+			errs = append(errs, fmt.Errorf("composant %s : %w", component, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/**
+ * parseLogLevel convertit un niveau de journal.
+ * @param {string} level - Le niveau : debug, info, warn ou error (info si vide).
+ * @return {slog.Level} - Le niveau.
+ * @return {error} - Erreur si le niveau est inconnu.
+ */
+func parseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("niveau %q inconnu (debug, info, warn ou error)", level)
+}
+
+// Niveau de chaque composant, modifié par ConfigureLogging
+var (
+	logComponentsMutex sync.Mutex
+	logComponents      = make(map[string]*slog.LevelVar)
+)
+
+/**
+ * logSink est la sortie des journaux de tous les composants.
+ * @property {slog.Handler} handler - Le handler écrivant les lignes (texte ou JSON).
+ */
+type logSink struct {
+	handler slog.Handler
+}
+
+// Sortie des journaux, remplacée par ConfigureLogging (texte sur la sortie d'erreur par défaut)
+var logOutput atomic.Pointer[logSink]
+
+func init() {
+	logOutput.Store(&logSink{handler: newLogHandler("text", os.Stderr)})
+	slog.SetDefault(newComponentLogger("main"))
+}
+
+// Journaux de chaque composant
+var (
+	collectorLog = newComponentLogger("collector")
+	processorLog = newComponentLogger("processor")
+	agencyLog    = newComponentLogger("agency")
+	telegramLog  = newComponentLogger("telegram")
+	dedupLog     = newComponentLogger("dedup")
+	healthLog    = newComponentLogger("health")
+	storeLog     = newComponentLogger("store")
+	serverLog    = newComponentLogger("server")
+	cassetteLog  = newComponentLogger("cassette")
+)
+
+/**
+ * newComponentLogger crée le journal d'un composant, dont le niveau est configurable séparément.
+ * @param {string} component - Le nom du composant, ajouté à chaque ligne.
+ * @return {slog.Logger} - Le journal du composant.
+ */
+func newComponentLogger(component string) *slog.Logger {
+	logComponentsMutex.Lock()
+	level, exists := logComponents[component]
+	if !exists {
+		level = new(slog.LevelVar)
+		logComponents[component] = level
+	}
+	logComponentsMutex.Unlock()
+
+	return slog.New(&componentHandler{level: level}).With("component", component)
+}
+
+/**
+ * logComponentNames retourne le nom des composants, triés.
+ * @return {[]string} - Les noms.
+ */
+func logComponentNames() []string {
+	logComponentsMutex.Lock()
+	defer logComponentsMutex.Unlock()
+
+	names := make([]string, 0, len(logComponents))
+	for name := range logComponents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/**
+ * ConfigureLogging applique la configuration des journaux : format de sortie et niveau de chaque composant.
+ * Les journaux du paquet log (log.Printf) passent aussi par cette sortie, au niveau info.
+ * @param {LogSettings} settings - Les paramètres des journaux (validés).
+ * @param {io.Writer} output - La sortie des journaux.
+ * @return {error} - Erreur si un niveau est invalide.
+ */
+func ConfigureLogging(settings LogSettings, output io.Writer) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	defaultLevel, _ := parseLogLevel(settings.Level)
+
+	logComponentsMutex.Lock()
+	for name, level := range logComponents {
+		level.Set(defaultLevel)
+		if componentLevel, exists := settings.Components[name]; exists {
+			parsed, _ := parseLogLevel(componentLevel)
+			level.Set(parsed)
+		}
+	}
+	logComponentsMutex.Unlock()
+
+	logOutput.Store(&logSink{handler: newLogHandler(settings.Format, output)})
+	return nil
+}
+
+/**
+ * newLogHandler crée la sortie des journaux ; le filtrage par niveau est fait par composant.
+ * @param {string} format - text (logfmt) ou json.
+ * @param {io.Writer} output - La sortie des journaux.
+ * @return {slog.Handler} - Le handler de sortie.
+ */
+func newLogHandler(format string, output io.Writer) slog.Handler {
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if format == "json" {
+		return slog.NewJSONHandler(output, options)
+	}
+	return slog.NewTextHandler(output, options)
+}
+
+/**
+ * componentHandler filtre les journaux d'un composant selon son niveau, ajoute les champs portés par le contexte
+ * (agence, recherche, cycle) et les écrit sur la sortie courante.
+ * @property {slog.LevelVar} level - Niveau minimal du composant.
+ * @property {[]func(slog.Handler) slog.Handler} wraps - Champs et groupes ajoutés par With et WithGroup, appliqués à la sortie.
+ */
+type componentHandler struct {
+	level *slog.LevelVar
+	wraps []func(slog.Handler) slog.Handler
+}
+
+func (handler *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= handler.level.Level()
+}
+
+func (handler *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs, ok := ctx.Value(logAttrsKey{}).([]slog.Attr); ok {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+
+	output := logOutput.Load().handler
+	for _, wrap := range handler.wraps {
+		output = wrap(output)
+	}
+	return output.Handle(ctx, record)
+}
+
+func (handler *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return handler.with(func(output slog.Handler) slog.Handler { return output.WithAttrs(attrs) })
+}
+
+func (handler *componentHandler) WithGroup(name string) slog.Handler {
+	return handler.with(func(output slog.Handler) slog.Handler { return output.WithGroup(name) })
+}
+
+/**
+ * with retourne une copie du handler appliquant une transformation supplémentaire à la sortie.
+ * @param {func(slog.Handler) slog.Handler} wrap - La transformation.
+ * @return {slog.Handler} - La copie du handler.
+ */
+func (handler *componentHandler) with(wrap func(slog.Handler) slog.Handler) slog.Handler {
+	wraps := append(append([]func(slog.Handler) slog.Handler(nil), handler.wraps...), wrap)
+	return &componentHandler{level: handler.level, wraps: wraps}
+}
+
+// Clé du contexte portant les champs ajoutés à chaque ligne de journal
+type logAttrsKey struct{}
+
+/**
+ * withLogAttrs retourne un contexte dont les journaux portent des champs supplémentaires (agence, recherche, cycle...).
+ * @param {context.Context} ctx - Le contexte parent.
+ * @param {...slog.Attr} attrs - Les champs à ajouter.
+ * @return {context.Context} - Le contexte enrichi.
+ */
+func withLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	return context.WithValue(ctx, logAttrsKey{}, append(append([]slog.Attr(nil), existing...), attrs...))
+}
+
+/**
+ * newCycleID génère l'identifiant d'un cycle de scraping, ajouté aux journaux de ce cycle.
+ * @return {string} - L'identifiant (8 caractères hexadécimaux).
+ */
+func newCycleID() string {
+	return fmt.Sprintf("%08x", rand.Uint32())
+}
+
+// Clé du contexte colly portant le contexte de journalisation de la requête
+const logContextKey = "logContext"
+
+/**
+ * elementLogger retourne le journal des scrapers d'agence pour un élément HTML : agence, recherche, cycle
+ * et URL de la page de l'élément.
+ * @param {colly.HTMLElement} element - L'élément HTML en cours de traitement.
+ * @return {slog.Logger} - Le journal enrichi.
+ */
+func elementLogger(element *colly.HTMLElement) *slog.Logger {
+	logger := agencyLog
+	if ctx, ok := element.Request.Ctx.GetAny(logContextKey).(context.Context); ok {
+		if attrs, ok := ctx.Value(logAttrsKey{}).([]slog.Attr); ok {
+			for _, attr := range attrs {
+				logger = logger.With(attr)
+			}
+		}
+	}
+	return logger.With("page", element.Request.URL.String())
+}
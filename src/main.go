@@ -1,6 +1,42 @@
 package main
 
-// Point d'entrée de l'application
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Point d'entrée de l'application : voir runCLI pour les commandes et les options
 func main() {
-	RunScraper(1)
+	// Contexte racine annulé à la réception de SIGINT ou SIGTERM (arrêt du pod Kubernetes)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := runCLI(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		// Niveau error : le message reste visible quel que soit le niveau des journaux configuré
+		slog.Error("Arrêt du scraper sur erreur", "error", err)
+		os.Exit(1)
+	}
+}
+
+/**
+ * getEnv retourne la valeur d'une variable d'environnement, ou la valeur par défaut si elle est absente.
+ * @param {string} key - Nom de la variable d'environnement.
+ * @param {string} fallback - Valeur par défaut.
+ * @return {string} - La valeur de la variable.
+ */
+func getEnv(key string, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists && value != "" {
+		return value
+	}
+	return fallback
 }
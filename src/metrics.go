@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registre des métriques exposées sur /metrics (métriques du scraper, du runtime Go et du processus)
+var metricsRegistry = prometheus.NewRegistry()
+
+// Métriques du scraper
+var (
+	metricsFactory = promauto.With(metricsRegistry)
+
+	scrapeDuration = metricsFactory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scraper_scrape_duration_seconds",
+		Help:    "Durée du scraping d'une recherche (pages de résultats et de détail).",
+		Buckets: []float64{1, 2, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"agency"})
+
+	pagesFetched = metricsFactory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_pages_fetched_total",
+		Help: "Pages téléchargées, par type de page (results ou detail).",
+	}, []string{"agency", "kind"})
+
+	httpResponses = metricsFactory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_http_responses_total",
+		Help: "Réponses HTTP des sites d'agences, par code de statut (error pour une erreur réseau).",
+	}, []string{"agency", "code"})
+
+	listingsFound = metricsFactory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_listings_found_total",
+		Help: "Annonces extraites à chaque scraping.",
+	}, []string{"agency"})
+
+	newListings = metricsFactory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_new_listings_total",
+		Help: "Nouvelles annonces détectées (hors premier scraping de l'agence).",
+	}, []string{"agency"})
+
+	extractionFailures = metricsFactory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_extraction_failures_total",
+		Help: "Annonces de la page de résultats dont le détail n'a pas pu être extrait.",
+	}, []string{"agency"})
+
+	notificationsSent = metricsFactory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_notifications_total",
+		Help: "Notifications envoyées, par service et résultat (success ou error).",
+	}, []string{"notifier", "result"})
+
+	telegramSends = metricsFactory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_telegram_sends_total",
+		Help: "Appels à l'API Telegram, par résultat (success, retry_after ou error).",
+	}, []string{"result"})
+
+	telegramRetryAfter = metricsFactory.NewHistogram(prometheus.HistogramOpts{
+		Name:    "scraper_telegram_retry_after_seconds",
+		Help:    "Attentes imposées par l'API Telegram (RetryAfter).",
+		Buckets: []float64{1, 5, 10, 30, 60, 120, 300},
+	})
+
+	lastCycle = metricsFactory.NewGauge(prometheus.GaugeOpts{
+		Name: "scraper_last_successful_cycle_timestamp_seconds",
+		Help: "Date (timestamp Unix) de la fin du dernier cycle de scraping réussi (au moins une recherche scrapée sans erreur).",
+	})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+/**
+ * metricsHandler retourne le handler HTTP exposant les métriques au format Prometheus.
+ * @return {http.Handler} - Le handler de /metrics.
+ */
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+/**
+ * statusLabel retourne le libellé du code de statut d'une réponse HTTP.
+ * @param {int} statusCode - Le code de statut, 0 pour une erreur réseau.
+ * @return {string} - Le code, ou "error".
+ */
+func statusLabel(statusCode int) string {
+	if statusCode == 0 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode)
+}
+
+/**
+ * resultLabel retourne le libellé du résultat d'une opération.
+ * @param {error} err - L'erreur de l'opération.
+ * @return {string} - "success" ou "error".
+ */
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,503 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Délai maximal d'une requête HTTP vers un service de notification
+const notifierHTTPTimeout = 15 * time.Second
+
+/**
+ * Message est une notification à envoyer, indépendante du service utilisé.
+ * @property {string} Title - Titre court (titre de la recherche, sujet d'e-mail).
+ * @property {string} Text - Texte complet du message.
+ * @property {string} URL - Lien principal du message.
+ * @property {[]string} PhotoURLs - Photos à joindre.
+ * @property {[]MessageButton} Buttons - Boutons de lien.
+ * @property {Agency} Agency - Agence de l'annonce, vide pour un message sans annonce.
+ * @property {*AnnouncementData} Announcement - Données structurées de l'annonce, nil pour un message sans annonce.
+ * @property {[]PropertyListing} Listings - Annonces du même bien chez plusieurs agences, vide pour une seule annonce.
+ * @property {map[string]string} ReplyTo - Identifiant, par service, de la notification à laquelle ce message répond.
+ */
+type Message struct {
+	Title        string
+	Text         string
+	URL          string
+	PhotoURLs    []string
+	Buttons      []MessageButton
+	Agency       Agency
+	Announcement *AnnouncementData
+	Listings     []PropertyListing
+	ReplyTo      map[string]string
+}
+
+/**
+ * MessageButton est un bouton de lien affiché sous un message.
+ * @property {string} Label - Libellé du bouton.
+ * @property {string} URL - Lien du bouton.
+ */
+type MessageButton struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+/**
+ * newAnnouncementMessage construit la notification d'une nouvelle annonce.
+ * @param {SearchTarget} target - La recherche ayant trouvé l'annonce.
+ * @param {Announcement} announcement - L'annonce.
+ * @return {Message} - Le message à envoyer.
+ */
+func newAnnouncementMessage(target SearchTarget, announcement Announcement) Message {
+	return newListingMessage(target, announcement, "Nouvelle annonce immobilière !")
+}
+
+/**
+ * newListingMessage construit la notification d'une annonce avec l'objet donné (nouvelle annonce, baisse de loyer...).
+ * @param {SearchTarget} target - La recherche ayant trouvé l'annonce.
+ * @param {Announcement} announcement - L'annonce.
+ * @param {string} headline - L'objet de la notification.
+ * @return {Message} - Le message à envoyer.
+ */
+func newListingMessage(target SearchTarget, announcement Announcement, headline string) Message {
+	data := announcement.Data()
+	message := Message{
+		Title:        target.Title,
+		Text:         formatAnnouncementMessage(target.Title, headline, announcement),
+		URL:          announcement.url,
+		PhotoURLs:    announcement.photoURLs,
+		Agency:       target.Agency,
+		Announcement: &data,
+	}
+	if announcement.url != "" {
+		message.Buttons = []MessageButton{{Label: "Voir l'annonce", URL: announcement.url}}
+	}
+	return message
+}
+
+/**
+ * Notifier est l'interface des services de notification (Telegram, e-mail, webhooks, Matrix, ntfy).
+ */
+type Notifier interface {
+	/**
+	 * Name retourne le nom du service, pour les journaux.
+	 * @return {string} - Le nom du service.
+	 */
+	Name() string
+
+	/**
+	 * Notify envoie un message.
+	 * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+	 * @param {Message} message - Le message à envoyer.
+	 * @return {error} - Erreur lors de l'envoi.
+	 */
+	Notify(ctx context.Context, message Message) error
+}
+
+/**
+ * receiptNotifier est implémentée par les services capables de retrouver un message envoyé (réponse Telegram).
+ */
+type receiptNotifier interface {
+	/**
+	 * NotifyWithReceipt envoie un message et retourne son identifiant.
+	 * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+	 * @param {Message} message - Le message à envoyer.
+	 * @return {string} - L'identifiant du message envoyé.
+	 * @return {error} - Erreur lors de l'envoi.
+	 */
+	NotifyWithReceipt(ctx context.Context, message Message) (string, error)
+}
+
+/**
+ * pingNotifier est implémentée par les services capables de vérifier qu'ils sont joignables (sonde de disponibilité).
+ */
+type pingNotifier interface {
+	/**
+	 * Ping vérifie que le service est joignable, sans envoyer de message.
+	 * @param {context.Context} ctx - Contexte d'annulation de la vérification.
+	 * @return {error} - Erreur si le service est injoignable.
+	 */
+	Ping(ctx context.Context) error
+}
+
+/**
+ * pingNotifiers vérifie que chaque service de notification capable de le faire est joignable.
+ * @param {context.Context} ctx - Contexte d'annulation de la vérification.
+ * @param {Notifier} notifier - Les services de notification.
+ * @return {error} - Erreur si un service est injoignable.
+ */
+func pingNotifiers(ctx context.Context, notifier Notifier) error {
+	if multi, ok := notifier.(*MultiNotifier); ok {
+		var errs []error
+		for _, service := range multi.notifiers {
+			if err := pingNotifiers(ctx, service); err != nil {
+				errs = append(errs, fmt.Errorf("%s : %w", service.Name(), err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	if pinger, ok := notifier.(pingNotifier); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+/**
+ * notifyWithReceipts envoie un message et retourne l'identifiant du message envoyé par chaque service qui le fournit.
+ * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+ * @param {Notifier} notifier - Les services de notification.
+ * @param {Message} message - Le message à envoyer.
+ * @return {map[string]string} - Les identifiants, par nom de service.
+ * @return {error} - Erreur lors de l'envoi.
+ */
+func notifyWithReceipts(ctx context.Context, notifier Notifier, message Message) (map[string]string, error) {
+	if multi, ok := notifier.(*MultiNotifier); ok {
+		return multi.NotifyWithReceipts(ctx, message)
+	}
+	if tracked, ok := notifier.(receiptNotifier); ok {
+		receipt, err := tracked.NotifyWithReceipt(ctx, message)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{notifier.Name(): receipt}, nil
+	}
+	return nil, notifier.Notify(ctx, message)
+}
+
+/**
+ * MultiNotifier envoie chaque message à plusieurs services.
+ * @property {[]Notifier} notifiers - Les services de notification.
+ */
+type MultiNotifier struct {
+	notifiers []Notifier
+}
+
+func (multi *MultiNotifier) Name() string {
+	return "multi"
+}
+
+/**
+ * Notify envoie le message à tous les services ; l'échec d'un service n'empêche pas l'envoi aux autres.
+ * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+ * @param {Message} message - Le message à envoyer.
+ * @return {error} - Les erreurs de chaque service en échec, ou nil.
+ */
+func (multi *MultiNotifier) Notify(ctx context.Context, message Message) error {
+	_, err := multi.NotifyWithReceipts(ctx, message)
+	return err
+}
+
+/**
+ * NotifyWithReceipts envoie le message à tous les services et retourne l'identifiant du message envoyé par chacun.
+ * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+ * @param {Message} message - Le message à envoyer.
+ * @return {map[string]string} - Les identifiants des services qui les fournissent, par nom de service.
+ * @return {error} - Les erreurs de chaque service en échec, ou nil.
+ */
+func (multi *MultiNotifier) NotifyWithReceipts(ctx context.Context, message Message) (map[string]string, error) {
+	receipts := make(map[string]string)
+	var errs []error
+	var failed []string
+	for _, notifier := range multi.notifiers {
+		received, err := notifyWithReceipts(ctx, notifier, message)
+		notificationsSent.WithLabelValues(notifier.Name(), resultLabel(err)).Inc()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s : %w", notifier.Name(), err))
+			failed = append(failed, notifier.Name())
+		}
+		for name, receipt := range received {
+			receipts[name] = receipt
+		}
+	}
+	if len(errs) == 0 {
+		return receipts, nil
+	}
+	return receipts, &deliveryError{failed: failed, delivered: len(failed) < len(multi.notifiers), err: errors.Join(errs...)}
+}
+
+/**
+ * deliveryError est l'erreur d'un envoi à plusieurs services, dont certains ont pu recevoir le message.
+ * @property {[]string} failed - Nom des services en échec.
+ * @property {bool} delivered - true si au moins un service a reçu le message.
+ * @property {error} err - Les erreurs de chaque service en échec.
+ */
+type deliveryError struct {
+	failed    []string
+	delivered bool
+	err       error
+}
+
+func (deliveryErr *deliveryError) Error() string {
+	return deliveryErr.err.Error()
+}
+
+func (deliveryErr *deliveryError) Unwrap() error {
+	return deliveryErr.err
+}
+
+/**
+ * deliveryFailures retourne les services n'ayant pas reçu un message.
+ * @param {Notifier} notifier - Les services de notification utilisés pour l'envoi.
+ * @param {error} err - L'erreur de l'envoi.
+ * @return {[]string} - Nom des services en échec.
+ * @return {bool} - true si au moins un service a reçu le message.
+ */
+func deliveryFailures(notifier Notifier, err error) ([]string, bool) {
+	if err == nil {
+		return nil, true
+	}
+	var deliveryErr *deliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.failed, deliveryErr.delivered
+	}
+	if multi, ok := notifier.(*MultiNotifier); ok {
+		failed := make([]string, 0, len(multi.notifiers))
+		for _, service := range multi.notifiers {
+			failed = append(failed, service.Name())
+		}
+		return failed, false
+	}
+	return []string{notifier.Name()}, false
+}
+
+/**
+ * notifierFor retourne les seuls services de notification désignés, pour retenter un envoi sur les services en échec.
+ * @param {Notifier} notifier - Les services de notification configurés.
+ * @param {[]string} services - Nom des services à conserver.
+ * @return {Notifier} - Les services désignés encore configurés, nil s'il n'y en a plus.
+ */
+func notifierFor(notifier Notifier, services []string) Notifier {
+	if multi, ok := notifier.(*MultiNotifier); ok {
+		selected := &MultiNotifier{}
+		for _, service := range multi.notifiers {
+			if slices.Contains(services, service.Name()) {
+				selected.notifiers = append(selected.notifiers, service)
+			}
+		}
+		if len(selected.notifiers) == 0 {
+			return nil
+		}
+		return selected
+	}
+	if slices.Contains(services, notifier.Name()) {
+		return notifier
+	}
+	return nil
+}
+
+/**
+ * NotifierConfig décrit un service de notification dans le fichier de configuration.
+ * Les valeurs sensibles ne sont jamais dans le fichier : les champs *_secret donnent le nom du secret à lire
+ * (variable d'environnement, fichier <NOM>_FILE ou fichier du dossier SECRETS_DIR).
+ * @property {string} Type - Type de service : telegram, email, webhook, discord, slack, matrix ou ntfy.
+ * @property {string} Name - Nom affiché dans les journaux (type par défaut).
+ * @property {string} URL - URL du webhook, du serveur ntfy, du serveur Matrix ou de l'API Telegram.
+ * @property {string} URLSecret - Nom du secret contenant l'URL (webhooks Discord et Slack).
+ * @property {string} TokenSecret - Nom du secret contenant le jeton d'accès (Matrix, ntfy, webhook).
+ * @property {map[string]string} Headers - En-têtes HTTP supplémentaires (webhook).
+ * @property {string} Topic - Sujet ntfy.
+ * @property {string} RoomID - Salon Matrix.
+ * @property {string} Channel - Canal Telegram, prioritaire sur TELEGRAM_CHANNEL (canal d'administration).
+ * @property {string} SMTPHost - Serveur SMTP.
+ * @property {int} SMTPPort - Port SMTP (587 par défaut).
+ * @property {string} Username - Utilisateur SMTP.
+ * @property {string} PasswordSecret - Nom du secret contenant le mot de passe SMTP.
+ * @property {string} From - Expéditeur des e-mails.
+ * @property {[]string} To - Destinataires des e-mails.
+ */
+type NotifierConfig struct {
+	Type           string            `yaml:"type"`
+	Name           string            `yaml:"name"`
+	URL            string            `yaml:"url"`
+	URLSecret      string            `yaml:"url_secret"`
+	TokenSecret    string            `yaml:"token_secret"`
+	Headers        map[string]string `yaml:"headers"`
+	Topic          string            `yaml:"topic"`
+	RoomID         string            `yaml:"room_id"`
+	Channel        string            `yaml:"channel"`
+	SMTPHost       string            `yaml:"smtp_host"`
+	SMTPPort       int               `yaml:"smtp_port"`
+	Username       string            `yaml:"username"`
+	PasswordSecret string            `yaml:"password_secret"`
+	From           string            `yaml:"from"`
+	To             []string          `yaml:"to"`
+}
+
+/**
+ * DisplayName retourne le nom du service pour les journaux.
+ * @return {string} - Le nom configuré, ou le type.
+ */
+func (config NotifierConfig) DisplayName() string {
+	if config.Name != "" {
+		return config.Name
+	}
+	return config.Type
+}
+
+/**
+ * Validate vérifie que les champs obligatoires du type de service sont renseignés.
+ * Les secrets ne sont pas lus ici : ils le sont à la création du service.
+ * @return {error} - Les erreurs de validation, ou nil.
+ */
+func (config NotifierConfig) Validate() error {
+	var errs []error
+	require := func(value string, field string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s est obligatoire pour le type %s", field, config.Type))
+		}
+	}
+	requireURL := func(value string, field string) {
+		if parsedURL, err := url.Parse(value); value != "" && (err != nil || parsedURL.Host == "") {
+			errs = append(errs, fmt.Errorf("%s invalide : %q", field, value))
+		}
+	}
+
+	switch config.Type {
+	case "telegram":
+		requireURL(config.URL, "url")
+		if config.Channel != "" && !telegramChannelPattern.MatchString(config.Channel) {
+			errs = append(errs, fmt.Errorf("channel invalide : %q", config.Channel))
+		}
+	case "email":
+		require(config.SMTPHost, "smtp_host")
+		require(config.From, "from")
+		if len(config.To) == 0 {
+			errs = append(errs, errors.New("to est obligatoire pour le type email"))
+		}
+		if config.Username != "" {
+			require(config.PasswordSecret, "password_secret")
+		}
+	case "webhook":
+		require(config.URL, "url")
+		requireURL(config.URL, "url")
+	case "discord", "slack":
+		if config.URL == "" && config.URLSecret == "" {
+			errs = append(errs, fmt.Errorf("url ou url_secret est obligatoire pour le type %s", config.Type))
+		}
+		requireURL(config.URL, "url")
+	case "matrix":
+		require(config.URL, "url")
+		requireURL(config.URL, "url")
+		require(config.RoomID, "room_id")
+		require(config.TokenSecret, "token_secret")
+	case "ntfy":
+		requireURL(config.URL, "url")
+		require(config.Topic, "topic")
+	case "":
+		errs = append(errs, errors.New("type est obligatoire"))
+	default:
+		errs = append(errs, fmt.Errorf("type de notification inconnu : %s", config.Type))
+	}
+
+	return errors.Join(errs...)
+}
+
+/**
+ * NewNotifier crée les services de notification configurés et les regroupe dans un MultiNotifier.
+ * @param {[]NotifierConfig} configs - Les services configurés.
+ * @return {Notifier} - Le service regroupant tous les services configurés.
+ * @return {error} - Erreur si un service ne peut pas être créé (secret manquant, bot invalide...).
+ */
+func NewNotifier(configs []NotifierConfig) (Notifier, error) {
+	multi := &MultiNotifier{}
+	client := &http.Client{Timeout: notifierHTTPTimeout}
+
+	for _, config := range configs {
+		var notifier Notifier
+		var err error
+
+		switch config.Type {
+		case "telegram":
+			notifier, err = NewTelegramNotifier(config)
+		case "email":
+			notifier, err = NewEmailNotifier(config)
+		case "webhook":
+			notifier, err = NewWebhookNotifier(config, client)
+		case "discord", "slack":
+			notifier, err = NewChatWebhookNotifier(config, client)
+		case "matrix":
+			notifier, err = NewMatrixNotifier(config, client)
+		case "ntfy":
+			notifier, err = NewNtfyNotifier(config, client)
+		default:
+			err = fmt.Errorf("type de notification inconnu : %s", config.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("notification %s : %w", config.DisplayName(), err)
+		}
+
+		multi.notifiers = append(multi.notifiers, notifier)
+	}
+
+	return multi, nil
+}
+
+/**
+ * requireSecret lit un secret obligatoire.
+ * @param {string} key - Nom du secret.
+ * @return {string} - La valeur du secret.
+ * @return {error} - Erreur si le secret est absent.
+ */
+func requireSecret(key string) (string, error) {
+	value := lookupSecret(key)
+	if value == "" {
+		return "", fmt.Errorf("secret %s manquant", key)
+	}
+	return value, nil
+}
+
+/**
+ * postJSON envoie un document JSON et vérifie le code de retour HTTP.
+ * @param {context.Context} ctx - Contexte d'annulation de la requête.
+ * @param {http.Client} client - Le client HTTP.
+ * @param {string} method - La méthode HTTP (POST, PUT).
+ * @param {string} targetURL - L'URL cible.
+ * @param {any} payload - Le document à encoder en JSON.
+ * @param {map[string]string} headers - En-têtes supplémentaires.
+ * @return {error} - Erreur réseau ou code de retour hors 2xx.
+ */
+func postJSON(ctx context.Context, client *http.Client, method string, targetURL string, payload any, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encodage JSON : %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	return doRequest(client, request)
+}
+
+/**
+ * doRequest exécute une requête HTTP et vérifie le code de retour.
+ * @param {http.Client} client - Le client HTTP.
+ * @param {http.Request} request - La requête.
+ * @return {error} - Erreur réseau ou code de retour hors 2xx.
+ */
+func doRequest(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("réponse HTTP %d : %s", response.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
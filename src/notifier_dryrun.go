@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/**
+ * DryRunNotifier remplace les services de notification en simulation (-dry-run) : chaque message est écrit
+ * tel qu'il serait envoyé (texte, photos, boutons, réponse à une notification précédente) au lieu d'être envoyé.
+ * @property {sync.Mutex} mutex - Verrou sérialisant l'écriture des messages (recherches scrapées en parallèle).
+ * @property {io.Writer} output - Sortie des messages (sortie standard ou fichier).
+ * @property {[]string} services - Services configurés qui auraient reçu les messages.
+ * @property {int} sent - Nombre de messages écrits, servant d'identifiant aux réponses.
+ */
+type DryRunNotifier struct {
+	mutex    sync.Mutex
+	output   io.Writer
+	services []string
+	sent     int
+}
+
+/**
+ * NewDryRunNotifier crée le service de simulation.
+ * @param {io.Writer} output - Sortie des messages.
+ * @param {[]NotifierConfig} configs - Services configurés, rappelés avec chaque message ; aucun n'est créé.
+ * @return {DryRunNotifier} - Le service de simulation.
+ */
+func NewDryRunNotifier(output io.Writer, configs []NotifierConfig) *DryRunNotifier {
+	services := make([]string, 0, len(configs))
+	for _, config := range configs {
+		services = append(services, config.DisplayName())
+	}
+	return &DryRunNotifier{output: output, services: services}
+}
+
+func (dryRun *DryRunNotifier) Name() string {
+	return "dry-run"
+}
+
+func (dryRun *DryRunNotifier) Notify(ctx context.Context, message Message) error {
+	_, err := dryRun.NotifyWithReceipt(ctx, message)
+	return err
+}
+
+/**
+ * NotifyWithReceipt écrit le message et retourne son numéro, pour que les notifications suivantes (retrait, baisse
+ * de loyer) affichent à quel message elles répondent.
+ * @param {context.Context} ctx - Contexte d'annulation.
+ * @param {Message} message - Le message simulé.
+ * @return {string} - Le numéro du message.
+ * @return {error} - Erreur d'écriture.
+ */
+func (dryRun *DryRunNotifier) NotifyWithReceipt(ctx context.Context, message Message) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	dryRun.mutex.Lock()
+	defer dryRun.mutex.Unlock()
+
+	dryRun.sent++
+	receipt := strconv.Itoa(dryRun.sent)
+	if _, err := io.WriteString(dryRun.output, dryRun.render(receipt, message)); err != nil {
+		return "", fmt.Errorf("écriture de la notification simulée : %w", err)
+	}
+	return receipt, nil
+}
+
+/**
+ * render met en forme un message simulé.
+ * @param {string} receipt - Le numéro du message.
+ * @param {Message} message - Le message.
+ * @return {string} - Le message mis en forme.
+ */
+func (dryRun *DryRunNotifier) render(receipt string, message Message) string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "=== Notification simulée n°%s : %s", receipt, message.Title)
+	if message.Agency != "" {
+		fmt.Fprintf(&builder, " (%s)", message.Agency)
+	}
+	builder.WriteString(" ===\n")
+	if len(dryRun.services) > 0 {
+		fmt.Fprintf(&builder, "Services : %s\n", strings.Join(dryRun.services, ", "))
+	}
+
+	// Notification précédente à laquelle le message répond, par service
+	if len(message.ReplyTo) > 0 {
+		replies := make([]string, 0, len(message.ReplyTo))
+		for service, id := range message.ReplyTo {
+			if service == dryRun.Name() {
+				replies = append(replies, "notification simulée n°"+id)
+			} else {
+				replies = append(replies, service+" "+id)
+			}
+		}
+		sort.Strings(replies)
+		fmt.Fprintf(&builder, "En réponse à : %s\n", strings.Join(replies, ", "))
+	}
+
+	if len(message.PhotoURLs) > 0 {
+		fmt.Fprintf(&builder, "Photos (%d) :\n", len(message.PhotoURLs))
+		for _, photoURL := range message.PhotoURLs {
+			fmt.Fprintf(&builder, "  %s\n", photoURL)
+		}
+		if len(message.Text) > telegramCaptionMaxLength {
+			fmt.Fprintf(&builder, "  (texte de plus de %d caractères : envoyé sans photo sur Telegram)\n", telegramCaptionMaxLength)
+		}
+	}
+
+	fmt.Fprintf(&builder, "Texte :\n%s\n", message.Text)
+
+	if len(message.Buttons) > 0 {
+		builder.WriteString("Boutons :\n")
+		for _, button := range message.Buttons {
+			fmt.Fprintf(&builder, "  [%s] %s\n", button.Label, button.URL)
+		}
+	}
+
+	builder.WriteString("\n")
+	return builder.String()
+}
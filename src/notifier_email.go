@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * EmailNotifier envoie les notifications par e-mail via un serveur SMTP.
+ * @property {string} host - Nom du serveur SMTP.
+ * @property {string} address - Adresse du serveur SMTP (hôte:port).
+ * @property {smtp.Auth} auth - Authentification SMTP (nil sans utilisateur).
+ * @property {string} from - Expéditeur.
+ * @property {[]string} to - Destinataires.
+ */
+type EmailNotifier struct {
+	host    string
+	address string
+	auth    smtp.Auth
+	from    string
+	to      []string
+}
+
+/**
+ * NewEmailNotifier crée le service d'envoi d'e-mails.
+ * @param {NotifierConfig} config - La configuration du service (smtp_host, smtp_port, username, password_secret, from, to).
+ * @return {EmailNotifier} - Le service prêt à envoyer des e-mails.
+ * @return {error} - Erreur si le mot de passe est manquant.
+ */
+func NewEmailNotifier(config NotifierConfig) (*EmailNotifier, error) {
+	port := config.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+
+	notifier := &EmailNotifier{
+		host:    config.SMTPHost,
+		address: net.JoinHostPort(config.SMTPHost, strconv.Itoa(port)),
+		from:    config.From,
+		to:      config.To,
+	}
+
+	if config.Username != "" {
+		password, err := requireSecret(config.PasswordSecret)
+		if err != nil {
+			return nil, err
+		}
+		notifier.auth = smtp.PlainAuth("", config.Username, password, config.SMTPHost)
+	}
+
+	return notifier, nil
+}
+
+func (email *EmailNotifier) Name() string {
+	return "email"
+}
+
+/**
+ * Notify envoie le message par e-mail, en texte brut UTF-8, avec les liens des photos et des boutons en fin de message.
+ * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+ * @param {Message} message - Le message à envoyer.
+ * @return {error} - Erreur lors de l'envoi.
+ */
+func (email *EmailNotifier) Notify(ctx context.Context, message Message) error {
+	body := message.Text
+	for _, button := range message.Buttons {
+		body += fmt.Sprintf("\n%s : %s", button.Label, button.URL)
+	}
+	for _, photoURL := range message.PhotoURLs {
+		body += "\nPhoto : " + photoURL
+	}
+
+	headers := []string{
+		"From: " + email.from,
+		"To: " + strings.Join(email.to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", message.Title),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	content := strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(body, "\n", "\r\n") + "\r\n"
+
+	if err := email.send(ctx, []byte(content)); err != nil {
+		return fmt.Errorf("envoi de l'e-mail : %w", err)
+	}
+	return nil
+}
+
+/**
+ * send envoie le contenu d'un e-mail comme smtp.SendMail, en interrompant la connexion à l'annulation du contexte.
+ * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+ * @param {[]byte} content - Le contenu de l'e-mail (en-têtes et corps).
+ * @return {error} - Erreur lors de l'envoi.
+ */
+func (email *EmailNotifier) send(ctx context.Context, content []byte) error {
+	dialer := net.Dialer{Timeout: notifierHTTPTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", email.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, email.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: email.host}); err != nil {
+			return err
+		}
+	}
+	if email.auth != nil {
+		if err := client.Auth(email.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(email.from); err != nil {
+		return err
+	}
+	for _, recipient := range email.to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(content); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/**
+ * WebhookNotifier envoie chaque message en JSON (POST) vers une URL générique.
+ * @property {http.Client} client - Le client HTTP.
+ * @property {string} url - L'URL du webhook.
+ * @property {map[string]string} headers - En-têtes supplémentaires (dont Authorization si token_secret est défini).
+ */
+type WebhookNotifier struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+/**
+ * webhookPayload est le document JSON envoyé au webhook générique.
+ */
+type webhookPayload struct {
+	Title        string            `json:"title"`
+	Text         string            `json:"text"`
+	URL          string            `json:"url,omitempty"`
+	PhotoURLs    []string          `json:"photoURLs,omitempty"`
+	Buttons      []MessageButton   `json:"buttons,omitempty"`
+	Agency       Agency            `json:"agency,omitempty"`
+	Announcement *AnnouncementData `json:"announcement,omitempty"`
+	Listings     []PropertyListing `json:"listings,omitempty"`
+}
+
+/**
+ * NewWebhookNotifier crée le service de webhook JSON générique.
+ * @param {NotifierConfig} config - La configuration du service (url, headers, token_secret).
+ * @param {http.Client} client - Le client HTTP.
+ * @return {WebhookNotifier} - Le service prêt à envoyer des messages.
+ * @return {error} - Erreur si le jeton est manquant.
+ */
+func NewWebhookNotifier(config NotifierConfig, client *http.Client) (*WebhookNotifier, error) {
+	headers := make(map[string]string, len(config.Headers)+1)
+	for key, value := range config.Headers {
+		headers[key] = value
+	}
+	if config.TokenSecret != "" {
+		token, err := requireSecret(config.TokenSecret)
+		if err != nil {
+			return nil, err
+		}
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	return &WebhookNotifier{client: client, url: config.URL, headers: headers}, nil
+}
+
+func (webhook *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (webhook *WebhookNotifier) Notify(ctx context.Context, message Message) error {
+	return postJSON(ctx, webhook.client, http.MethodPost, webhook.url, webhookPayload{
+		Title:        message.Title,
+		Text:         message.Text,
+		URL:          message.URL,
+		PhotoURLs:    message.PhotoURLs,
+		Buttons:      message.Buttons,
+		Agency:       message.Agency,
+		Announcement: message.Announcement,
+		Listings:     message.Listings,
+	}, webhook.headers)
+}
+
+// Longueur maximale du contenu d'un message Discord
+const discordContentMaxLength = 2000
+
+/**
+ * ChatWebhookNotifier envoie les messages vers un webhook entrant Discord ou Slack.
+ * @property {http.Client} client - Le client HTTP.
+ * @property {string} kind - Le format du webhook : discord ou slack.
+ * @property {string} url - L'URL du webhook.
+ */
+type ChatWebhookNotifier struct {
+	client *http.Client
+	kind   string
+	url    string
+}
+
+/**
+ * NewChatWebhookNotifier crée le service de webhook entrant Discord ou Slack.
+ * L'URL d'un webhook entrant donne le droit de publier : elle peut être lue depuis un secret (url_secret).
+ * @param {NotifierConfig} config - La configuration du service (type, url ou url_secret).
+ * @param {http.Client} client - Le client HTTP.
+ * @return {ChatWebhookNotifier} - Le service prêt à envoyer des messages.
+ * @return {error} - Erreur si le secret est manquant.
+ */
+func NewChatWebhookNotifier(config NotifierConfig, client *http.Client) (*ChatWebhookNotifier, error) {
+	webhookURL := config.URL
+	if config.URLSecret != "" {
+		secretURL, err := requireSecret(config.URLSecret)
+		if err != nil {
+			return nil, err
+		}
+		webhookURL = secretURL
+	}
+
+	return &ChatWebhookNotifier{client: client, kind: config.Type, url: webhookURL}, nil
+}
+
+func (chat *ChatWebhookNotifier) Name() string {
+	return chat.kind
+}
+
+func (chat *ChatWebhookNotifier) Notify(ctx context.Context, message Message) error {
+	if chat.kind == "slack" {
+		return postJSON(ctx, chat.client, http.MethodPost, chat.url, map[string]any{
+			"text":         message.Text,
+			"unfurl_links": true,
+		}, nil)
+	}
+
+	content := []rune(message.Text)
+	if len(content) > discordContentMaxLength {
+		content = content[:discordContentMaxLength]
+	}
+	payload := map[string]any{"content": string(content)}
+	if len(message.PhotoURLs) > 0 {
+		payload["embeds"] = []map[string]any{{
+			"title": message.Title,
+			"url":   message.URL,
+			"image": map[string]string{"url": message.PhotoURLs[0]},
+		}}
+	}
+	return postJSON(ctx, chat.client, http.MethodPost, chat.url, payload, nil)
+}
+
+/**
+ * MatrixNotifier envoie les messages dans un salon Matrix via l'API client-serveur.
+ * @property {http.Client} client - Le client HTTP.
+ * @property {string} homeserver - L'URL du serveur Matrix.
+ * @property {string} roomID - L'identifiant du salon.
+ * @property {string} accessToken - Le jeton d'accès du compte émetteur.
+ * @property {atomic.Int64} transactionCounter - Compteur garantissant des identifiants de transaction uniques.
+ */
+type MatrixNotifier struct {
+	client             *http.Client
+	homeserver         string
+	roomID             string
+	accessToken        string
+	transactionCounter atomic.Int64
+}
+
+/**
+ * NewMatrixNotifier crée le service d'envoi vers Matrix.
+ * @param {NotifierConfig} config - La configuration du service (url, room_id, token_secret).
+ * @param {http.Client} client - Le client HTTP.
+ * @return {MatrixNotifier} - Le service prêt à envoyer des messages.
+ * @return {error} - Erreur si le jeton est manquant.
+ */
+func NewMatrixNotifier(config NotifierConfig, client *http.Client) (*MatrixNotifier, error) {
+	accessToken, err := requireSecret(config.TokenSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MatrixNotifier{
+		client:      client,
+		homeserver:  strings.TrimSuffix(config.URL, "/"),
+		roomID:      config.RoomID,
+		accessToken: accessToken,
+	}, nil
+}
+
+func (matrix *MatrixNotifier) Name() string {
+	return "matrix"
+}
+
+func (matrix *MatrixNotifier) Notify(ctx context.Context, message Message) error {
+	transactionID := fmt.Sprintf("%d-%d", time.Now().UnixNano(), matrix.transactionCounter.Add(1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		matrix.homeserver, url.PathEscape(matrix.roomID), transactionID)
+
+	return postJSON(ctx, matrix.client, http.MethodPut, endpoint, map[string]string{
+		"msgtype": "m.text",
+		"body":    message.Text,
+	}, map[string]string{"Authorization": "Bearer " + matrix.accessToken})
+}
+
+/**
+ * NtfyNotifier publie les messages sur un sujet ntfy.
+ * @property {http.Client} client - Le client HTTP.
+ * @property {string} url - L'URL du sujet (serveur + sujet).
+ * @property {string} token - Le jeton d'accès (optionnel).
+ */
+type NtfyNotifier struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+/**
+ * NewNtfyNotifier crée le service de publication ntfy.
+ * @param {NotifierConfig} config - La configuration du service (url du serveur, https://ntfy.sh par défaut, topic, token_secret).
+ * @param {http.Client} client - Le client HTTP.
+ * @return {NtfyNotifier} - Le service prêt à envoyer des messages.
+ * @return {error} - Erreur si le jeton est manquant.
+ */
+func NewNtfyNotifier(config NotifierConfig, client *http.Client) (*NtfyNotifier, error) {
+	server := config.URL
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+
+	notifier := &NtfyNotifier{
+		client: client,
+		url:    strings.TrimSuffix(server, "/") + "/" + url.PathEscape(config.Topic),
+	}
+	if config.TokenSecret != "" {
+		token, err := requireSecret(config.TokenSecret)
+		if err != nil {
+			return nil, err
+		}
+		notifier.token = token
+	}
+
+	return notifier, nil
+}
+
+func (ntfy *NtfyNotifier) Name() string {
+	return "ntfy"
+}
+
+func (ntfy *NtfyNotifier) Notify(ctx context.Context, message Message) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, ntfy.url, strings.NewReader(message.Text))
+	if err != nil {
+		return err
+	}
+
+	// Les en-têtes HTTP ne supportent que l'ASCII : ntfy accepte l'encodage RFC 2047 pour le titre
+	request.Header.Set("Title", mime.QEncoding.Encode("utf-8", message.Title))
+	request.Header.Set("Tags", "house")
+	if message.URL != "" {
+		request.Header.Set("Click", message.URL)
+	}
+	if len(message.PhotoURLs) > 0 {
+		request.Header.Set("Attach", message.PhotoURLs[0])
+	}
+	if ntfy.token != "" {
+		request.Header.Set("Authorization", "Bearer "+ntfy.token)
+	}
+
+	return doRequest(ntfy.client, request)
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testMessage est le message envoyé à chaque service dans les tests
+var testMessage = Message{
+	Title:     "AFEDIM",
+	Text:      "AFEDIM\nNouvelle annonce immobilière !\nRéférence : REF-1",
+	URL:       "https://example.com/annonce/1",
+	PhotoURLs: []string{"https://example.com/photo.jpg"},
+	Buttons:   []MessageButton{{Label: "Voir l'annonce", URL: "https://example.com/annonce/1"}},
+	Agency:    Afedim,
+	Announcement: &AnnouncementData{
+		Reference: "REF-1",
+		URL:       "https://example.com/annonce/1",
+	},
+}
+
+// recordedRequest est une requête reçue par un serveur de test
+type recordedRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// newRecordingServer démarre un serveur de test qui enregistre les requêtes reçues
+func newRecordingServer(t *testing.T) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+
+	var mutex sync.Mutex
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		requests = append(requests, recordedRequest{method: r.Method, path: r.URL.Path, header: r.Header.Clone(), body: body})
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []recordedRequest {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	t.Setenv("WEBHOOK_TOKEN", "secret-token")
+
+	notifier, err := NewNotifier([]NotifierConfig{{
+		Type:        "webhook",
+		URL:         server.URL + "/hook",
+		TokenSecret: "WEBHOOK_TOKEN",
+		Headers:     map[string]string{"X-Source": "scraper"},
+	}})
+	if err != nil {
+		t.Fatalf("NewNotifier : %v", err)
+	}
+	if err := notifier.Notify(context.Background(), testMessage); err != nil {
+		t.Fatalf("Notify : %v", err)
+	}
+
+	received := requests()
+	if len(received) != 1 {
+		t.Fatalf("requêtes reçues = %d, attendu 1", len(received))
+	}
+	request := received[0]
+	if request.method != http.MethodPost || request.path != "/hook" {
+		t.Errorf("requête = %s %s, attendu POST /hook", request.method, request.path)
+	}
+	if got := request.header.Get("Authorization"); got != "Bearer secret-token" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := request.header.Get("X-Source"); got != "scraper" {
+		t.Errorf("X-Source = %q", got)
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatalf("décodage du corps : %v", err)
+	}
+	if payload.Title != "AFEDIM" || payload.Agency != Afedim || payload.Announcement == nil || payload.Announcement.Reference != "REF-1" {
+		t.Errorf("payload inattendu : %+v", payload)
+	}
+}
+
+func TestChatWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		kind      string
+		wantField string
+	}{
+		{kind: "discord", wantField: "content"},
+		{kind: "slack", wantField: "text"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.kind, func(t *testing.T) {
+			server, requests := newRecordingServer(t)
+			t.Setenv("CHAT_WEBHOOK_URL", server.URL+"/incoming")
+
+			notifier, err := NewNotifier([]NotifierConfig{{Type: test.kind, URLSecret: "CHAT_WEBHOOK_URL"}})
+			if err != nil {
+				t.Fatalf("NewNotifier : %v", err)
+			}
+			if err := notifier.Notify(context.Background(), testMessage); err != nil {
+				t.Fatalf("Notify : %v", err)
+			}
+
+			received := requests()
+			if len(received) != 1 || received[0].path != "/incoming" {
+				t.Fatalf("requêtes reçues inattendues : %+v", received)
+			}
+			var payload map[string]any
+			if err := json.Unmarshal(received[0].body, &payload); err != nil {
+				t.Fatalf("décodage du corps : %v", err)
+			}
+			if payload[test.wantField] != testMessage.Text {
+				t.Errorf("%s = %v, attendu %q", test.wantField, payload[test.wantField], testMessage.Text)
+			}
+		})
+	}
+}
+
+func TestMatrixNotifier(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	t.Setenv("MATRIX_TOKEN", "matrix-token")
+
+	notifier, err := NewNotifier([]NotifierConfig{{
+		Type:        "matrix",
+		URL:         server.URL,
+		RoomID:      "!room:example.com",
+		TokenSecret: "MATRIX_TOKEN",
+	}})
+	if err != nil {
+		t.Fatalf("NewNotifier : %v", err)
+	}
+	if err := notifier.Notify(context.Background(), testMessage); err != nil {
+		t.Fatalf("Notify : %v", err)
+	}
+
+	received := requests()
+	if len(received) != 1 {
+		t.Fatalf("requêtes reçues = %d, attendu 1", len(received))
+	}
+	request := received[0]
+	if request.method != http.MethodPut || !strings.HasPrefix(request.path, "/_matrix/client/v3/rooms/!room:example.com/send/m.room.message/") {
+		t.Errorf("requête = %s %s", request.method, request.path)
+	}
+	if got := request.header.Get("Authorization"); got != "Bearer matrix-token" {
+		t.Errorf("Authorization = %q", got)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatalf("décodage du corps : %v", err)
+	}
+	if payload["msgtype"] != "m.text" || payload["body"] != testMessage.Text {
+		t.Errorf("payload inattendu : %v", payload)
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	server, requests := newRecordingServer(t)
+
+	notifier, err := NewNotifier([]NotifierConfig{{Type: "ntfy", URL: server.URL, Topic: "annonces"}})
+	if err != nil {
+		t.Fatalf("NewNotifier : %v", err)
+	}
+	if err := notifier.Notify(context.Background(), testMessage); err != nil {
+		t.Fatalf("Notify : %v", err)
+	}
+
+	received := requests()
+	if len(received) != 1 {
+		t.Fatalf("requêtes reçues = %d, attendu 1", len(received))
+	}
+	request := received[0]
+	if request.path != "/annonces" || string(request.body) != testMessage.Text {
+		t.Errorf("requête inattendue : %s %q", request.path, request.body)
+	}
+	if request.header.Get("Click") != testMessage.URL || request.header.Get("Attach") != testMessage.PhotoURLs[0] {
+		t.Errorf("en-têtes inattendus : %v", request.header)
+	}
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	var mutex sync.Mutex
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		mutex.Lock()
+		methods = append(methods, method)
+		mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch method {
+		case "getMe":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`))
+		case "sendPhoto":
+			if r.FormValue("chat_id") != "@annonces_test" || r.FormValue("photo") != testMessage.PhotoURLs[0] {
+				t.Errorf("sendPhoto inattendu : %v", r.Form)
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":42,"date":0,"chat":{"id":-100}}}`))
+		case "sendMessage":
+			if r.FormValue("reply_to_message_id") != "42" {
+				t.Errorf("sendMessage inattendu : %v", r.Form)
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":43,"date":0,"chat":{"id":-100}}}`))
+		default:
+			t.Errorf("méthode inattendue : %s", method)
+			_, _ = w.Write([]byte(`{"ok":false,"description":"unexpected"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("TELEGRAM_BOT_TOKEN", "123:test")
+	t.Setenv("TELEGRAM_CHANNEL", "@annonces_test")
+
+	notifier, err := NewNotifier([]NotifierConfig{{Type: "telegram", URL: server.URL}})
+	if err != nil {
+		t.Fatalf("NewNotifier : %v", err)
+	}
+	if err := notifier.Notify(context.Background(), testMessage); err != nil {
+		t.Fatalf("Notify : %v", err)
+	}
+
+	// Le retrait de l'annonce est envoyé en réponse à la notification d'origine
+	receipts, err := notifyWithReceipts(context.Background(), notifier, testMessage)
+	if err != nil || receipts["telegram"] != "42" {
+		t.Fatalf("notifyWithReceipts = %v, %v", receipts, err)
+	}
+	if err := notifier.Notify(context.Background(), Message{Text: "Loué / retiré", ReplyTo: receipts}); err != nil {
+		t.Fatalf("Notify (réponse) : %v", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if strings.Join(methods, ",") != "getMe,sendPhoto,sendPhoto,sendMessage" {
+		t.Errorf("méthodes appelées = %v", methods)
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("écoute SMTP : %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	// Serveur SMTP minimal qui enregistre le contenu du message
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost")
+
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					received <- data.String()
+					reply("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case command == "DATA":
+				inData = true
+				reply("354 Go ahead")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	notifier, err := NewNotifier([]NotifierConfig{{
+		Type:     "email",
+		SMTPHost: "127.0.0.1",
+		SMTPPort: port,
+		From:     "scraper@example.com",
+		To:       []string{"moi@example.com"},
+	}})
+	if err != nil {
+		t.Fatalf("NewNotifier : %v", err)
+	}
+	if err := notifier.Notify(context.Background(), testMessage); err != nil {
+		t.Fatalf("Notify : %v", err)
+	}
+
+	content := <-received
+	for _, want := range []string{"To: moi@example.com", "Subject: AFEDIM", "Référence : REF-1", "Photo : https://example.com/photo.jpg"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("contenu sans %q :\n%s", want, content)
+		}
+	}
+}
+
+func TestDryRunNotifier(t *testing.T) {
+	var output strings.Builder
+	notifier := NewDryRunNotifier(&output, []NotifierConfig{{Type: "telegram"}, {Type: "email", Name: "famille"}})
+
+	first, err := notifier.NotifyWithReceipt(context.Background(), testMessage)
+	if err != nil {
+		t.Fatalf("NotifyWithReceipt : %v", err)
+	}
+	gone := Message{Title: "AFEDIM", Text: "Annonce retirée", ReplyTo: map[string]string{notifier.Name(): first}}
+	if err := notifier.Notify(context.Background(), gone); err != nil {
+		t.Fatalf("Notify : %v", err)
+	}
+
+	want := "=== Notification simulée n°1 : AFEDIM (Afedim) ===\n" +
+		"Services : telegram, famille\n" +
+		"Photos (1) :\n  https://example.com/photo.jpg\n" +
+		"Texte :\nAFEDIM\nNouvelle annonce immobilière !\nRéférence : REF-1\n" +
+		"Boutons :\n  [Voir l'annonce] https://example.com/annonce/1\n\n" +
+		"=== Notification simulée n°2 : AFEDIM ===\n" +
+		"Services : telegram, famille\n" +
+		"En réponse à : notification simulée n°1\n" +
+		"Texte :\nAnnonce retirée\n\n"
+	if got := output.String(); got != want {
+		t.Errorf("sortie :\n%s\nattendu :\n%s", got, want)
+	}
+}
+
+func TestNotifierConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  NotifierConfig
+		wantErr bool
+	}{
+		{name: "telegram par défaut", config: NotifierConfig{Type: "telegram"}},
+		{name: "type inconnu", config: NotifierConfig{Type: "pigeon"}, wantErr: true},
+		{name: "webhook sans url", config: NotifierConfig{Type: "webhook"}, wantErr: true},
+		{name: "matrix sans salon", config: NotifierConfig{Type: "matrix", URL: "https://matrix.org", TokenSecret: "T"}, wantErr: true},
+		{name: "email complet", config: NotifierConfig{Type: "email", SMTPHost: "smtp", From: "a@b.c", To: []string{"d@e.f"}}},
+		{name: "email sans mot de passe", config: NotifierConfig{Type: "email", SMTPHost: "smtp", From: "a@b.c", To: []string{"d@e.f"}, Username: "u"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.config.Validate(); (err != nil) != test.wantErr {
+				t.Errorf("Validate() = %v, erreur attendue : %v", err, test.wantErr)
+			}
+		})
+	}
+}
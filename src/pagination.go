@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Nombre total d'annonces affiché sur la page de résultats (ex : "42 biens", "1 250 résultats")
+var totalCountPattern = regexp.MustCompile(`\d{1,3}(?:[ \x{a0}\x{202f}.]\d{3})+|\d+`)
+
+/**
+ * Pagination décrit comment trouver la page de résultats suivante d'une agence.
+ * Trois modes sont possibles :
+ * - lien "page suivante" : NextSelector cible le lien vers la page suivante ;
+ * - paramètre de page : PageParam est incrémenté tant que la page courante contient des annonces ;
+ * - nombre total : TotalSelector cible le nombre total d'annonces (ou l'API JSON de l'agence le fournit)
+ *   et PageSize le nombre d'annonces par page, le paramètre PageParam est incrémenté jusqu'à la dernière page.
+ * @property {string} NextSelector - Sélecteur du lien vers la page suivante.
+ * @property {string} PageParam - Paramètre de requête portant le numéro de page (ex : "page").
+ * @property {string} TotalSelector - Sélecteur de l'élément affichant le nombre total d'annonces.
+ * @property {int} PageSize - Nombre d'annonces par page (mode nombre total).
+ */
+type Pagination struct {
+	NextSelector  string `yaml:"next"`
+	PageParam     string `yaml:"page_param"`
+	TotalSelector string `yaml:"total"`
+	PageSize      int    `yaml:"page_size"`
+}
+
+/**
+ * paginationState conserve les informations de pagination lues sur la dernière page visitée.
+ * @property {string} nextURL - URL de la page suivante (mode lien "page suivante").
+ * @property {int} total - Nombre total d'annonces (mode nombre total), 0 si inconnu.
+ */
+type paginationState struct {
+	nextURL string
+	total   int
+}
+
+/**
+ * register configure le collecteur pour lire les informations de pagination de chaque page.
+ * @param {colly.Collector} collector - Le collecteur de la page de résultats.
+ * @param {paginationState} state - L'état à remplir.
+ * @return {void}
+ */
+func (pagination *Pagination) register(collector *colly.Collector, state *paginationState) {
+	if pagination.NextSelector != "" {
+		collector.OnHTML(pagination.NextSelector, func(e *colly.HTMLElement) {
+			if href := e.Attr("href"); href != "" && state.nextURL == "" {
+				state.nextURL = e.Request.AbsoluteURL(href)
+			}
+		})
+	}
+
+	if pagination.TotalSelector != "" {
+		collector.OnHTML(pagination.TotalSelector, func(e *colly.HTMLElement) {
+			if total := parseNumber(totalCountPattern.FindString(e.Text)); total != nil {
+				state.total = int(*total)
+			}
+		})
+	}
+}
+
+/**
+ * next retourne l'URL de la page suivante, ou une chaîne vide s'il n'y en a pas.
+ * @param {string} pageURL - L'URL de la page qui vient d'être visitée.
+ * @param {int} page - Le numéro de la page visitée (à partir de 1).
+ * @param {int} newItems - Le nombre de nouvelles annonces trouvées sur la page.
+ * @param {int} found - Le nombre d'annonces trouvées depuis la première page.
+ * @param {paginationState} state - Les informations lues sur la page.
+ * @return {string} - L'URL de la page suivante.
+ */
+func (pagination *Pagination) next(pageURL string, page int, newItems int, found int, state *paginationState) string {
+	// Une page sans nouvelle annonce est la dernière (certains sites renvoient la dernière page au-delà de la fin)
+	if newItems == 0 {
+		return ""
+	}
+
+	if pagination.NextSelector != "" {
+		return state.nextURL
+	}
+
+	// Nombre total connu : s'arrêter une fois toutes les annonces trouvées ou la dernière page atteinte
+	if state.total > 0 && (found >= state.total || (pagination.PageSize > 0 && page*pagination.PageSize >= state.total)) {
+		return ""
+	}
+
+	if pagination.PageParam != "" {
+		return withPageParam(pageURL, pagination.PageParam)
+	}
+	return ""
+}
+
+/**
+ * withPageParam incrémente le paramètre de page d'une URL (absent : la page courante est la première).
+ * @param {string} pageURL - L'URL de la page courante.
+ * @param {string} param - Le nom du paramètre de page.
+ * @return {string} - L'URL de la page suivante, ou une chaîne vide si l'URL est invalide.
+ */
+func withPageParam(pageURL string, param string) string {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+
+	query := parsedURL.Query()
+	current, err := strconv.Atoi(query.Get(param))
+	if err != nil || current < 1 {
+		current = 1
+	}
+	query.Set(param, strconv.Itoa(current+1))
+	parsedURL.RawQuery = query.Encode()
+
+	return parsedURL.String()
+}
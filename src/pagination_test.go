@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestWithPageParam(t *testing.T) {
+	tests := []struct {
+		name    string
+		pageURL string
+		want    string
+	}{
+		{name: "sans paramètre", pageURL: "https://www.exemple.fr/location", want: "https://www.exemple.fr/location?page=2"},
+		{name: "première page", pageURL: "https://www.exemple.fr/location?page=1", want: "https://www.exemple.fr/location?page=2"},
+		{name: "page suivante", pageURL: "https://www.exemple.fr/location?page=3", want: "https://www.exemple.fr/location?page=4"},
+		{name: "numéro invalide", pageURL: "https://www.exemple.fr/location?page=abc", want: "https://www.exemple.fr/location?page=2"},
+		{name: "numéro nul", pageURL: "https://www.exemple.fr/location?page=0", want: "https://www.exemple.fr/location?page=2"},
+		// Les paramètres sont triés et réencodés, sans perdre les critères de la recherche
+		{
+			name:    "autres paramètres",
+			pageURL: "https://www.exemple.fr/location?ville=saint-malo&page=1&type=appartement,maison&prix=500+800",
+			want:    "https://www.exemple.fr/location?page=2&prix=500+800&type=appartement%2Cmaison&ville=saint-malo",
+		},
+		{name: "URL invalide", pageURL: "://exemple", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := withPageParam(test.pageURL, "page"); got != test.want {
+				t.Errorf("withPageParam = %q, attendu %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPaginationNext(t *testing.T) {
+	const pageURL = "https://www.exemple.fr/location?page=2"
+	nextLink := &Pagination{NextSelector: "a.next"}
+	pageParam := &Pagination{PageParam: "page"}
+	total := &Pagination{PageParam: "page", TotalSelector: "span.total", PageSize: 10}
+
+	tests := []struct {
+		name       string
+		pagination *Pagination
+		page       int
+		newItems   int
+		found      int
+		state      paginationState
+		want       string
+	}{
+		{name: "lien suivant", pagination: nextLink, page: 2, newItems: 10, found: 20, state: paginationState{nextURL: "https://www.exemple.fr/location/p3"}, want: "https://www.exemple.fr/location/p3"},
+		{name: "sans lien suivant", pagination: nextLink, page: 2, newItems: 10, found: 20},
+		{name: "page sans nouvelle annonce", pagination: nextLink, page: 2, found: 20, state: paginationState{nextURL: "https://www.exemple.fr/location/p3"}},
+		{name: "paramètre de page", pagination: pageParam, page: 2, newItems: 10, found: 20, want: "https://www.exemple.fr/location?page=3"},
+		{name: "paramètre de page, dernière page", pagination: pageParam, page: 2, found: 20},
+		{name: "nombre total non atteint", pagination: total, page: 2, newItems: 10, found: 20, state: paginationState{total: 25}, want: "https://www.exemple.fr/location?page=3"},
+		{name: "nombre total atteint", pagination: total, page: 2, newItems: 5, found: 25, state: paginationState{total: 25}},
+		// Annonces en double sur les pages : la dernière page est déduite du nombre d'annonces par page
+		{name: "dernière page d'après le total", pagination: total, page: 3, newItems: 4, found: 24, state: paginationState{total: 25}},
+		{name: "nombre total inconnu", pagination: total, page: 3, newItems: 10, found: 30, want: "https://www.exemple.fr/location?page=3"},
+		{name: "aucun mode", pagination: &Pagination{}, page: 1, newItems: 10, found: 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := test.state
+			if got := test.pagination.next(pageURL, test.page, test.newItems, test.found, &state); got != test.want {
+				t.Errorf("next = %q, attendu %q", got, test.want)
+			}
+		})
+	}
+}
+
+// newPaginatedServer sert une recherche de 5 annonces, 2 par page : le paramètre page ou le lien "suivante" change de page,
+// et une page au-delà de la dernière renvoie la dernière page
+func newPaginatedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+		page = min(page, 3)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><body><span class="total">5 biens</span>`)
+		for id := 1000 + 2*page - 1; id <= min(1000+2*page, 1005); id++ {
+			fmt.Fprintf(w, `<article><a href="/location/bien-%d">Bien %d</a></article>`, id, id)
+		}
+		if page < 3 {
+			fmt.Fprintf(w, `<a class="suivante" href="/location?page=%d">Suivante</a>`, page+1)
+		}
+		fmt.Fprint(w, `</body></html>`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestScrapeAnnouncementPagination(t *testing.T) {
+	server := newPaginatedServer(t)
+	t.Cleanup(func() { _ = RegisterAgencyDefinitions(nil) })
+
+	err := RegisterAgencyDefinitions([]AgencyDefinition{
+		{Name: "Lien suivant", Listing: ListingDefinition{Item: "article", Link: "a"}, Pagination: &Pagination{NextSelector: "a.suivante"}},
+		{Name: "Paramètre de page", Listing: ListingDefinition{Item: "article", Link: "a"}, Pagination: &Pagination{PageParam: "page"}},
+		{Name: "Nombre total", Listing: ListingDefinition{Item: "article", Link: "a"}, Pagination: &Pagination{PageParam: "page", TotalSelector: "span.total", PageSize: 2}},
+		{Name: "Sans pagination", Listing: ListingDefinition{Item: "article", Link: "a"}},
+	})
+	if err != nil {
+		t.Fatalf("RegisterAgencyDefinitions : %v", err)
+	}
+
+	tests := []struct {
+		agency   Agency
+		url      string
+		maxPages int
+		want     ScrapeStats
+	}{
+		{agency: "Lien suivant", url: "/location", maxPages: 5, want: ScrapeStats{Pages: 3, Items: 5, Announcements: 5}},
+		// Dernière page atteinte exactement à max_pages : la recherche n'est pas tronquée
+		{agency: "Lien suivant", url: "/location", maxPages: 3, want: ScrapeStats{Pages: 3, Items: 5, Announcements: 5}},
+		{agency: "Lien suivant", url: "/location", maxPages: 2, want: ScrapeStats{Pages: 2, Truncated: true, Items: 4, Announcements: 4}},
+		// La 4e page renvoie la dernière page : aucune nouvelle annonce, fin de la recherche
+		{agency: "Paramètre de page", url: "/location?page=1", maxPages: 10, want: ScrapeStats{Pages: 4, Items: 5, Announcements: 5}},
+		{agency: "Nombre total", url: "/location", maxPages: 10, want: ScrapeStats{Pages: 3, Items: 5, Announcements: 5}},
+		{agency: "Sans pagination", url: "/location", maxPages: 10, want: ScrapeStats{Pages: 1, Items: 2, Announcements: 2}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s, %d pages au plus", test.agency, test.maxPages), func(t *testing.T) {
+			// Recherches en parallèle : le collecteur attend 2 secondes entre deux pages
+			t.Parallel()
+			collyService := NewCollyService()
+			announcements, err := collyService.ScrapeAnnouncement(context.Background(), test.agency, server.URL+test.url, test.maxPages)
+			if err != nil {
+				t.Fatalf("ScrapeAnnouncement : %v", err)
+			}
+			if stats := collyService.Stats(); stats != test.want {
+				t.Errorf("statistiques = %+v, attendu %+v", stats, test.want)
+			}
+			for i, announcement := range announcements {
+				if want := strconv.Itoa(1001 + i); announcement.propertyReference != want {
+					t.Errorf("annonce %d = %q, attendu %q", i, announcement.propertyReference, want)
+				}
+			}
+		})
+	}
+}
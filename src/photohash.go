@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"  // Décodage des photos GIF
+	_ "image/jpeg" // Décodage des photos JPEG
+	_ "image/png"  // Décodage des photos PNG
+	"io"
+	"math/bits"
+	"net/http"
+)
+
+// Taille maximale d'une photo téléchargée pour le calcul de l'empreinte
+const photoMaxBytes = 8 << 20
+
+// Distance de Hamming maximale entre deux empreintes de photos d'un même bien
+const photoHashMaxDistance = 10
+
+/**
+ * fetchPhotoHash télécharge une photo et calcule son empreinte perceptuelle.
+ * @param {context.Context} ctx - Contexte d'annulation du téléchargement.
+ * @param {http.Client} client - Le client HTTP.
+ * @param {string} photoURL - L'URL de la photo.
+ * @return {uint64} - L'empreinte de la photo.
+ * @return {error} - Erreur de téléchargement ou format d'image non supporté.
+ */
+func fetchPhotoHash(ctx context.Context, client *http.Client, photoURL string) (uint64, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, photoURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("réponse HTTP %d", response.StatusCode)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(response.Body, photoMaxBytes))
+	if err != nil {
+		return 0, fmt.Errorf("décodage de la photo : %w", err)
+	}
+	return differenceHash(img), nil
+}
+
+/**
+ * differenceHash calcule l'empreinte perceptuelle "dHash" d'une image : l'image est réduite à 9x8 niveaux de gris
+ * et chaque bit indique si un pixel est plus clair que son voisin de droite. Deux photos identiques,
+ * même recadrées légèrement ou recompressées, ont des empreintes proches.
+ * @param {image.Image} img - L'image.
+ * @return {uint64} - L'empreinte sur 64 bits.
+ */
+func differenceHash(img image.Image) uint64 {
+	const width, height = 9, 8
+	bounds := img.Bounds()
+
+	// Réduire l'image en moyennant les pixels de chaque case
+	var gray [height][width]float64
+	for y := 0; y < height; y++ {
+		minY := bounds.Min.Y + y*bounds.Dy()/height
+		maxY := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, minY+1)
+		for x := 0; x < width; x++ {
+			minX := bounds.Min.X + x*bounds.Dx()/width
+			maxX := max(bounds.Min.X+(x+1)*bounds.Dx()/width, minX+1)
+
+			var sum float64
+			var count int
+			for py := minY; py < maxY; py++ {
+				for px := minX; px < maxX; px++ {
+					r, g, b, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					count++
+				}
+			}
+			gray[y][x] = sum / float64(count)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			hash <<= 1
+			if gray[y][x] > gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+/**
+ * photoHashDistance retourne le nombre de bits différents entre deux empreintes.
+ * @param {uint64} a - Première empreinte.
+ * @param {uint64} b - Seconde empreinte.
+ * @return {int} - La distance de Hamming.
+ */
+func photoHashDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
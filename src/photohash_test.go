@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// gradientImage crée une image en niveaux de gris dont la luminosité varie horizontalement
+func gradientImage(width int, height int, increasing bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			level := uint8(x * 255 / (width - 1))
+			if !increasing {
+				level = 255 - level
+			}
+			img.SetGray(x, y, color.Gray{Y: level})
+		}
+	}
+	return img
+}
+
+// checkerImage crée un damier de cases de size pixels
+func checkerImage(width int, height int, size int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if (x/size+y/size)%2 == 0 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestDifferenceHash(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want uint64
+	}{
+		// Chaque pixel réduit est plus sombre que son voisin de droite : aucun bit
+		{name: "dégradé croissant", img: gradientImage(90, 80, true), want: 0},
+		{name: "dégradé décroissant", img: gradientImage(90, 80, false), want: ^uint64(0)},
+		{name: "image uniforme", img: image.NewGray(image.Rect(0, 0, 40, 40)), want: 0},
+		// Image plus petite que la réduction 9x8 : chaque case reprend au moins un pixel, les cases voisines
+		// d'un même pixel sont égales et seuls les changements de pixel donnent un bit (2 par ligne)
+		{name: "image minuscule", img: gradientImage(3, 2, false), want: 0x2424242424242424},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := differenceHash(test.img); got != test.want {
+				t.Errorf("differenceHash = %016x, attendu %016x", got, test.want)
+			}
+		})
+	}
+
+	// Une même photo redimensionnée garde une empreinte proche, une autre photo non
+	original := differenceHash(checkerImage(360, 320, 40))
+	if distance := photoHashDistance(original, differenceHash(checkerImage(180, 160, 20))); distance > photoHashMaxDistance {
+		t.Errorf("distance après redimensionnement = %d, attendu au plus %d", distance, photoHashMaxDistance)
+	}
+	if distance := photoHashDistance(original, differenceHash(gradientImage(360, 320, false))); distance <= photoHashMaxDistance {
+		t.Errorf("distance entre deux photos différentes = %d, attendu plus de %d", distance, photoHashMaxDistance)
+	}
+}
+
+func TestPhotoHashDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{a: 0, b: 0, want: 0},
+		{a: 0, b: ^uint64(0), want: 64},
+		{a: 0b1011, b: 0b0001, want: 2},
+		{a: 0xff00ff00ff00ff00, b: 0xff00ff00ff00ff03, want: 2},
+	}
+	for _, test := range tests {
+		if got := photoHashDistance(test.a, test.b); got != test.want {
+			t.Errorf("photoHashDistance(%016x, %016x) = %d, attendu %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestFetchPhotoHash(t *testing.T) {
+	var photo bytes.Buffer
+	if err := png.Encode(&photo, gradientImage(90, 80, false)); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/photo.png" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(photo.Bytes())
+	}))
+	t.Cleanup(server.Close)
+
+	hash, err := fetchPhotoHash(context.Background(), server.Client(), server.URL+"/photo.png")
+	if err != nil || hash != ^uint64(0) {
+		t.Errorf("fetchPhotoHash = %016x (%v), attendu %016x", hash, err, ^uint64(0))
+	}
+	if _, err := fetchPhotoHash(context.Background(), server.Client(), server.URL+"/absente.png"); err == nil {
+		t.Error("une photo absente doit être une erreur")
+	}
+}
+
+func TestDeduplicatorPhotoCassette(t *testing.T) {
+	var photo bytes.Buffer
+	if err := png.Encode(&photo, gradientImage(90, 80, false)); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(photo.Bytes())
+	}))
+	announcement := Announcement{propertyReference: "A1", photoURLs: []string{server.URL + "/photo.png"}}
+
+	add := func(cassette *Cassette) string {
+		t.Helper()
+		dedup := NewDeduplicator(&PropertyIndex{}, true)
+		dedup.Add(withCassette(context.Background(), cassette), SearchTarget{Agency: Afedim}, announcement, true)
+		return dedup.pending[0].photoHash
+	}
+
+	// La photo est enregistrée avec le cycle, puis rejouée sans interroger le site
+	dir := t.TempDir()
+	recorder, err := RecordCassette(dir)
+	if err != nil {
+		t.Fatalf("RecordCassette : %v", err)
+	}
+	recorded := add(recorder.ForCycle("0000cafe"))
+	server.Close()
+
+	cycles, err := os.ReadDir(dir)
+	if err != nil || len(cycles) != 1 {
+		t.Fatalf("cassettes enregistrées = %v (%v), attendu une cassette", cycles, err)
+	}
+	player, err := ReplayCassette(filepath.Join(dir, cycles[0].Name()))
+	if err != nil {
+		t.Fatalf("ReplayCassette : %v", err)
+	}
+	if replayed := add(player); recorded == "" || replayed != recorded {
+		t.Errorf("empreinte rejouée = %q, enregistrée %q", replayed, recorded)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	probePingTimeout = 5 * time.Second // Délai maximal de la vérification des services de notification
+	probePingCache   = time.Minute     // Durée de validité de la dernière vérification des services de notification
+)
+
+/**
+ * Probes suit l'état du scraper pour les sondes Kubernetes : vivacité (/healthz) et disponibilité (/readyz).
+ * @property {sync.Mutex} mutex - Verrou protégeant l'état (boucle de planification et requêtes HTTP concurrentes).
+ * @property {sync.Mutex} pingMutex - Verrou limitant la vérification des services de notification à une requête à la fois.
+ * @property {time.Duration} maxDelay - Délai maximal sans tour de la boucle de planification avant d'échouer la vivacité.
+ * @property {time.Time} lastBeat - Date du dernier tour de la boucle de planification (démarrage à défaut).
+ * @property {SeenStore} store - Stockage des références traitées.
+ * @property {error} storeErr - Erreur du dernier enregistrement des références traitées.
+ * @property {Notifier} notifier - Services de notification des nouvelles annonces.
+ * @property {time.Time} pingedAt - Date de la dernière vérification des services de notification.
+ * @property {error} pingErr - Résultat de la dernière vérification des services de notification.
+ */
+type Probes struct {
+	mutex     sync.Mutex
+	pingMutex sync.Mutex
+	maxDelay  time.Duration
+	lastBeat  time.Time
+	store     *SeenStore
+	storeErr  error
+	notifier  Notifier
+	pingedAt  time.Time
+	pingErr   error
+}
+
+/**
+ * NewProbes crée le suivi d'état des sondes, une fois la configuration chargée.
+ * @param {Settings} settings - Paramètres globaux (intervalle et multiple de l'intervalle toléré sans cycle).
+ * @param {SeenStore} store - Stockage des références traitées.
+ * @param {Notifier} notifier - Services de notification des nouvelles annonces.
+ * @return {Probes} - Le suivi d'état des sondes.
+ */
+func NewProbes(settings Settings, store *SeenStore, notifier Notifier) *Probes {
+	return &Probes{
+		maxDelay: time.Duration(float64(settings.Interval) * settings.LivenessFactor),
+		lastBeat: time.Now(),
+		store:    store,
+		notifier: notifier,
+	}
+}
+
+/**
+ * Beat enregistre la fin d'un tour de la boucle de planification et le résultat de l'enregistrement des références.
+ * @param {error} storeErr - Erreur du dernier enregistrement des références traitées, nil s'il a réussi.
+ * @return {void}
+ */
+func (probes *Probes) Beat(storeErr error) {
+	probes.mutex.Lock()
+	defer probes.mutex.Unlock()
+	probes.lastBeat = time.Now()
+	probes.storeErr = storeErr
+}
+
+/**
+ * Live vérifie que la boucle de planification n'est pas bloquée.
+ * @param {time.Time} now - Date de la vérification.
+ * @return {error} - Erreur si aucun cycle ne s'est terminé depuis plus du délai maximal.
+ */
+func (probes *Probes) Live(now time.Time) error {
+	probes.mutex.Lock()
+	defer probes.mutex.Unlock()
+	if delay := now.Sub(probes.lastBeat); delay > probes.maxDelay {
+		return fmt.Errorf("aucun cycle terminé depuis %s (maximum %s)", delay.Round(time.Second), probes.maxDelay)
+	}
+	return nil
+}
+
+/**
+ * Ready vérifie que le scraper peut traiter les annonces : stockage des références ouvert et enregistrable,
+ * services de notification joignables (vérification mise en cache une minute pour ne pas solliciter les API).
+ * @param {context.Context} ctx - Contexte d'annulation de la vérification.
+ * @param {time.Time} now - Date de la vérification.
+ * @return {error} - Erreur décrivant chaque vérification en échec.
+ */
+func (probes *Probes) Ready(ctx context.Context, now time.Time) error {
+	var errs []error
+	if err := probes.storeStatus(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := probes.ping(ctx, now); err != nil {
+		errs = append(errs, fmt.Errorf("notifications : %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+/**
+ * storeStatus vérifie que le stockage des références est ouvert et que son dernier enregistrement a réussi.
+ * @return {error} - Erreur décrivant l'état du stockage.
+ */
+func (probes *Probes) storeStatus() error {
+	probes.mutex.Lock()
+	defer probes.mutex.Unlock()
+	if probes.store == nil {
+		return errors.New("stockage des références non ouvert")
+	}
+	if probes.storeErr != nil {
+		return fmt.Errorf("stockage des références : %w", probes.storeErr)
+	}
+	return nil
+}
+
+/**
+ * ping vérifie les services de notification, en dehors du verrou de l'état pour ne pas bloquer la boucle de planification.
+ * Le résultat, succès comme échec, est réutilisé pendant probePingCache.
+ * @param {context.Context} ctx - Contexte d'annulation de la vérification.
+ * @param {time.Time} now - Date de la vérification.
+ * @return {error} - Résultat de la dernière vérification.
+ */
+func (probes *Probes) ping(ctx context.Context, now time.Time) error {
+	// Les requêtes concurrentes attendent la vérification en cours puis réutilisent son résultat
+	probes.pingMutex.Lock()
+	defer probes.pingMutex.Unlock()
+
+	probes.mutex.Lock()
+	pingedAt, pingErr := probes.pingedAt, probes.pingErr
+	probes.mutex.Unlock()
+	if !pingedAt.IsZero() && now.Sub(pingedAt) <= probePingCache {
+		return pingErr
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, probePingTimeout)
+	defer cancel()
+	pingErr = pingNotifiers(pingCtx, probes.notifier)
+
+	probes.mutex.Lock()
+	probes.pingedAt, probes.pingErr = now, pingErr
+	probes.mutex.Unlock()
+	return pingErr
+}
+
+/**
+ * probeHandler convertit une vérification en handler HTTP : 200 "ok", ou 503 avec la cause de l'échec.
+ * @param {func(*http.Request) error} check - La vérification.
+ * @return {http.Handler} - Le handler de la sonde.
+ */
+func probeHandler(check func(r *http.Request) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := check(r); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// stubPinger compte les vérifications et attend release avant de répondre err
+type stubPinger struct {
+	stubNotifier
+	pings   int
+	started chan struct{}
+	release chan struct{}
+}
+
+func (stub *stubPinger) Ping(ctx context.Context) error {
+	stub.pings++
+	if stub.started != nil {
+		stub.started <- struct{}{}
+		<-stub.release
+	}
+	return stub.err
+}
+
+func TestProbesReady(t *testing.T) {
+	store, err := OpenSeenStore(filepath.Join(t.TempDir(), "seen.json"))
+	if err != nil {
+		t.Fatalf("OpenSeenStore : %v", err)
+	}
+	config := &Config{}
+	config.applyDefaults()
+	now := time.Now()
+
+	t.Run("échec mis en cache", func(t *testing.T) {
+		pinger := &stubPinger{stubNotifier: stubNotifier{err: errors.New("getMe refusé")}}
+		probes := NewProbes(config.Settings, store, pinger)
+
+		for _, at := range []time.Time{now, now.Add(probePingCache / 2)} {
+			if err := probes.Ready(context.Background(), at); err == nil {
+				t.Error("un service de notification injoignable doit échouer la disponibilité")
+			}
+		}
+		if pinger.pings != 1 {
+			t.Errorf("vérifications = %d, attendu 1 pendant la durée du cache", pinger.pings)
+		}
+
+		// Le service est de nouveau joignable une fois le cache expiré
+		pinger.err = nil
+		if err := probes.Ready(context.Background(), now.Add(probePingCache+time.Second)); err != nil || pinger.pings != 2 {
+			t.Errorf("Ready = %v après %d vérifications, attendu nil après 2", err, pinger.pings)
+		}
+	})
+
+	t.Run("vérification hors du verrou", func(t *testing.T) {
+		// La boucle de planification n'attend pas la fin de la vérification des services de notification
+		pinger := &stubPinger{started: make(chan struct{}), release: make(chan struct{})}
+		probes := NewProbes(config.Settings, store, pinger)
+		ready := make(chan error)
+		go func() { ready <- probes.Ready(context.Background(), now) }()
+
+		<-pinger.started
+		probes.Beat(nil)
+		if err := probes.Live(time.Now()); err != nil {
+			t.Errorf("Live = %v pendant la vérification", err)
+		}
+		close(pinger.release)
+		if err := <-ready; err != nil {
+			t.Errorf("Ready = %v, attendu nil", err)
+		}
+	})
+}
@@ -1,110 +1,405 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 /**
- * RunScraper lance le scraping des annonces immobilières à intervalles réguliers.
+ * RunScraper lance le scraping des annonces immobilières selon le calendrier de chaque recherche, jusqu'à l'annulation du contexte.
+ * À l'annulation, aucun nouveau scraping n'est lancé : les scrapings en cours disposent de settings.shutdown_timeout
+ * pour se terminer avant d'être interrompus, puis les références traitées sont enregistrées.
  * Cette fonction est appelée depuis le point d'entrée de l'application.
- * @param {int} intervalMinutes - Intervalle de temps en minutes entre chaque cycle de scraping
- * return {void}
+ * @param {context.Context} ctx - Contexte racine, annulé à la réception de SIGINT ou SIGTERM
+ * @param {Config} config - Configuration des recherches à scraper
+ * @param {SeenStore} store - Stockage des références déjà traitées par les différentes agences
+ * @param {Notifier} notifier - Services de notification des nouvelles annonces
+ * @param {PropertyIndex} properties - Biens regroupés par annonces de plusieurs agences, nil si le regroupement est désactivé
+ * @param {HealthMonitor} health - Suivi de santé des recherches, alertant les administrateurs
+ * @param {Probes} probes - État du scraper pour les sondes Kubernetes, mis à jour à chaque tour de la boucle
+ * @return {error} - Erreur si le calendrier d'une recherche est invalide ou si l'enregistrement final échoue
  */
-func RunScraper(intervalMinutes int) {
-	// Map globale pour suivre les références des biens déjà traités par les différentes agences
-	processedReferencesAfedim := make(map[string]bool)
-	processedReferencesGiboire := make(map[string]bool)
-	processedReferencesFoncia := make(map[string]bool)
-	processedReferencesAgenceDuColombier := make(map[string]bool)
-	processedReferencesLaFrancaiseImmobiliere := make(map[string]bool)
-	processedReferencesGuenno := make(map[string]bool)
-	processedReferencesLaMotte := make(map[string]bool)
-	processedReferencesKermarrec := make(map[string]bool)
-	processedReferencesNestenn := make(map[string]bool)
-	processedReferencesSquareHabitat := make(map[string]bool)
-	processedReferencesCAImmobilier := make(map[string]bool)
-	processedReferencesPigeaultImmobilier := make(map[string]bool)
-	processedReferencesLaForetImmobilier := make(map[string]bool)
-	processedReferencesCogir := make(map[string]bool)
+func RunScraper(ctx context.Context, config *Config, store *SeenStore, notifier Notifier, properties *PropertyIndex, health *HealthMonitor, probes *Probes) error {
+	scheduler, err := NewScheduler(config.Targets, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, run := range scheduler.NextRuns() {
+		processorLog.Info("Premier scraping de la recherche planifié", "title", run.Target.Title, "agency", run.Target.Agency, "url", run.Target.URL, "next", run.Next.Format(time.DateTime))
+	}
 
-	for {
-		// Lancer le scraping pour l'agence Afedim
-		processAgencyScraping(processedReferencesAfedim, "https://www.afedim.fr/fr/location/annonces/Appartement-Maison-Parking-Garage/Rennes-France/1-5-pieces/surface-0-100-m2/budget-0-90000-euros/rayon-10-km/disponible-/options-/exclusPlafondRess-/Resultats", "AFEDIM", "Afedim")
+	workCtx, cancelWork := newWorkContext(ctx, time.Duration(config.Settings.ShutdownTimeout))
+	defer cancelWork()
+	cycle := newScrapingCycle(config, store, notifier, properties, health)
 
-		// Lancer le scraping pour l'agence Giboire
-		processAgencyScraping(processedReferencesGiboire, "https://www.giboire.com/recherche-location/appartement/?searchBy=default&address%5B%5D=RENNES&address%5B%5D=CHANTEPIE&address%5B%5D=CESSON+SEVIGNE&priceMax=700&nbBedrooms%5B%5D=1&transactionType%5B%5D=Location&searchBy=default", "GIBOIRE", "Giboire")
+	var saveErr error
+	for ctx.Err() == nil {
+		// Sélectionner les recherches dont la date de scraping est atteinte
+		if due := scheduler.Due(time.Now()); len(due) > 0 {
+			saveErr = cycle.run(ctx, workCtx, due)
+		}
+		probes.Beat(saveErr)
 
-		// Lancer le scraping pour l'agence Foncia
-		processAgencyScraping(processedReferencesFoncia, "https://fr.foncia.com/location/rennes-35--chantepie-35135--cesson-sevigne-35510/appartement?nbPiece=2--&prix=--700&advanced=", "FONCIA", "Foncia")
+		// Attendre le prochain scraping prévu, ou l'arrêt ; la boucle se réveille au moins une fois par intervalle
+		// (hors plage horaire active comprise) pour signaler qu'elle n'est pas bloquée
+		timer := time.NewTimer(min(time.Until(scheduler.NextWakeup()), time.Duration(config.Settings.Interval)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
 
-		// Lancer le scraping pour l'agence Agence du Colombier
-		processAgencyScraping(processedReferencesAgenceDuColombier, "https://agenceducolombier.com/annonces/?filter_search_action%5B%5D=louer&filter_search_type%5B%5D=&nb-pieces=&min-chambres=&min-surface=&max-surface=&price_low=0&price_max=6000000&submit=LANCER+MA+RECHERCHE", "AGENCE DU COLOMBIER", "Agence du Colombier")
+	// Enregistrer les références traitées avant de quitter
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("enregistrement des références traitées : %w", err)
+	}
+	processorLog.Info("Scraper arrêté, références traitées enregistrées")
+	return nil
+}
 
-		// Lancer le scraping pour l'agence La Française Immobilière
-		processAgencyScraping(processedReferencesLaFrancaiseImmobiliere, "https://www.la-francaise-immobiliere.fr/location/?post_types=location&categorie%5B%5D=27&zone%5B%5D=6212&zone%5B%5D=6204&zone%5B%5D=6214&nb_chambres_min=0&nb_chambres_max=&prix_min=0&prix_max=700&submitted=1&o=date-desc&action=load_search_results&wia_6_type=&searchOnMap=0&wia_1_reference=", "LA FRANCAISE IMMOBILIERE", "La Française Immobilière")
+/**
+ * RunCycle scrape une seule fois toutes les recherches actives, sans tenir compte de leur calendrier ni de leur
+ * plage horaire, puis enregistre les références traitées (relecture d'une cassette, tâches cron).
+ * @param {context.Context} ctx - Contexte racine, annulé à la réception de SIGINT ou SIGTERM
+ * @param {Config} config - Configuration des recherches à scraper
+ * @param {SeenStore} store - Stockage des références déjà traitées par les différentes agences
+ * @param {Notifier} notifier - Services de notification des nouvelles annonces
+ * @param {PropertyIndex} properties - Biens regroupés par annonces de plusieurs agences, nil si le regroupement est désactivé
+ * @param {HealthMonitor} health - Suivi de santé des recherches, alertant les administrateurs
+ * @return {error} - Erreur si l'enregistrement des références échoue, ou si le cycle a été interrompu
+ */
+func RunCycle(ctx context.Context, config *Config, store *SeenStore, notifier Notifier, properties *PropertyIndex, health *HealthMonitor) error {
+	var runs []ScheduledRun
+	for _, target := range config.Targets {
+		if target.IsEnabled() {
+			runs = append(runs, ScheduledRun{Target: target})
+		}
+	}
 
-		// Lancez le scraping pour l'agence Guenno
-		processAgencyScraping(processedReferencesGuenno, "https://www.guenno.com/biens/recherche?mandate_type=2&realty_type%5B%5D=1&number_room%5B%5D=2&min_surface=&town=RENNES+35000&price_max=700", "GUENNO", "Guenno")
+	workCtx, cancelWork := newWorkContext(ctx, time.Duration(config.Settings.ShutdownTimeout))
+	defer cancelWork()
 
-		// Lancer le scraping pour l'agence La Motte
-		processAgencyScraping(processedReferencesLaMotte, "https://www.lamotte.fr/location-appartement/ille-et-vilaine/rennes/", "LA MOTTE", "La Motte")
+	if err := newScrapingCycle(config, store, notifier, properties, health).run(ctx, workCtx, runs); err != nil {
+		return fmt.Errorf("enregistrement des références traitées : %w", err)
+	}
+	return ctx.Err()
+}
 
-		// Lancer le scraping pour l'agence Kermarrec
-		processAgencyScraping(processedReferencesKermarrec, "https://www.kermarrec-habitation.fr/location/?post_type=location&false-select=on&99795fbc=&ville%5B%5D=cesson-sevigne-35510&ville%5B%5D=chantepie-35135&ville%5B%5D=rennes-35000&typebien%5B%5D=appartement&budget_max=700&reference=&rayon=0&avec_carte=false&tri=pertinence", "KERMARREC", "Kermarrec")
+/**
+ * newWorkContext crée le contexte des scrapings en cours : il survit à l'arrêt demandé pendant le délai d'arrêt,
+ * puis est annulé.
+ * @param {context.Context} ctx - Contexte racine, annulé à l'arrêt demandé.
+ * @param {time.Duration} shutdownTimeout - Délai laissé aux scrapings en cours pour se terminer.
+ * @return {context.Context} - Le contexte des scrapings.
+ * @return {context.CancelFunc} - Fonction libérant le contexte.
+ */
+func newWorkContext(ctx context.Context, shutdownTimeout time.Duration) (context.Context, context.CancelFunc) {
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	stopShutdownTimer := context.AfterFunc(ctx, func() {
+		processorLog.Info("Arrêt demandé : fin des scrapings en cours", "timeout", shutdownTimeout)
+		time.AfterFunc(shutdownTimeout, cancelWork)
+	})
 
-		// Lancer le scraping pour l'agence Nestenn
-		processAgencyScraping(processedReferencesNestenn, "https://immobilier-rennes-centre.nestenn.com/?action=listing&prestige=0&meuble=0&transaction=louer&list_ville=35+Rennes%2C35135+Chantepie%2C35510+Cesson-S%C3%A9vign%C3%A9&list_type=Appartement&type=Appartement&prix_max=700&pieces=2", "NESTENN", "Nestenn")
+	return workCtx, func() {
+		stopShutdownTimer()
+		cancelWork()
+	}
+}
 
-		// Lancer le scraping pour l'agence Square Habitat
-		processAgencyScraping(processedReferencesSquareHabitat, "https://www.squarehabitat.fr/annonces/location/bien/appartement/immobilier/bretagne/ille-et-vilaine/rennes-35000", "SQUARE HABITAT", "Square Habitat")
+/**
+ * scrapingCycle regroupe ce qui est partagé par les cycles de scraping successifs.
+ * @property {Config} config - Configuration des recherches.
+ * @property {SeenStore} store - Stockage des références déjà traitées.
+ * @property {Notifier} notifier - Services de notification des nouvelles annonces.
+ * @property {PropertyIndex} properties - Biens regroupés par annonces de plusieurs agences, nil si le regroupement est désactivé.
+ * @property {Deduplicator} dedup - Regroupement des annonces d'un même bien, nil si désactivé.
+ * @property {HealthMonitor} health - Suivi de santé des recherches.
+ * @property {domainLocks} domains - Verrous par domaine : deux recherches d'un même site ne sont jamais scrapées en même temps.
+ */
+type scrapingCycle struct {
+	config     *Config
+	store      *SeenStore
+	notifier   Notifier
+	properties *PropertyIndex
+	dedup      *Deduplicator
+	health     *HealthMonitor
+	domains    *domainLocks
+}
 
-		// Lancez le scraping pour l'agence CA Immobilier
-		processAgencyScraping(processedReferencesCAImmobilier, "https://www.ca-immobilier.fr/louer/location/appartement/35/Ille-et-vilaine", "CA IMMOBILIER", "CA Immobilier")
+/**
+ * newScrapingCycle prépare les cycles de scraping.
+ * @param {Config} config - Configuration des recherches.
+ * @param {SeenStore} store - Stockage des références déjà traitées.
+ * @param {Notifier} notifier - Services de notification des nouvelles annonces.
+ * @param {PropertyIndex} properties - Biens regroupés par annonces de plusieurs agences, nil si le regroupement est désactivé.
+ * @param {HealthMonitor} health - Suivi de santé des recherches.
+ * @return {scrapingCycle} - Les cycles de scraping.
+ */
+func newScrapingCycle(config *Config, store *SeenStore, notifier Notifier, properties *PropertyIndex, health *HealthMonitor) *scrapingCycle {
+	cycle := &scrapingCycle{
+		config:     config,
+		store:      store,
+		notifier:   notifier,
+		properties: properties,
+		health:     health,
+		domains:    newDomainLocks(),
+	}
 
-		// Lancer le scraping pour l'agence Pigeault Immobilier
-		processAgencyScraping(processedReferencesPigeaultImmobilier, "https://www.pigeaultimmobilier.com/location/?sous-categorie%5B%5D=1455&agences%5B%5D=26548&prix_min=0&prix_max=700&submitted=1&o=date-desc&action=load_search_results&wia_6_type=location&searchOnMap=0&wia_1_reference=", "PIGEAULT IMMOBILIER", "Pigeault Immobilier")
+	// Regroupement des annonces d'un même bien : les notifications partent en fin de cycle
+	if properties != nil {
+		cycle.dedup = NewDeduplicator(properties, *config.Settings.Dedup.PhotoHash)
+	}
+	return cycle
+}
 
-		// Lancer le scraping pour l'agence La Foret Immobilier
-		processAgencyScraping(processedReferencesLaForetImmobilier, "https://www.laforet.com/louer/location-appartement?filter%5Btypes%5D=apartment&filter%5Bmax%5D=700&filter%5Bcities%5D=35238%2C35051%2C35055", "LA FORET IMMOBILIER", "La Foret Immobilier")
+/**
+ * run scrape un lot de recherches en parallèle, notifie les biens regroupés puis enregistre les références traitées.
+ * @param {context.Context} ctx - Contexte racine : aucun scraping n'est lancé après son annulation.
+ * @param {context.Context} workCtx - Contexte des scrapings en cours.
+ * @param {[]ScheduledRun} runs - Les recherches à scraper.
+ * @return {error} - Erreur lors de l'enregistrement des références traitées.
+ */
+func (cycle *scrapingCycle) run(ctx context.Context, workCtx context.Context, runs []ScheduledRun) error {
+	// Identifiant du cycle, ajouté aux journaux de tous ses scrapings et notifications
+	cycleID := newCycleID()
+	cycleCtx := withLogAttrs(workCtx, slog.String("cycle", cycleID))
 
-		// Lancer le scraping pour l'agence Cogir
-		processAgencyScraping(processedReferencesCogir, "https://www.cogir.fr/fr/listing-location.html?loc=location&type%5B%5D=appartement&insee%5B%5D=35051&insee%5B%5D=35055&insee%5B%5D=35238&surfacemin=&prixmax=700&numero=&coordonnees=&archivage_statut=&tri=prix-desc&page=1", "COGIR", "Cogir")
+	// Requêtes enregistrées dans la cassette du cycle
+	if cassette := cassetteFromContext(cycleCtx); cassette != nil {
+		cycleCtx = withCassette(cycleCtx, cassette.ForCycle(cycleID))
+	}
 
-		// Attendre avant le prochain cycle
-		time.Sleep(time.Duration(intervalMinutes) * time.Minute)
+	// Lancer le scraping des recherches en parallèle, dans la limite du nombre de workers
+	var succeeded atomic.Int32
+	runWorkers(runs, cycle.config.Settings.Workers, func(run ScheduledRun) {
+		unlock := cycle.domains.Lock(targetDomain(run.Target.URL))
+		defer unlock()
+
+		// Ne plus démarrer de scraping une fois l'arrêt demandé
+		if ctx.Err() != nil {
+			return
+		}
+
+		targetCtx := withLogAttrs(cycleCtx, slog.String("agency", string(run.Target.Agency)), slog.String("url", run.Target.URL))
+		stats, err := processAgencyScraping(targetCtx, cycle.store, &cycle.config.Settings, cycle.notifier, cycle.dedup, run.Target)
+		if !errors.Is(err, context.Canceled) {
+			cycle.health.Record(targetCtx, run.Target, stats, err)
+		}
+		if err == nil && stats.Errors == 0 {
+			succeeded.Add(1)
+		}
+		if !run.Next.IsZero() {
+			processorLog.InfoContext(targetCtx, "Prochain scraping de la recherche planifié", "title", run.Target.Title, "next", run.Next.Format(time.DateTime))
+		}
+	})
+
+	// Notifier une fois chaque bien trouvé pendant le cycle, toutes agences confondues
+	if cycle.dedup != nil {
+		cycle.dedup.Flush(cycleCtx, cycle.store, cycle.notifier)
+		if err := cycle.properties.Save(time.Duration(cycle.config.Settings.Dedup.Retention)); err != nil {
+			processorLog.ErrorContext(cycleCtx, "Erreur lors de l'enregistrement des biens", "error", err)
+		}
+	}
+
+	// Écrire les références traitées sur disque à la fin du cycle
+	saveErr := cycle.store.Save()
+	if saveErr != nil {
+		processorLog.ErrorContext(cycleCtx, "Erreur lors de l'enregistrement des références traitées", "error", saveErr)
 	}
+	// Un cycle réussi a scrapé au moins une recherche sans erreur et enregistré ses références
+	if ctx.Err() == nil && saveErr == nil && succeeded.Load() > 0 {
+		lastCycle.SetToCurrentTime()
+	}
+	return saveErr
 }
 
 /**
  * processAgencyScraping lance le scraping pour une agence immobilière spécifique.
- * @param {map[string]bool} processedReferences - Map contenant les références des biens déjà traités.
- * @param {string} url - L'URL de la page de l'agence à scraper.
- * @param {string} titleMessageTelegram - Le titre du message Telegram.
- * @param {Agency} nameAgency - Le nom de l'agence.
- * @return {void}
+ * @param {context.Context} ctx - Contexte d'annulation du scraping et des notifications.
+ * @param {SeenStore} store - Stockage des références des biens déjà traités.
+ * @param {Settings} settings - Paramètres globaux (premier scraping sans notification, suivi des changements).
+ * @param {Notifier} notifier - Services de notification des nouvelles annonces.
+ * @param {Deduplicator} dedup - Regroupement des annonces par bien ; nil pour notifier chaque annonce immédiatement.
+ * @param {SearchTarget} target - La recherche à scraper (agence, URL, titre et critères).
+ * @return {ScrapeStats} - Les statistiques du scraping, pour le suivi de santé.
+ * @return {error} - Erreur du scraping (context.Canceled si le scraping a été interrompu).
  */
-func processAgencyScraping(processedReferences map[string]bool, url string, titleMessageTelegram string, nameAgency Agency) {
+func processAgencyScraping(ctx context.Context, store *SeenStore, settings *Settings, notifier Notifier, dedup *Deduplicator, target SearchTarget) (ScrapeStats, error) {
 	// Créer une nouvelle instance de CollyService
 	collyService := NewCollyService()
 
 	// Récupérer les annonces complètes depuis l'agence
-	newAnnouncements := collyService.ScrapeAnnouncement(nameAgency, url)
+	start := time.Now()
+	newAnnouncements, err := collyService.ScrapeAnnouncement(ctx, target.Agency, target.URL, target.MaxPages)
+	if errors.Is(err, context.Canceled) {
+		processorLog.WarnContext(ctx, "Scraping interrompu par l'arrêt du scraper")
+		return collyService.Stats(), err
+	}
+
+	// Métriques du scraping
+	stats := collyService.Stats()
+	scrapeDuration.WithLabelValues(string(target.Agency)).Observe(time.Since(start).Seconds())
+	listingsFound.WithLabelValues(string(target.Agency)).Add(float64(stats.Announcements))
+	if failures := stats.Items - stats.Announcements; failures > 0 {
+		extractionFailures.WithLabelValues(string(target.Agency)).Add(float64(failures))
+	}
+	if err != nil {
+		processorLog.ErrorContext(ctx, "Erreur lors du scraping de l'agence", "error", err)
+		return collyService.Stats(), err
+	}
+
+	// Premier scraping de l'agence : les annonces sont marquées comme vues sans notification
+	silent := *settings.Warmup && !store.IsWarmedUp(target.Agency)
+	if silent && len(newAnnouncements) > 0 {
+		processorLog.InfoContext(ctx, "Premier scraping de l'agence : annonces marquées comme vues sans notification", "count", len(newAnnouncements))
+	}
 
 	// Comparer les références des biens pour détecter les nouvelles annonces
+	now := time.Now()
+	found := make(map[string]bool, len(newAnnouncements))
 	for _, announcement := range newAnnouncements {
-		if _, exists := processedReferences[announcement.propertyReference]; !exists {
-			// Nouvelle annonce détectée
-			fmt.Println("Nouvelle annonce détectée référence :", announcement.propertyReference)
-			processedReferences[announcement.propertyReference] = true
-
-			// Envoie un message sur le canal Telegram
-			sendTelegramMessageToPublicChannel(fmt.Sprintf(
-				"%s\nNouvelle annonce immobilière !\nRéférence : %s\nURL : %s",
-				titleMessageTelegram,
-				announcement.propertyReference,
-				announcement.url,
-			))
+		// Après interruption, les annonces restantes ne sont pas marquées vues : elles seront notifiées au prochain démarrage
+		if ctx.Err() != nil {
+			processorLog.WarnContext(ctx, "Traitement des annonces interrompu par l'arrêt du scraper")
+			return collyService.Stats(), ctx.Err()
+		}
+
+		found[announcement.propertyReference] = true
+		update := store.Touch(target.Agency, target.URL, announcement, now)
+		if !update.New {
+			if update.WasGone {
+				processorLog.InfoContext(ctx, "Annonce retirée de nouveau en ligne", "reference", announcement.propertyReference, "absence", now.Sub(update.PreviousSeen).Round(time.Minute).String())
+			}
+
+			// Notification non reçue par certains services : nouvel essai sur ces seuls services
+			if len(update.Pending) > 0 {
+				retryNotification(ctx, store, notifier, target, announcement, update.Pending)
+			}
+
+			// Annonce déjà notifiée : notifier une variation du loyer ou une republication
+			if changes := listingChanges(update, announcement, now, settings.Changes); len(changes) > 0 {
+				processorLog.InfoContext(ctx, "Annonce modifiée", "reference", announcement.propertyReference, "changes", strings.Join(changes, ", "))
+				if err := notifier.Notify(ctx, newListingMessage(target, announcement, strings.Join(changes, "\n"))); err != nil {
+					processorLog.ErrorContext(ctx, "Erreur lors de l'envoi de la notification de l'annonce", "reference", announcement.propertyReference, "error", err)
+				}
+			}
+			continue
+		}
+
+		// Les annonces du premier scraping sont tout de même regroupées, pour reconnaître leur bien chez une autre agence
+		if silent {
+			if dedup != nil {
+				dedup.Add(ctx, target, announcement, false)
+			}
+			continue
+		}
+
+		// Nouvelle annonce détectée
+		processorLog.InfoContext(ctx, "Nouvelle annonce détectée", "reference", announcement.propertyReference)
+		newListings.WithLabelValues(string(target.Agency)).Inc()
+
+		// Appliquer les critères avant notification
+		if ok, reason := target.Filters.Evaluate(announcement); !ok {
+			processorLog.InfoContext(ctx, "Annonce ignorée par les critères", "reference", announcement.propertyReference, "reason", reason)
+			continue
+		}
+
+		// Notification en fin de cycle, une fois les annonces des autres agences regroupées
+		if dedup != nil {
+			dedup.Add(ctx, target, announcement, true)
+			continue
+		}
+
+		// Envoie la notification sur chaque service configuré
+		receipts, err := notifyWithReceipts(ctx, notifier, newAnnouncementMessage(target, announcement))
+		failed, delivered := deliveryFailures(notifier, err)
+		if !delivered {
+			// Aucun service n'a reçu la notification : oublier la référence pour la retenter au prochain cycle
+			processorLog.ErrorContext(ctx, "Erreur lors de l'envoi de la notification de l'annonce", "reference", announcement.propertyReference, "error", err)
+			store.Forget(target.Agency, announcement.propertyReference)
+			continue
+		}
+		if err != nil {
+			processorLog.ErrorContext(ctx, "Notification de l'annonce non reçue par certains services : nouvel essai au prochain scraping", "reference", announcement.propertyReference, "services", failed, "error", err)
 		}
+		store.MarkNotified(target.Agency, announcement.propertyReference, receipts, failed)
+	}
+
+	// Scraping complet : les annonces absentes de la recherche depuis plusieurs scrapings sont considérées retirées
+	if collyService.Stats().Complete() {
+		processGoneListings(ctx, store, settings, notifier, target, found, now)
+	}
+
+	// Le premier scraping est considéré effectué dès qu'une annonce a été trouvée
+	if len(newAnnouncements) > 0 {
+		store.MarkWarmedUp(target.Agency)
+	}
+
+	return collyService.Stats(), nil
+}
+
+/**
+ * retryNotification renvoie la notification d'une nouvelle annonce aux seuls services qui ne l'ont pas reçue.
+ * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+ * @param {SeenStore} store - Stockage des références des biens déjà traités.
+ * @param {Notifier} notifier - Services de notification configurés.
+ * @param {SearchTarget} target - La recherche ayant trouvé l'annonce.
+ * @param {Announcement} announcement - L'annonce.
+ * @param {[]string} pending - Nom des services n'ayant pas reçu la notification.
+ * @return {void}
+ */
+func retryNotification(ctx context.Context, store *SeenStore, notifier Notifier, target SearchTarget, announcement Announcement, pending []string) {
+	services := notifierFor(notifier, pending)
+	if services == nil {
+		// Services retirés de la configuration : plus rien à retenter
+		store.MarkNotified(target.Agency, announcement.propertyReference, nil, nil)
+		return
+	}
+
+	receipts, err := notifyWithReceipts(ctx, services, newAnnouncementMessage(target, announcement))
+	failed, _ := deliveryFailures(services, err)
+	if err != nil {
+		processorLog.ErrorContext(ctx, "Erreur lors du nouvel envoi de la notification de l'annonce", "reference", announcement.propertyReference, "services", failed, "error", err)
+	}
+	store.MarkNotified(target.Agency, announcement.propertyReference, receipts, failed)
+}
+
+/**
+ * processGoneListings enregistre le retrait des annonces absentes d'une recherche et le signale pour les annonces notifiées.
+ * @param {context.Context} ctx - Contexte d'annulation des notifications.
+ * @param {SeenStore} store - Stockage des références des biens déjà traités.
+ * @param {Settings} settings - Paramètres globaux (détection des retraits).
+ * @param {Notifier} notifier - Services de notification.
+ * @param {SearchTarget} target - La recherche scrapée.
+ * @param {map[string]bool} found - Les références trouvées par le scraping.
+ * @param {time.Time} now - Date du scraping.
+ * @return {void}
+ */
+func processGoneListings(ctx context.Context, store *SeenStore, settings *Settings, notifier Notifier, target SearchTarget, found map[string]bool, now time.Time) {
+	gone := store.Sweep(target, found, settings.Gone.MissedCycles, now)
+	if len(gone) == 0 {
+		return
+	}
+
+	for _, entry := range gone {
+		processorLog.InfoContext(ctx, "Annonce retirée", "reference", entry.PropertyReference, "time_on_market", formatAbsence(entry.timeOnMarket()))
+
+		if !*settings.Gone.Notify || !entry.Notified {
+			continue
+		}
+		if err := notifier.Notify(ctx, newGoneMessage(target, entry)); err != nil {
+			processorLog.ErrorContext(ctx, "Erreur lors de l'envoi de la notification de retrait de l'annonce", "reference", entry.PropertyReference, "error", err)
+		}
+	}
+
+	if market, exists := store.TimeOnMarket()[target.Agency]; exists {
+		processorLog.InfoContext(ctx, "Durée de mise en ligne des annonces de l'agence",
+			"median", formatAbsence(market.Median), "average", formatAbsence(market.Average), "count", market.Count)
 	}
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newProcessorTest prépare le scraping de la réponse d'exemple de l'API Square Habitat, sans premier scraping silencieux
+func newProcessorTest(t *testing.T) (*SeenStore, *Settings, SearchTarget) {
+	t.Helper()
+
+	body := readFixture(t, "squarehabitat_search.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	store, err := OpenSeenStore(filepath.Join(t.TempDir(), "seen.json"))
+	if err != nil {
+		t.Fatalf("OpenSeenStore : %v", err)
+	}
+	config := &Config{}
+	config.applyDefaults()
+	*config.Settings.Warmup = false
+
+	target := SearchTarget{Agency: SquareHabitat, URL: server.URL + "/api/recherche?page=1", Title: "SQUARE HABITAT", MaxPages: 1}
+	return store, &config.Settings, target
+}
+
+func TestProcessAgencyScrapingNotificationFailure(t *testing.T) {
+	store, settings, target := newProcessorTest(t)
+
+	// Échec de l'envoi : les annonces ne sont pas enregistrées et seront notifiées au cycle suivant
+	if _, err := processAgencyScraping(context.Background(), store, settings, &stubNotifier{err: errors.New("Telegram indisponible")}, nil, target); err != nil {
+		t.Fatalf("processAgencyScraping : %v", err)
+	}
+	if entries := store.Entries(SquareHabitat); len(entries) != 0 {
+		t.Fatalf("références enregistrées malgré l'échec de l'envoi : %d", len(entries))
+	}
+
+	notifier := &stubNotifier{}
+	if _, err := processAgencyScraping(context.Background(), store, settings, notifier, nil, target); err != nil {
+		t.Fatalf("processAgencyScraping : %v", err)
+	}
+	entries := store.Entries(SquareHabitat)
+	if len(notifier.messages) == 0 || len(notifier.messages) != len(entries) {
+		t.Fatalf("messages = %d, références = %d", len(notifier.messages), len(entries))
+	}
+	for _, entry := range entries {
+		if !entry.Notified {
+			t.Errorf("annonce %s non marquée notifiée", entry.PropertyReference)
+		}
+	}
+}
+
+func TestProcessAgencyScrapingPartialDelivery(t *testing.T) {
+	store, settings, target := newProcessorTest(t)
+	telegram := &stubNotifier{name: "telegram"}
+	email := &stubNotifier{name: "email", err: errors.New("SMTP indisponible")}
+	notifier := &MultiNotifier{notifiers: []Notifier{telegram, email}}
+
+	// Un service a reçu la notification : l'annonce est notifiée, seul le service en échec sera retenté
+	if _, err := processAgencyScraping(context.Background(), store, settings, notifier, nil, target); err != nil {
+		t.Fatalf("processAgencyScraping : %v", err)
+	}
+	entries := store.Entries(SquareHabitat)
+	if len(entries) == 0 || len(telegram.messages) != len(entries) || len(email.messages) != 0 {
+		t.Fatalf("messages telegram = %d, e-mail = %d, références = %d", len(telegram.messages), len(email.messages), len(entries))
+	}
+	for _, entry := range entries {
+		if !entry.Notified || !slices.Equal(entry.Pending, []string{"email"}) {
+			t.Errorf("annonce %s : notifiée %v, services à retenter %q", entry.PropertyReference, entry.Notified, entry.Pending)
+		}
+	}
+
+	// Le service rétabli reçoit les annonces manquées, sans nouvel envoi aux autres services
+	email.err = nil
+	if _, err := processAgencyScraping(context.Background(), store, settings, notifier, nil, target); err != nil {
+		t.Fatalf("processAgencyScraping : %v", err)
+	}
+	if len(telegram.messages) != len(entries) || len(email.messages) != len(entries) {
+		t.Errorf("messages telegram = %d, e-mail = %d, attendu %d chacun", len(telegram.messages), len(email.messages), len(entries))
+	}
+	for _, entry := range store.Entries(SquareHabitat) {
+		if len(entry.Pending) != 0 {
+			t.Errorf("annonce %s : services à retenter %q, attendu aucun", entry.PropertyReference, entry.Pending)
+		}
+	}
+
+	// Aucun service n'a reçu la notification : les références sont oubliées
+	store, settings, target = newProcessorTest(t)
+	failing := &MultiNotifier{notifiers: []Notifier{&stubNotifier{name: "telegram", err: errors.New("Telegram indisponible")}, email}}
+	email.err = errors.New("SMTP indisponible")
+	if _, err := processAgencyScraping(context.Background(), store, settings, failing, nil, target); err != nil {
+		t.Fatalf("processAgencyScraping : %v", err)
+	}
+	if entries := store.Entries(SquareHabitat); len(entries) != 0 {
+		t.Errorf("références enregistrées alors qu'aucun service n'a reçu la notification : %d", len(entries))
+	}
+}
+
+func TestScrapeStatsComplete(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats ScrapeStats
+		want  bool
+	}{
+		{name: "complet", stats: ScrapeStats{Pages: 2, Items: 12, Announcements: 12}, want: true},
+		{name: "erreur", stats: ScrapeStats{Pages: 2, Errors: 1, Items: 12, Announcements: 12}, want: false},
+		{name: "tronqué", stats: ScrapeStats{Pages: 5, Truncated: true, Items: 12, Announcements: 12}, want: false},
+		{name: "aucune annonce", stats: ScrapeStats{Pages: 1}, want: false},
+		// Une annonce dont la référence n'a pas pu être extraite ne doit pas compter comme absente
+		{name: "extraction échouée", stats: ScrapeStats{Pages: 1, Items: 12, Announcements: 11}, want: false},
+	}
+	for _, test := range tests {
+		if got := test.stats.Complete(); got != test.want {
+			t.Errorf("%s : Complete = %v, attendu %v", test.name, got, test.want)
+		}
+	}
+}
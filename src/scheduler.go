@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+	_ "time/tzdata" // Fuseaux horaires embarqués : l'image Docker n'a pas forcément de base tzdata
+
+	"github.com/robfig/cron/v3"
+)
+
+/**
+ * ActiveHours est la plage horaire pendant laquelle une recherche peut être scrapée (ex : 07:00–23:00 Europe/Paris).
+ * Une plage dont le début est après la fin passe minuit (ex : 22:00–06:00).
+ * @property {string} Start - Heure de début (HH:MM).
+ * @property {string} End - Heure de fin (HH:MM), exclue.
+ * @property {string} Timezone - Fuseau horaire IANA (heure locale du serveur par défaut).
+ */
+type ActiveHours struct {
+	Start    string `yaml:"start"`
+	End      string `yaml:"end"`
+	Timezone string `yaml:"timezone"`
+}
+
+/**
+ * activeWindow est une plage horaire analysée.
+ * @property {time.Duration} start - Début de la plage, depuis minuit.
+ * @property {time.Duration} end - Fin de la plage, depuis minuit.
+ * @property {time.Location} location - Fuseau horaire de la plage.
+ */
+type activeWindow struct {
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+}
+
+/**
+ * parse analyse et valide la plage horaire.
+ * @return {activeWindow} - La plage analysée.
+ * @return {error} - Erreur si une heure ou le fuseau horaire est invalide.
+ */
+func (hours ActiveHours) parse() (*activeWindow, error) {
+	var errs []error
+	window := &activeWindow{location: time.Local}
+
+	parseClock := func(value string, field string) time.Duration {
+		clock, err := time.Parse("15:04", value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s invalide %q (format attendu HH:MM)", field, value))
+			return 0
+		}
+		return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute
+	}
+	window.start = parseClock(hours.Start, "start")
+	window.end = parseClock(hours.End, "end")
+
+	if hours.Timezone != "" {
+		location, err := time.LoadLocation(hours.Timezone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fuseau horaire inconnu %q", hours.Timezone))
+		} else {
+			window.location = location
+		}
+	}
+
+	if len(errs) == 0 && window.start == window.end {
+		errs = append(errs, errors.New("start et end doivent être différents"))
+	}
+
+	return window, errors.Join(errs...)
+}
+
+/**
+ * clock retourne l'heure locale d'une date dans le fuseau de la plage, depuis minuit.
+ * @param {time.Time} t - La date.
+ * @return {time.Time} - La date dans le fuseau de la plage.
+ * @return {time.Duration} - L'heure locale depuis minuit.
+ */
+func (window *activeWindow) clock(t time.Time) (time.Time, time.Duration) {
+	local := t.In(window.location)
+	return local, time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+}
+
+/**
+ * contains indique si une date est dans la plage horaire.
+ * @param {time.Time} t - La date.
+ * @return {bool} - true si la date est dans la plage.
+ */
+func (window *activeWindow) contains(t time.Time) bool {
+	_, clock := window.clock(t)
+	if window.start < window.end {
+		return clock >= window.start && clock < window.end
+	}
+	return clock >= window.start || clock < window.end
+}
+
+/**
+ * nextStart retourne le prochain début de plage après une date hors de la plage.
+ * @param {time.Time} t - La date, hors de la plage.
+ * @return {time.Time} - Le prochain début de plage.
+ */
+func (window *activeWindow) nextStart(t time.Time) time.Time {
+	local, clock := window.clock(t)
+	day := local.Day()
+	if clock >= window.start {
+		day++
+	}
+	// Heure murale du début de plage, correcte même les jours de changement d'heure
+	return time.Date(local.Year(), local.Month(), day, int(window.start/time.Hour), int(window.start%time.Hour/time.Minute), 0, 0, window.location)
+}
+
+/**
+ * targetSchedule calcule les dates de scraping d'une recherche : intervalle ou expression cron,
+ * décalage aléatoire et plage horaire active.
+ * @property {cron.Schedule} cron - Expression cron analysée, nil pour un intervalle fixe.
+ * @property {time.Duration} interval - Intervalle entre deux scrapings (sans expression cron).
+ * @property {time.Duration} jitter - Décalage aléatoire maximal ajouté à chaque date.
+ * @property {activeWindow} window - Plage horaire active, nil pour scraper à toute heure.
+ */
+type targetSchedule struct {
+	cron     cron.Schedule
+	interval time.Duration
+	jitter   time.Duration
+	window   *activeWindow
+}
+
+/**
+ * newTargetSchedule crée le calendrier d'une recherche à partir de sa configuration.
+ * @param {SearchTarget} target - La recherche.
+ * @return {targetSchedule} - Le calendrier de la recherche.
+ * @return {error} - Erreur si l'expression cron ou la plage horaire est invalide.
+ */
+func newTargetSchedule(target SearchTarget) (*targetSchedule, error) {
+	schedule := &targetSchedule{
+		interval: time.Duration(target.Interval),
+		jitter:   time.Duration(target.Jitter),
+	}
+
+	var errs []error
+	if target.Cron != "" {
+		parsed, err := cron.ParseStandard(target.Cron)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cron invalide %q : %w", target.Cron, err))
+		}
+		schedule.cron = parsed
+	} else if schedule.interval <= 0 {
+		errs = append(errs, errors.New("interval doit être positif"))
+	}
+	if schedule.jitter < 0 {
+		errs = append(errs, errors.New("jitter doit être positif"))
+	}
+	if target.ActiveHours != nil {
+		window, err := target.ActiveHours.parse()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("active_hours : %w", err))
+		}
+		schedule.window = window
+	}
+
+	return schedule, errors.Join(errs...)
+}
+
+/**
+ * first retourne la date du premier scraping : immédiatement pour un intervalle, à la prochaine échéance pour une expression cron.
+ * @param {time.Time} now - La date de démarrage.
+ * @return {time.Time} - La date du premier scraping.
+ */
+func (schedule *targetSchedule) first(now time.Time) time.Time {
+	if schedule.cron != nil {
+		return schedule.Next(now)
+	}
+	return schedule.adjust(now)
+}
+
+/**
+ * Next retourne la date du prochain scraping après une date donnée.
+ * @param {time.Time} after - La date du dernier scraping.
+ * @return {time.Time} - La date du prochain scraping.
+ */
+func (schedule *targetSchedule) Next(after time.Time) time.Time {
+	var next time.Time
+	if schedule.cron != nil {
+		next = schedule.cron.Next(after)
+	} else {
+		next = after.Add(schedule.interval)
+	}
+
+	// Décalage aléatoire pour ne pas interroger les sites à heures fixes
+	if schedule.jitter > 0 {
+		next = next.Add(rand.N(schedule.jitter))
+	}
+
+	return schedule.adjust(next)
+}
+
+/**
+ * adjust reporte une date hors de la plage horaire active au prochain début de plage (plus un décalage aléatoire).
+ * @param {time.Time} t - La date prévue.
+ * @return {time.Time} - La date dans la plage horaire active.
+ */
+func (schedule *targetSchedule) adjust(t time.Time) time.Time {
+	if schedule.window == nil || schedule.window.contains(t) {
+		return t
+	}
+
+	next := schedule.window.nextStart(t)
+	if schedule.jitter > 0 {
+		next = next.Add(rand.N(schedule.jitter))
+	}
+	return next
+}
+
+/**
+ * ScheduledRun est le prochain scraping prévu d'une recherche.
+ * @property {SearchTarget} Target - La recherche.
+ * @property {time.Time} Next - La date du prochain scraping.
+ */
+type ScheduledRun struct {
+	Target SearchTarget
+	Next   time.Time
+}
+
+/**
+ * Scheduler planifie le scraping de chaque recherche active selon son propre calendrier.
+ * @property {sync.Mutex} mutex - Verrou protégeant les dates prévues.
+ * @property {[]targetSchedule} schedules - Calendrier de chaque recherche.
+ * @property {[]ScheduledRun} runs - Prochain scraping de chaque recherche.
+ */
+type Scheduler struct {
+	mutex     sync.Mutex
+	schedules []*targetSchedule
+	runs      []ScheduledRun
+}
+
+/**
+ * NewScheduler crée le planificateur des recherches actives.
+ * @param {[]SearchTarget} targets - Les recherches configurées (les recherches désactivées sont ignorées).
+ * @param {time.Time} now - La date de démarrage.
+ * @return {Scheduler} - Le planificateur.
+ * @return {error} - Erreur si le calendrier d'une recherche est invalide.
+ */
+func NewScheduler(targets []SearchTarget, now time.Time) (*Scheduler, error) {
+	scheduler := &Scheduler{}
+	var errs []error
+
+	for _, target := range targets {
+		if !target.IsEnabled() {
+			continue
+		}
+
+		schedule, err := newTargetSchedule(target)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s) : %w", target.Title, target.Agency, err))
+			continue
+		}
+
+		scheduler.schedules = append(scheduler.schedules, schedule)
+		scheduler.runs = append(scheduler.runs, ScheduledRun{Target: target, Next: schedule.first(now)})
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return scheduler, nil
+}
+
+/**
+ * Due retourne les recherches dont la date de scraping est atteinte et planifie leur scraping suivant.
+ * @param {time.Time} now - La date courante.
+ * @return {[]ScheduledRun} - Les recherches à scraper, avec la date de leur prochain scraping.
+ */
+func (scheduler *Scheduler) Due(now time.Time) []ScheduledRun {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	var due []ScheduledRun
+	for i := range scheduler.runs {
+		if scheduler.runs[i].Next.After(now) {
+			continue
+		}
+		scheduler.runs[i].Next = scheduler.schedules[i].Next(now)
+		due = append(due, scheduler.runs[i])
+	}
+	return due
+}
+
+/**
+ * NextWakeup retourne la date du prochain scraping, toutes recherches confondues.
+ * @return {time.Time} - La date la plus proche.
+ */
+func (scheduler *Scheduler) NextWakeup() time.Time {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	var next time.Time
+	for _, run := range scheduler.runs {
+		if next.IsZero() || run.Next.Before(next) {
+			next = run.Next
+		}
+	}
+	return next
+}
+
+/**
+ * NextRuns retourne le prochain scraping de chaque recherche, du plus proche au plus lointain.
+ * @return {[]ScheduledRun} - Les prochains scrapings.
+ */
+func (scheduler *Scheduler) NextRuns() []ScheduledRun {
+	scheduler.mutex.Lock()
+	runs := append([]ScheduledRun(nil), scheduler.runs...)
+	scheduler.mutex.Unlock()
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Next.Before(runs[j].Next)
+	})
+	return runs
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// paris est le fuseau horaire des plages horaires des tests
+var paris, _ = time.LoadLocation("Europe/Paris")
+
+func TestNewTargetScheduleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target SearchTarget
+		want   string
+	}{
+		{name: "cron invalide", target: SearchTarget{Cron: "0 8 * *"}, want: `cron invalide "0 8 * *"`},
+		{name: "intervalle nul", target: SearchTarget{}, want: "interval doit être positif"},
+		{name: "décalage négatif", target: SearchTarget{Interval: Duration(time.Minute), Jitter: Duration(-time.Second)}, want: "jitter doit être positif"},
+		{name: "heure invalide", target: SearchTarget{Interval: Duration(time.Minute), ActiveHours: &ActiveHours{Start: "25:00", End: "06:00"}}, want: `start invalide "25:00"`},
+		{name: "fuseau inconnu", target: SearchTarget{Interval: Duration(time.Minute), ActiveHours: &ActiveHours{Start: "07:00", End: "23:00", Timezone: "Mars/Olympus"}}, want: `fuseau horaire inconnu "Mars/Olympus"`},
+		{name: "plage vide", target: SearchTarget{Interval: Duration(time.Minute), ActiveHours: &ActiveHours{Start: "07:00", End: "07:00"}}, want: "start et end doivent être différents"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newTargetSchedule(test.target)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("erreur = %v, attendu %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestTargetScheduleNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		cron  string
+		now   time.Time
+		first time.Time
+		next  time.Time
+	}{
+		{
+			name:  "intervalle",
+			now:   time.Date(2026, 10, 16, 10, 0, 0, 0, paris),
+			first: time.Date(2026, 10, 16, 10, 0, 0, 0, paris),
+			next:  time.Date(2026, 10, 16, 10, 15, 0, 0, paris),
+		},
+		{
+			name:  "cron en semaine",
+			cron:  "0,30 8-20 * * 1-5",
+			now:   time.Date(2026, 10, 16, 10, 10, 0, 0, paris),
+			first: time.Date(2026, 10, 16, 10, 30, 0, 0, paris),
+			next:  time.Date(2026, 10, 16, 10, 30, 0, 0, paris),
+		},
+		{
+			// Vendredi soir : prochaine échéance le lundi matin
+			name:  "cron après la dernière échéance de la semaine",
+			cron:  "0,30 8-20 * * 1-5",
+			now:   time.Date(2026, 10, 16, 20, 45, 0, 0, paris),
+			first: time.Date(2026, 10, 19, 8, 0, 0, 0, paris),
+			next:  time.Date(2026, 10, 19, 8, 0, 0, 0, paris),
+		},
+		{
+			// Passage à l'heure d'été : 8h locale reste 8h locale (6h UTC au lieu de 7h)
+			name:  "cron au changement d'heure",
+			cron:  "0 8 * * *",
+			now:   time.Date(2026, 3, 28, 9, 0, 0, 0, paris),
+			first: time.Date(2026, 3, 29, 6, 0, 0, 0, time.UTC),
+			next:  time.Date(2026, 3, 29, 6, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := newTargetSchedule(SearchTarget{Interval: Duration(15 * time.Minute), Cron: test.cron})
+			if err != nil {
+				t.Fatalf("newTargetSchedule : %v", err)
+			}
+			if got := schedule.first(test.now); !got.Equal(test.first) {
+				t.Errorf("first = %s, attendu %s", got, test.first)
+			}
+			if got := schedule.Next(test.now); !got.Equal(test.next) {
+				t.Errorf("Next = %s, attendu %s", got, test.next)
+			}
+		})
+	}
+}
+
+func TestTargetScheduleJitter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, paris)
+	jitter := 2 * time.Minute
+	schedule, err := newTargetSchedule(SearchTarget{Interval: Duration(10 * time.Minute), Jitter: Duration(jitter)})
+	if err != nil {
+		t.Fatalf("newTargetSchedule : %v", err)
+	}
+
+	// Le décalage est ajouté à l'échéance, sans jamais atteindre le décalage maximal
+	earliest, latest := now.Add(10*time.Minute), now.Add(10*time.Minute+jitter)
+	for range 200 {
+		if next := schedule.Next(now); next.Before(earliest) || !next.Before(latest) {
+			t.Fatalf("Next = %s, attendu entre %s et %s", next, earliest, latest)
+		}
+	}
+}
+
+func TestActiveWindow(t *testing.T) {
+	day, err := ActiveHours{Start: "07:00", End: "23:00", Timezone: "Europe/Paris"}.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	night, err := ActiveHours{Start: "22:00", End: "06:00", Timezone: "Europe/Paris"}.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		window    *activeWindow
+		at        time.Time
+		contains  bool
+		nextStart time.Time
+	}{
+		{name: "avant la plage", window: day, at: time.Date(2026, 10, 16, 6, 59, 0, 0, paris), nextStart: time.Date(2026, 10, 16, 7, 0, 0, 0, paris)},
+		{name: "début de plage", window: day, at: time.Date(2026, 10, 16, 7, 0, 0, 0, paris), contains: true},
+		{name: "fin de plage exclue", window: day, at: time.Date(2026, 10, 16, 23, 0, 0, 0, paris), nextStart: time.Date(2026, 10, 17, 7, 0, 0, 0, paris)},
+		{name: "nuit : soir", window: night, at: time.Date(2026, 10, 16, 23, 30, 0, 0, paris), contains: true},
+		{name: "nuit : après minuit", window: night, at: time.Date(2026, 10, 17, 5, 59, 0, 0, paris), contains: true},
+		{name: "nuit : fin de plage", window: night, at: time.Date(2026, 10, 17, 6, 0, 0, 0, paris), nextStart: time.Date(2026, 10, 17, 22, 0, 0, 0, paris)},
+		{name: "nuit : journée", window: night, at: time.Date(2026, 10, 17, 12, 0, 0, 0, paris), nextStart: time.Date(2026, 10, 17, 22, 0, 0, 0, paris)},
+		// La date est comparée dans le fuseau de la plage : 5h30 UTC est 7h30 à Paris
+		{name: "autre fuseau", window: day, at: time.Date(2026, 10, 16, 5, 30, 0, 0, time.UTC), contains: true},
+		{name: "autre fuseau hors plage", window: day, at: time.Date(2026, 10, 16, 21, 30, 0, 0, time.UTC), nextStart: time.Date(2026, 10, 17, 5, 0, 0, 0, time.UTC)},
+		// Nuit du changement d'heure : le début de plage reste à 7h locale
+		{name: "passage à l'heure d'été", window: day, at: time.Date(2026, 3, 28, 23, 30, 0, 0, paris), nextStart: time.Date(2026, 3, 29, 5, 0, 0, 0, time.UTC)},
+		{name: "passage à l'heure d'hiver", window: day, at: time.Date(2026, 10, 24, 23, 30, 0, 0, paris), nextStart: time.Date(2026, 10, 25, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.window.contains(test.at); got != test.contains {
+				t.Fatalf("contains = %v, attendu %v", got, test.contains)
+			}
+			if test.contains {
+				return
+			}
+			if got := test.window.nextStart(test.at); !got.Equal(test.nextStart) {
+				t.Errorf("nextStart = %s, attendu %s", got, test.nextStart)
+			}
+		})
+	}
+}
+
+func TestTargetScheduleActiveHours(t *testing.T) {
+	schedule, err := newTargetSchedule(SearchTarget{
+		Interval:    Duration(30 * time.Minute),
+		ActiveHours: &ActiveHours{Start: "22:00", End: "06:00", Timezone: "Europe/Paris"},
+	})
+	if err != nil {
+		t.Fatalf("newTargetSchedule : %v", err)
+	}
+
+	// Une échéance hors de la plage est reportée au prochain début de plage
+	if got, want := schedule.first(time.Date(2026, 10, 16, 12, 0, 0, 0, paris)), time.Date(2026, 10, 16, 22, 0, 0, 0, paris); !got.Equal(want) {
+		t.Errorf("first = %s, attendu %s", got, want)
+	}
+	if got, want := schedule.Next(time.Date(2026, 10, 17, 5, 45, 0, 0, paris)), time.Date(2026, 10, 17, 22, 0, 0, 0, paris); !got.Equal(want) {
+		t.Errorf("Next = %s, attendu %s", got, want)
+	}
+	if got, want := schedule.Next(time.Date(2026, 10, 16, 23, 45, 0, 0, paris)), time.Date(2026, 10, 17, 0, 15, 0, 0, paris); !got.Equal(want) {
+		t.Errorf("Next = %s, attendu %s", got, want)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, paris)
+	disabled := false
+	scheduler, err := NewScheduler([]SearchTarget{
+		{Title: "Intervalle", Interval: Duration(10 * time.Minute)},
+		{Title: "Cron", Cron: "30 10 * * *"},
+		{Title: "Désactivée", Interval: Duration(time.Minute), Enabled: &disabled},
+	}, now)
+	if err != nil {
+		t.Fatalf("NewScheduler : %v", err)
+	}
+
+	runs := scheduler.NextRuns()
+	if len(runs) != 2 || runs[0].Target.Title != "Intervalle" || runs[1].Target.Title != "Cron" {
+		t.Fatalf("prochains scrapings = %+v", runs)
+	}
+
+	due := scheduler.Due(now)
+	if len(due) != 1 || due[0].Target.Title != "Intervalle" || !due[0].Next.Equal(now.Add(10*time.Minute)) {
+		t.Fatalf("Due = %+v, attendu la recherche Intervalle replanifiée dans 10 minutes", due)
+	}
+	if got := scheduler.NextWakeup(); !got.Equal(now.Add(10 * time.Minute)) {
+		t.Errorf("NextWakeup = %s, attendu %s", got, now.Add(10*time.Minute))
+	}
+	if due := scheduler.Due(now.Add(5 * time.Minute)); len(due) != 0 {
+		t.Errorf("Due = %+v, attendu aucune recherche", due)
+	}
+
+	if _, err := NewScheduler([]SearchTarget{{Title: "Invalide", Agency: Afedim}}, now); err == nil || !strings.Contains(err.Error(), "Invalide (Afedim)") {
+		t.Errorf("erreur = %v, attendu la recherche invalide", err)
+	}
+}
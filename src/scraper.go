@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+)
+
+/**
+ * Scraper est l'interface que chaque agence immobilière implémente pour être scrapée.
+ * Le collecteur principal ne connaît aucune agence : il récupère l'implémentation dans le registre.
+ */
+type Scraper interface {
+	/**
+	 * SetupMainPage configure le collecteur de la page de résultats de l'agence.
+	 * @param {colly.Collector} collector - Le collecteur à configurer.
+	 * @param {[]string} listingItems - La liste à remplir (URLs des pages de détail, ou identifiants si l'agence n'a pas de pages de détail).
+	 * @return {void}
+	 */
+	SetupMainPage(collector *colly.Collector, listingItems *[]string)
+
+	/**
+	 * ProcessDetailPages configure le collecteur des pages de détail de l'agence.
+	 * @param {colly.Collector} collector - Le collecteur à configurer.
+	 * @param {[]Announcement} announcements - La liste des annonces à remplir.
+	 * @return {void}
+	 */
+	ProcessDetailPages(collector *colly.Collector, announcements *[]Announcement)
+
+	/**
+	 * HasDetailPages indique si les annonces de l'agence doivent être complétées par la visite des pages de détail.
+	 * @return {bool} - true si les pages de détail doivent être visitées.
+	 */
+	HasDetailPages() bool
+
+	/**
+	 * HasAPI indique si l'agence sait décoder les réponses JSON de l'API de recherche de son site.
+	 * @return {bool} - true si les réponses JSON peuvent être décodées.
+	 */
+	HasAPI() bool
+
+	/**
+	 * DecodeAPIResponse décode une réponse JSON de l'API de recherche de l'agence (URL de recherche pointant vers l'API).
+	 * @param {[]byte} body - Le corps de la réponse.
+	 * @param {colly.Request} request - La requête, pour construire les URLs absolues.
+	 * @return {[]Announcement} - Les annonces de la page.
+	 * @return {int} - Le nombre total d'annonces de la recherche, 0 s'il est inconnu.
+	 * @return {error} - Erreur de décodage.
+	 */
+	DecodeAPIResponse(body []byte, request *colly.Request) ([]Announcement, int, error)
+
+	/**
+	 * Pagination indique comment trouver les pages de résultats suivantes.
+	 * @return {Pagination} - La description de la pagination, nil si seule la première page est scrapée.
+	 */
+	Pagination() *Pagination
+
+	/**
+	 * DeriveAnnouncement construit une annonce directement depuis un élément de la page de résultats,
+	 * pour les agences sans pages de détail.
+	 * @param {string} listingItem - L'élément collecté par SetupMainPage.
+	 * @return {Announcement} - L'annonce avec sa référence dérivée.
+	 */
+	DeriveAnnouncement(listingItem string) Announcement
+}
+
+/**
+ * agencyScraper est l'implémentation de Scraper construite à partir des fonctions setupMainPage* et processDetailPages*.
+ * @property {func} setupMainPage - Fonction de configuration de la page de résultats.
+ * @property {func} processDetailPages - Fonction de configuration des pages de détail (nil si l'agence n'en a pas).
+ * @property {func} deriveAnnouncement - Fonction de dérivation de la référence depuis la page de résultats (nil si l'agence a des pages de détail).
+ * @property {func} decodeAPI - Fonction de décodage des réponses JSON de l'API de recherche (nil si l'agence n'a pas d'API).
+ * @property {Pagination} pagination - Pagination de la page de résultats (nil si l'agence n'a qu'une page).
+ */
+type agencyScraper struct {
+	setupMainPage      func(collector *colly.Collector, listingItems *[]string)
+	processDetailPages func(collector *colly.Collector, announcements *[]Announcement)
+	deriveAnnouncement func(listingItem string) Announcement
+	decodeAPI          func(body []byte, request *colly.Request) ([]Announcement, int, error)
+	pagination         *Pagination
+}
+
+func (scraper *agencyScraper) SetupMainPage(collector *colly.Collector, listingItems *[]string) {
+	scraper.setupMainPage(collector, listingItems)
+}
+
+func (scraper *agencyScraper) ProcessDetailPages(collector *colly.Collector, announcements *[]Announcement) {
+	if scraper.processDetailPages != nil {
+		scraper.processDetailPages(collector, announcements)
+	}
+}
+
+func (scraper *agencyScraper) HasDetailPages() bool {
+	return scraper.processDetailPages != nil
+}
+
+func (scraper *agencyScraper) HasAPI() bool {
+	return scraper.decodeAPI != nil
+}
+
+func (scraper *agencyScraper) DecodeAPIResponse(body []byte, request *colly.Request) ([]Announcement, int, error) {
+	if scraper.decodeAPI == nil {
+		return nil, 0, errors.New("l'agence n'a pas d'API de recherche")
+	}
+	return scraper.decodeAPI(body, request)
+}
+
+func (scraper *agencyScraper) Pagination() *Pagination {
+	return scraper.pagination
+}
+
+func (scraper *agencyScraper) DeriveAnnouncement(listingItem string) Announcement {
+	if scraper.deriveAnnouncement != nil {
+		return scraper.deriveAnnouncement(listingItem)
+	}
+	return Announcement{propertyReference: listingItem, url: listingItem}
+}
+
+// Registre des scrapers, indexé par agence
+var (
+	scrapersMutex sync.RWMutex
+	scrapers      = make(map[Agency]Scraper)
+)
+
+/**
+ * RegisterScraper enregistre l'implémentation du scraper d'une agence.
+ * Un second enregistrement pour la même agence est une erreur de programmation.
+ * @param {Agency} agency - L'agence concernée.
+ * @param {Scraper} scraper - L'implémentation du scraper.
+ * @return {void}
+ */
+func RegisterScraper(agency Agency, scraper Scraper) {
+	scrapersMutex.Lock()
+	defer scrapersMutex.Unlock()
+
+	if _, exists := scrapers[agency]; exists {
+		panic(fmt.Sprintf("scraper déjà enregistré pour l'agence : %s", agency))
+	}
+	scrapers[agency] = scraper
+}
+
+/**
+ * GetScraper retourne le scraper enregistré pour une agence.
+ * @param {Agency} agency - L'agence recherchée.
+ * @return {Scraper} - L'implémentation du scraper.
+ * @return {error} - Erreur si l'agence est inconnue.
+ */
+func GetScraper(agency Agency) (Scraper, error) {
+	scrapersMutex.RLock()
+	defer scrapersMutex.RUnlock()
+
+	scraper, exists := scrapers[agency]
+	if !exists {
+		return nil, fmt.Errorf("agence inconnue : %s", agency)
+	}
+	return scraper, nil
+}
+
+/**
+ * RegisteredAgencies retourne la liste triée des agences enregistrées.
+ * @return {[]Agency} - Les agences disponibles.
+ */
+func RegisteredAgencies() []Agency {
+	scrapersMutex.RLock()
+	defer scrapersMutex.RUnlock()
+
+	agencies := make([]Agency, 0, len(scrapers))
+	for agency := range scrapers {
+		agencies = append(agencies, agency)
+	}
+	sort.Slice(agencies, func(i, j int) bool { return agencies[i] < agencies[j] })
+	return agencies
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Délai laissé aux requêtes en cours du serveur HTTP à l'arrêt
+const httpShutdownTimeout = 5 * time.Second
+
+/**
+ * newHTTPHandler crée les routes du serveur HTTP d'exploitation.
+ * @param {Probes} probes - État du scraper pour les sondes Kubernetes.
+ * @return {http.Handler} - Le handler des routes (/metrics, /healthz et /readyz).
+ */
+func newHTTPHandler(probes *Probes) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metricsHandler())
+	mux.Handle("GET /healthz", probeHandler(func(r *http.Request) error {
+		return probes.Live(time.Now())
+	}))
+	mux.Handle("GET /readyz", probeHandler(func(r *http.Request) error {
+		return probes.Ready(r.Context(), time.Now())
+	}))
+	return mux
+}
+
+/**
+ * StartHTTPServer démarre le serveur HTTP d'exploitation en arrière-plan, jusqu'à l'annulation du contexte.
+ * @param {context.Context} ctx - Contexte racine : le serveur s'arrête à son annulation.
+ * @param {string} addr - Adresse d'écoute (ex : ":8080").
+ * @param {http.Handler} handler - Les routes du serveur.
+ * @return {void}
+ */
+func StartHTTPServer(ctx context.Context, addr string, handler http.Handler) {
+	server := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		serverLog.Info("Serveur HTTP démarré", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverLog.Error("Erreur du serveur HTTP", "error", err)
+		}
+	}()
+
+	context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			serverLog.Error("Erreur lors de l'arrêt du serveur HTTP", "error", err)
+		}
+	})
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Version du format du fichier de stockage des références déjà traitées
+// (2 : références canoniques de Square Habitat, CA Immobilier et La Forêt)
+const seenStoreVersion = 2
+
+/**
+ * SeenEntry est une structure pour stocker une référence déjà traitée.
+ * @property {Agency} Agency - L'agence de l'annonce.
+ * @property {string} PropertyReference - Référence du bien immobilier.
+ * @property {string} URL - URL de la page de détails de l'annonce.
+ * @property {time.Time} FirstSeen - Date de la première détection.
+ * @property {time.Time} LastSeen - Date de la dernière détection.
+ * @property {bool} Notified - true si l'annonce a été notifiée.
+ * @property {map[string]string} Receipts - Identifiant de la notification envoyée, par service (réponse Telegram au retrait).
+ * @property {[]string} Pending - Services de notification n'ayant pas reçu la notification, retentés au scraping suivant.
+ * @property {[]Observation} History - Loyer, charges et disponibilité observés, une entrée par changement.
+ * @property {string} Search - URL de la recherche ayant détecté l'annonce en dernier.
+ * @property {int} Missed - Nombre de scrapings complets consécutifs de cette recherche sans l'annonce.
+ * @property {*time.Time} GoneAt - Date du retrait de l'annonce, nil si elle est en ligne.
+ */
+type SeenEntry struct {
+	Agency            Agency            `json:"agency"`
+	PropertyReference string            `json:"propertyReference"`
+	URL               string            `json:"url"`
+	FirstSeen         time.Time         `json:"firstSeen"`
+	LastSeen          time.Time         `json:"lastSeen"`
+	Notified          bool              `json:"notified,omitempty"`
+	Receipts          map[string]string `json:"receipts,omitempty"`
+	Pending           []string          `json:"pending,omitempty"`
+	History           []Observation     `json:"history,omitempty"`
+	Search            string            `json:"search,omitempty"`
+	Missed            int               `json:"missed,omitempty"`
+	GoneAt            *time.Time        `json:"goneAt,omitempty"`
+}
+
+/**
+ * seenStoreFile est le contenu sérialisé du fichier de stockage.
+ * @property {int} Version - Version du format du fichier.
+ * @property {[]Agency} WarmedUp - Agences dont le premier scraping a déjà été effectué.
+ * @property {[]SeenEntry} Entries - Références déjà traitées.
+ */
+type seenStoreFile struct {
+	Version  int          `json:"version"`
+	WarmedUp []Agency     `json:"warmedUp"`
+	Entries  []*SeenEntry `json:"entries"`
+}
+
+/**
+ * SeenStore est le stockage sur disque des références déjà traitées, indexées par agence et référence.
+ * Le fichier est chargé au démarrage et réécrit après chaque cycle de scraping.
+ * Les méthodes sont sûres en accès concurrent (agences scrapées en parallèle).
+ * @property {sync.Mutex} mutex - Verrou protégeant les références.
+ * @property {string} path - Chemin du fichier de stockage.
+ * @property {map[string]*SeenEntry} entries - Références déjà traitées.
+ * @property {map[Agency]bool} warmedUp - Agences dont le premier scraping a déjà été effectué.
+ * @property {map[string]bool} searches - Recherches configurées, par agence et URL (seenKey), nil si inconnues.
+ * @property {bool} readOnly - true si le stockage n'est jamais réécrit sur disque (simulation).
+ */
+type SeenStore struct {
+	mutex    sync.Mutex
+	path     string
+	entries  map[string]*SeenEntry
+	warmedUp map[Agency]bool
+	searches map[string]bool
+	readOnly bool
+}
+
+/**
+ * OpenSeenStore charge le stockage depuis le fichier indiqué, ou crée un stockage vide si le fichier n'existe pas.
+ * @param {string} path - Chemin du fichier de stockage.
+ * @return {SeenStore} - Le stockage chargé.
+ * @return {error} - Erreur si le fichier existe mais ne peut pas être lu.
+ */
+func OpenSeenStore(path string) (*SeenStore, error) {
+	store := &SeenStore{
+		path:     path,
+		entries:  make(map[string]*SeenEntry),
+		warmedUp: make(map[Agency]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lecture du stockage %s : %w", path, err)
+	}
+
+	var file seenStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("décodage du stockage %s : %w", path, err)
+	}
+	if file.Version > seenStoreVersion {
+		return nil, fmt.Errorf("version du stockage %s non supportée : %d", path, file.Version)
+	}
+
+	for _, agency := range file.WarmedUp {
+		store.warmedUp[agency] = true
+	}
+	migrated := 0
+	for _, entry := range file.Entries {
+		// Convertir les références des versions précédentes vers les références canoniques
+		if file.Version < 2 {
+			if reference := migrateReference(entry); reference != entry.PropertyReference {
+				entry.PropertyReference = reference
+				migrated++
+			}
+		}
+		store.entries[seenKey(entry.Agency, entry.PropertyReference)] = entry
+	}
+	if migrated > 0 {
+		storeLog.Info("Références converties vers le nouveau format", "path", path, "count", migrated, "version", seenStoreVersion)
+	}
+
+	return store, nil
+}
+
+/**
+ * seenKey construit la clé d'une référence dans le stockage.
+ * @param {Agency} agency - L'agence de l'annonce.
+ * @param {string} propertyReference - Référence du bien immobilier.
+ * @return {string} - La clé unique.
+ */
+func seenKey(agency Agency, propertyReference string) string {
+	return string(agency) + "\x00" + propertyReference
+}
+
+/**
+ * Touch enregistre la détection d'une annonce, met à jour sa date de dernière détection et son historique.
+ * Une annonce enregistrée sous une ancienne référence (legacyReferences) est reprise sous sa référence canonique.
+ * @param {Agency} agency - L'agence de l'annonce.
+ * @param {string} search - URL de la recherche ayant détecté l'annonce.
+ * @param {Announcement} announcement - L'annonce détectée.
+ * @param {time.Time} now - Date de la détection.
+ * @return {SeenUpdate} - L'annonce enregistrée avant cette détection (New si elle n'avait jamais été vue).
+ */
+func (store *SeenStore) Touch(agency Agency, search string, announcement Announcement, now time.Time) SeenUpdate {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := seenKey(agency, announcement.propertyReference)
+	if _, exists := store.entries[key]; !exists {
+		for _, legacy := range announcement.legacyReferences {
+			legacyKey := seenKey(agency, legacy)
+			if entry, found := store.entries[legacyKey]; found {
+				delete(store.entries, legacyKey)
+				entry.PropertyReference = announcement.propertyReference
+				store.entries[key] = entry
+				break
+			}
+		}
+	}
+
+	if entry, exists := store.entries[key]; exists {
+		update := SeenUpdate{Notified: entry.Notified, Pending: entry.Pending, PreviousSeen: entry.LastSeen, PreviousRent: entry.lastKnownRent(), WasGone: entry.GoneAt != nil}
+		entry.LastSeen = now
+		entry.URL = announcement.url
+		entry.Search = search
+		entry.Missed = 0
+		entry.GoneAt = nil
+		entry.recordObservation(announcement, now)
+		return update
+	}
+
+	entry := &SeenEntry{
+		Agency:            agency,
+		PropertyReference: announcement.propertyReference,
+		URL:               announcement.url,
+		FirstSeen:         now,
+		LastSeen:          now,
+		Search:            search,
+	}
+	entry.recordObservation(announcement, now)
+	store.entries[key] = entry
+	return SeenUpdate{New: true}
+}
+
+/**
+ * MarkNotified enregistre que l'annonce a été notifiée : ses changements (loyer, republication, retrait) seront notifiés.
+ * @param {Agency} agency - L'agence de l'annonce.
+ * @param {string} propertyReference - Référence du bien immobilier.
+ * @param {map[string]string} receipts - Identifiant de la notification envoyée, par service (ajoutés aux précédents).
+ * @param {[]string} pending - Services n'ayant pas reçu la notification, à retenter au scraping suivant.
+ * @return {void}
+ */
+func (store *SeenStore) MarkNotified(agency Agency, propertyReference string, receipts map[string]string, pending []string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if entry, exists := store.entries[seenKey(agency, propertyReference)]; exists {
+		entry.Notified = true
+		for service, receipt := range receipts {
+			if entry.Receipts == nil {
+				entry.Receipts = make(map[string]string)
+			}
+			entry.Receipts[service] = receipt
+		}
+		entry.Pending = pending
+	}
+}
+
+/**
+ * Forget supprime une référence, pour que l'annonce soit de nouveau détectée comme nouvelle (notification non envoyée).
+ * @param {Agency} agency - L'agence de l'annonce.
+ * @param {string} propertyReference - Référence du bien immobilier.
+ * @return {void}
+ */
+func (store *SeenStore) Forget(agency Agency, propertyReference string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.entries, seenKey(agency, propertyReference))
+}
+
+/**
+ * Clear supprime toutes les références d'une agence et son premier scraping : le prochain scraping de l'agence
+ * marque de nouveau les annonces en ligne comme vues, sans notification.
+ * @param {Agency} agency - L'agence concernée.
+ * @return {int} - Le nombre de références supprimées.
+ */
+func (store *SeenStore) Clear(agency Agency) int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	removed := 0
+	for key, entry := range store.entries {
+		if entry.Agency == agency {
+			delete(store.entries, key)
+			removed++
+		}
+	}
+	delete(store.warmedUp, agency)
+	return removed
+}
+
+/**
+ * Entries retourne une copie des références traitées, triées par agence puis par référence.
+ * @param {Agency} agency - L'agence dont les références sont retournées, vide pour toutes les agences.
+ * @return {[]SeenEntry} - Les références.
+ */
+func (store *SeenStore) Entries(agency Agency) []SeenEntry {
+	store.mutex.Lock()
+	var entries []SeenEntry
+	for _, entry := range store.entries {
+		if agency == "" || entry.Agency == agency {
+			copied := *entry
+			copied.History = append([]Observation(nil), entry.History...)
+			entries = append(entries, copied)
+		}
+	}
+	store.mutex.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Agency != entries[j].Agency {
+			return entries[i].Agency < entries[j].Agency
+		}
+		return entries[i].PropertyReference < entries[j].PropertyReference
+	})
+	return entries
+}
+
+/**
+ * SetSearches enregistre les recherches configurées (actives ou non) : les annonces d'une recherche retirée
+ * de la configuration, ou dont l'URL a été modifiée, sont reprises par la recherche suivante de la même agence.
+ * @param {[]SearchTarget} targets - Les recherches configurées.
+ * @return {void}
+ */
+func (store *SeenStore) SetSearches(targets []SearchTarget) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.searches = make(map[string]bool, len(targets))
+	for _, target := range targets {
+		store.searches[seenKey(target.Agency, target.URL)] = true
+	}
+}
+
+/**
+ * SetReadOnly empêche toute réécriture du stockage sur disque : les références restent modifiées en mémoire
+ * pendant l'exécution (simulation).
+ * @return {void}
+ */
+func (store *SeenStore) SetReadOnly() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.readOnly = true
+}
+
+/**
+ * IsWarmedUp indique si le premier scraping de l'agence a déjà été effectué.
+ * @param {Agency} agency - L'agence concernée.
+ * @return {bool} - true si l'agence a déjà été scrapée.
+ */
+func (store *SeenStore) IsWarmedUp(agency Agency) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.warmedUp[agency]
+}
+
+/**
+ * MarkWarmedUp enregistre que le premier scraping de l'agence a été effectué.
+ * @param {Agency} agency - L'agence concernée.
+ * @return {void}
+ */
+func (store *SeenStore) MarkWarmedUp(agency Agency) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.warmedUp[agency] = true
+}
+
+/**
+ * Save écrit le stockage sur disque via un fichier temporaire renommé, pour ne jamais laisser un fichier tronqué.
+ * @return {error} - Erreur lors de l'écriture.
+ */
+func (store *SeenStore) Save() error {
+	store.mutex.Lock()
+	if store.readOnly {
+		store.mutex.Unlock()
+		return nil
+	}
+	file := seenStoreFile{Version: seenStoreVersion}
+	for agency := range store.warmedUp {
+		file.WarmedUp = append(file.WarmedUp, agency)
+	}
+	for _, entry := range store.entries {
+		copied := *entry
+		copied.History = append([]Observation(nil), entry.History...)
+		file.Entries = append(file.Entries, &copied)
+	}
+	store.mutex.Unlock()
+
+	// Trier pour obtenir un fichier stable d'un cycle à l'autre
+	sort.Slice(file.WarmedUp, func(i, j int) bool { return file.WarmedUp[i] < file.WarmedUp[j] })
+	sort.Slice(file.Entries, func(i, j int) bool {
+		if file.Entries[i].Agency != file.Entries[j].Agency {
+			return file.Entries[i].Agency < file.Entries[j].Agency
+		}
+		return file.Entries[i].PropertyReference < file.Entries[j].PropertyReference
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encodage du stockage : %w", err)
+	}
+
+	return writeFileAtomic(store.path, data)
+}
+
+/**
+ * writeFileAtomic écrit un fichier via un fichier temporaire renommé, pour ne jamais laisser un fichier tronqué.
+ * Le dossier du fichier est créé si nécessaire.
+ * @param {string} path - Chemin du fichier.
+ * @param {[]byte} data - Contenu du fichier.
+ * @return {error} - Erreur lors de l'écriture.
+ */
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("création du dossier de %s : %w", path, err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("écriture de %s : %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("remplacement de %s : %w", path, err)
+	}
+
+	return nil
+}
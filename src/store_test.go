@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestOpenSeenStoreMigratesVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+	if err := os.WriteFile(path, readFixture(t, "seen_v1.json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenSeenStore(path)
+	if err != nil {
+		t.Fatalf("OpenSeenStore : %v", err)
+	}
+
+	// Conversions exactes faites au chargement
+	references := func(agency Agency) []string {
+		var references []string
+		for _, entry := range store.Entries(agency) {
+			references = append(references, entry.PropertyReference)
+		}
+		sort.Strings(references)
+		return references
+	}
+	wantReferences := map[Agency][]string{
+		SquareHabitat:     {hashReference("Rennes centre, appartement meublé de 38 m²."), hashReference("Studio étudiant proche campus de Beaulieu.")},
+		CAImmobilier:      {"24816033"},
+		LaForetImmobilier: {"LAF-1457720"},
+		Afedim:            {"AFD-2231"},
+	}
+	sort.Strings(wantReferences[SquareHabitat])
+	for agency, want := range wantReferences {
+		if got := references(agency); !slices.Equal(got, want) {
+			t.Errorf("références %s = %q, attendu %q", agency, got, want)
+		}
+		if !store.IsWarmedUp(agency) {
+			t.Errorf("premier scraping de %s oublié", agency)
+		}
+	}
+
+	// Les annonces scrapées avec leur nouvelle référence sont reconnues : aucune n'est notifiée de nouveau
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	announcements := []struct {
+		agency       Agency
+		announcement Announcement
+	}{
+		{SquareHabitat, Announcement{
+			propertyReference: "035-LOC-1284571",
+			url:               "https://www.squarehabitat.fr/annonce/location/appartement/rennes-35000/1284571",
+			description:       "Rennes centre, appartement meublé de 38 m².",
+			legacyReferences:  []string{hashReference("Rennes centre, appartement meublé de 38 m².")},
+		}},
+		{SquareHabitat, Announcement{
+			propertyReference: hashReference("Studio étudiant proche campus de Beaulieu."),
+			description:       "Studio étudiant proche campus de Beaulieu.",
+		}},
+		{CAImmobilier, Announcement{
+			propertyReference: "24816033",
+			url:               "https://www.ca-immobilier.fr/louer/bien-maison/thorigne-fouillard-35235/24816033?origin=list",
+			legacyReferences:  []string{"24816033?origin=list"},
+		}},
+		{LaForetImmobilier, Announcement{
+			propertyReference: "LAF-1457720",
+			legacyReferences:  []string{"Web: LAF-1457720, Agence: RE-2231"},
+		}},
+		{Afedim, Announcement{propertyReference: "AFD-2231"}},
+	}
+	for _, test := range announcements {
+		if update := store.Touch(test.agency, "", test.announcement, now); update.New {
+			t.Errorf("annonce %s %s considérée nouvelle après la migration", test.agency, test.announcement.propertyReference)
+		}
+	}
+	if entries := store.Entries(SquareHabitat); len(entries) != 2 {
+		t.Errorf("références Square Habitat = %d, attendu 2", len(entries))
+	}
+	for _, entry := range store.Entries(SquareHabitat) {
+		if entry.PropertyReference == "035-LOC-1284571" && !entry.FirstSeen.Equal(time.Date(2025, 3, 2, 8, 15, 0, 0, time.UTC)) {
+			t.Errorf("date de première détection perdue : %s", entry.FirstSeen)
+		}
+	}
+
+	// Le fichier est réécrit au format courant, sans nouvelle conversion au chargement suivant
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save : %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file seenStoreFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != seenStoreVersion {
+		t.Fatalf("version enregistrée = %d (%v), attendu %d", file.Version, err, seenStoreVersion)
+	}
+	reopened, err := OpenSeenStore(path)
+	if err != nil {
+		t.Fatalf("OpenSeenStore : %v", err)
+	}
+	if got := reopened.Entries(LaForetImmobilier); len(got) != 1 || got[0].PropertyReference != "LAF-1457720" {
+		t.Errorf("références La Forêt après rechargement = %+v", got)
+	}
+}
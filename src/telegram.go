@@ -1,52 +1,264 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 const (
-	TelegramBotToken = "7423967574:AAHUuvNAsvLsTQ6bHMxHuOWxws_LXVeQUHw" // Token du bot Telegram (@AnnoncesImmobiliersScraperBot)
-	TelegramChannel  = "@annonceimmobiliers"                            // Canal Telegram public (https://t.me/annonceimmobiliers)
-	MaxRetries       = 5                                                // Nombre maximal de tentatives
+	MaxRetries = 5 // Nombre maximal de tentatives
 )
 
-// sendTelegramMessageToPublicChannel envoie un message à un canal Telegram public.
-func sendTelegramMessageToPublicChannel(message string) {
-	// Initialiser le bot Telegram
-	bot, err := tgbotapi.NewBotAPI(TelegramBotToken)
+// Format attendu du token d'un bot Telegram (<id>:<secret>)
+var telegramTokenPattern = regexp.MustCompile(`^\d+:[A-Za-z0-9_-]+$`)
+
+// Format attendu d'un canal Telegram : @nom_public ou identifiant numérique (-100...)
+var telegramChannelPattern = regexp.MustCompile(`^(@[A-Za-z0-9_]{5,}|-?\d+)$`)
+
+/**
+ * TelegramConfig regroupe les identifiants Telegram lus depuis l'environnement ou les secrets montés.
+ * @property {string} BotToken - Token du bot Telegram.
+ * @property {string} Channel - Canal Telegram cible (@nom ou identifiant numérique).
+ */
+type TelegramConfig struct {
+	BotToken string
+	Channel  string
+}
+
+/**
+ * LoadTelegramConfig lit et valide la configuration Telegram.
+ * Le canal peut être spécifique à l'environnement (APP_ENV) : TELEGRAM_CHANNEL_STAGING, TELEGRAM_CHANNEL_PRODUCTION, ...
+ * @param {string} channel - Canal configuré (canal d'administration), prioritaire sur TELEGRAM_CHANNEL ; vide pour le canal par défaut.
+ * @return {TelegramConfig} - La configuration Telegram.
+ * @return {error} - Erreur si une valeur est manquante ou invalide.
+ */
+func LoadTelegramConfig(channel string) (TelegramConfig, error) {
+	config := TelegramConfig{
+		BotToken: lookupSecret("TELEGRAM_BOT_TOKEN"),
+		Channel:  channel,
+	}
+
+	// Canal propre à l'environnement, sinon canal par défaut
+	if env := strings.ToUpper(getEnv("APP_ENV", "")); config.Channel == "" && env != "" {
+		config.Channel = lookupSecret("TELEGRAM_CHANNEL_" + env)
+	}
+	if config.Channel == "" {
+		config.Channel = lookupSecret("TELEGRAM_CHANNEL")
+	}
+
+	var errs []error
+	if config.BotToken == "" {
+		errs = append(errs, errors.New("TELEGRAM_BOT_TOKEN est manquant"))
+	} else if !telegramTokenPattern.MatchString(config.BotToken) {
+		errs = append(errs, errors.New("TELEGRAM_BOT_TOKEN n'a pas le format <id>:<secret>"))
+	}
+	if config.Channel == "" {
+		errs = append(errs, errors.New("TELEGRAM_CHANNEL est manquant"))
+	} else if !telegramChannelPattern.MatchString(config.Channel) {
+		errs = append(errs, fmt.Errorf("TELEGRAM_CHANNEL invalide : %q", config.Channel))
+	}
+
+	return config, errors.Join(errs...)
+}
+
+/**
+ * lookupSecret lit une valeur sensible, par ordre de priorité :
+ * variable d'environnement KEY, fichier désigné par KEY_FILE, puis fichier KEY dans le dossier SECRETS_DIR
+ * (secret Kubernetes monté en volume).
+ * @param {string} key - Nom de la valeur.
+ * @return {string} - La valeur trouvée, ou une chaîne vide.
+ */
+func lookupSecret(key string) string {
+	if value := getEnv(key, ""); value != "" {
+		return value
+	}
+
+	var path string
+	if file := getEnv(key+"_FILE", ""); file != "" {
+		path = file
+	} else if dir := getEnv("SECRETS_DIR", ""); dir != "" {
+		path = filepath.Join(dir, key)
+	} else {
+		return ""
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			telegramLog.Error("Erreur lors de la lecture du secret", "key", key, "error", err)
+		}
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Longueur maximale de la légende d'une photo Telegram
+const telegramCaptionMaxLength = 1024
+
+/**
+ * TelegramNotifier envoie les notifications sur un canal Telegram.
+ * @property {sync.Mutex} mutex - Verrou sérialisant les envois : une attente RetryAfter bloque tous les workers.
+ * @property {tgbotapi.BotAPI} bot - Instance du bot Telegram.
+ * @property {string} channel - Canal Telegram cible.
+ */
+type TelegramNotifier struct {
+	mutex   sync.Mutex
+	bot     *tgbotapi.BotAPI
+	channel string
+}
+
+/**
+ * NewTelegramNotifier crée le bot Telegram et vérifie le token auprès de l'API.
+ * Le token et le canal proviennent de l'environnement ou des secrets montés (voir LoadTelegramConfig).
+ * @param {NotifierConfig} config - La configuration du service (url : API Telegram alternative, channel : canal, optionnels).
+ * @return {TelegramNotifier} - Le service prêt à envoyer des messages.
+ * @return {error} - Erreur si la configuration est invalide ou si le bot ne peut pas être initialisé.
+ */
+func NewTelegramNotifier(config NotifierConfig) (*TelegramNotifier, error) {
+	telegramConfig, err := LoadTelegramConfig(config.Channel)
 	if err != nil {
-		log.Fatalf("Erreur lors de la création du bot Telegram : %v", err)
+		return nil, err
 	}
 
-	// Créer un nouveau message pour le canal
-	msg := tgbotapi.NewMessageToChannel(TelegramChannel, message)
+	endpoint := tgbotapi.APIEndpoint
+	if config.URL != "" {
+		endpoint = strings.TrimSuffix(config.URL, "/") + "/bot%s/%s"
+	}
+
+	bot, err := tgbotapi.NewBotAPIWithClient(telegramConfig.BotToken, endpoint, &http.Client{Timeout: notifierHTTPTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("création du bot Telegram : %w", err)
+	}
+
+	return &TelegramNotifier{bot: bot, channel: telegramConfig.Channel}, nil
+}
+
+func (telegram *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+func (telegram *TelegramNotifier) Notify(ctx context.Context, message Message) error {
+	_, err := telegram.NotifyWithReceipt(ctx, message)
+	return err
+}
+
+/**
+ * Ping vérifie que l'API Telegram est joignable et que le token est toujours valide (getMe).
+ * @param {context.Context} ctx - Contexte d'annulation de la vérification.
+ * @return {error} - Erreur si l'API est injoignable ou refuse le token.
+ */
+func (telegram *TelegramNotifier) Ping(ctx context.Context) error {
+	// La bibliothèque Telegram ne gère pas les contextes : vérifier l'annulation avant l'appel
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := telegram.bot.GetMe(); err != nil {
+		return fmt.Errorf("vérification du bot Telegram : %w", err)
+	}
+	return nil
+}
+
+/**
+ * NotifyWithReceipt envoie le message sur le canal Telegram : avec la première photo en légende si l'annonce en a une,
+ * sinon en texte simple. Les boutons deviennent un clavier de liens sous le message. Un message répondant
+ * à une notification précédente (ReplyTo) est envoyé en réponse à celle-ci.
+ * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+ * @param {Message} message - Le message à envoyer.
+ * @return {string} - L'identifiant du message Telegram envoyé.
+ * @return {error} - Erreur lors de l'envoi.
+ */
+func (telegram *TelegramNotifier) NotifyWithReceipt(ctx context.Context, message Message) (string, error) {
+	replyTo, _ := strconv.Atoi(message.ReplyTo[telegram.Name()])
+
+	var markup interface{}
+	if len(message.Buttons) > 0 {
+		// Un bouton par ligne : les libellés "Voir chez <agence>" d'un bien publié par plusieurs agences restent lisibles
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for _, button := range message.Buttons {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL(button.Label, button.URL)))
+		}
+		markup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+
+	if len(message.PhotoURLs) > 0 && len(message.Text) <= telegramCaptionMaxLength {
+		photo := tgbotapi.NewPhotoToChannel(telegram.channel, tgbotapi.FileURL(message.PhotoURLs[0]))
+		photo.Caption = message.Text
+		photo.ReplyMarkup = markup
+		photo.ReplyToMessageID = replyTo
+		photo.AllowSendingWithoutReply = true
+		sent, err := telegram.send(ctx, photo)
+		if err == nil || ctx.Err() != nil {
+			return strconv.Itoa(sent.MessageID), err
+		}
+		// Telegram refuse parfois de télécharger la photo : le message part alors sans photo
+		telegramLog.WarnContext(ctx, "Erreur lors de l'envoi de la photo Telegram, envoi du texte seul", "error", err)
+	}
+
+	msg := tgbotapi.NewMessageToChannel(telegram.channel, message.Text)
+	msg.ReplyMarkup = markup
+	msg.ReplyToMessageID = replyTo
+	msg.AllowSendingWithoutReply = true
+	sent, err := telegram.send(ctx, msg)
+	return strconv.Itoa(sent.MessageID), err
+}
+
+/**
+ * send envoie un message Telegram en respectant les limites de débit de l'API.
+ * L'attente imposée par l'API est interrompue à l'annulation du contexte.
+ * @param {context.Context} ctx - Contexte d'annulation de l'envoi.
+ * @param {tgbotapi.Chattable} chattable - Le message à envoyer.
+ * @return {tgbotapi.Message} - Le message envoyé.
+ * @return {error} - Erreur lors de l'envoi.
+ */
+func (telegram *TelegramNotifier) send(ctx context.Context, chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
+	telegram.mutex.Lock()
+	defer telegram.mutex.Unlock()
 
 	retries := 0
 
 	for {
+		// La bibliothèque Telegram ne gère pas les contextes : vérifier l'annulation avant chaque tentative
+		if err := ctx.Err(); err != nil {
+			return tgbotapi.Message{}, err
+		}
+
 		// Envoyer le message
-		_, err = bot.Send(msg)
-		if err != nil {
-			// Vérifier si l'erreur est liée aux limites de débit
-			if apiErr, ok := err.(*tgbotapi.Error); ok && apiErr.RetryAfter > 0 {
-				log.Printf("Trop de requêtes pour l'API Telegram. Réessayer après %d secondes. Scraper mis en pause en attendant", apiErr.RetryAfter)
-				time.Sleep(time.Duration(apiErr.RetryAfter) * time.Second)
-			} else {
-				log.Printf("Erreur lors de l'envoi du message Telegram : %v", err)
-				return
-			}
-		} else {
-			log.Println("Message envoyé au canal Telegram.")
-			return
+		sent, err := telegram.bot.Send(chattable)
+		if err == nil {
+			telegramSends.WithLabelValues("success").Inc()
+			telegramLog.InfoContext(ctx, "Message envoyé au canal Telegram", "channel", telegram.channel, "message_id", sent.MessageID)
+			return sent, nil
+		}
+
+		// Vérifier si l'erreur est liée aux limites de débit
+		apiErr, ok := err.(*tgbotapi.Error)
+		if !ok || apiErr.RetryAfter <= 0 {
+			telegramSends.WithLabelValues("error").Inc()
+			return tgbotapi.Message{}, fmt.Errorf("envoi du message Telegram : %w", err)
+		}
+		telegramSends.WithLabelValues("retry_after").Inc()
+		telegramRetryAfter.Observe(float64(apiErr.RetryAfter))
+		telegramLog.WarnContext(ctx, "Trop de requêtes pour l'API Telegram : scraper mis en pause en attendant", "retry_after", apiErr.RetryAfter)
+		select {
+		case <-ctx.Done():
+			return tgbotapi.Message{}, ctx.Err()
+		case <-time.After(time.Duration(apiErr.RetryAfter) * time.Second):
 		}
 
 		retries++
 		if retries >= MaxRetries {
-			log.Println("Nombre maximal de tentatives atteint. Abandon de l'envoi.")
-			return
+			return tgbotapi.Message{}, errors.New("nombre maximal de tentatives atteint, abandon de l'envoi")
 		}
 	}
 }
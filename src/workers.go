@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+/**
+ * runWorkers exécute une tâche pour chaque scraping prévu avec un nombre borné de goroutines,
+ * et attend la fin de toutes les tâches.
+ * @param {[]ScheduledRun} runs - Les scrapings à traiter.
+ * @param {int} workers - Nombre maximal de scrapings traités en même temps.
+ * @param {func(ScheduledRun)} task - La tâche à exécuter pour chaque scraping.
+ * @return {void}
+ */
+func runWorkers(runs []ScheduledRun, workers int, task func(ScheduledRun)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(runs) {
+		workers = len(runs)
+	}
+
+	jobs := make(chan ScheduledRun)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for run := range jobs {
+				task(run)
+			}
+		}()
+	}
+
+	for _, run := range runs {
+		jobs <- run
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+/**
+ * domainLocks associe un verrou à chaque domaine, pour ne jamais scraper un même site depuis plusieurs workers.
+ * Le délai entre deux requêtes d'un même collecteur reste géré par la LimitRule de colly.
+ * @property {sync.Mutex} mutex - Verrou protégeant la table des domaines.
+ * @property {map[string]*sync.Mutex} locks - Verrou de chaque domaine.
+ */
+type domainLocks struct {
+	mutex sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+/**
+ * newDomainLocks crée une table de verrous par domaine vide.
+ * @return {domainLocks} - La table de verrous.
+ */
+func newDomainLocks() *domainLocks {
+	return &domainLocks{locks: make(map[string]*sync.Mutex)}
+}
+
+/**
+ * Lock attend que le domaine soit libre puis le réserve.
+ * @param {string} domain - Le domaine à réserver.
+ * @return {func()} - La fonction libérant le domaine.
+ */
+func (domains *domainLocks) Lock(domain string) func() {
+	domains.mutex.Lock()
+	lock, ok := domains.locks[domain]
+	if !ok {
+		lock = &sync.Mutex{}
+		domains.locks[domain] = lock
+	}
+	domains.mutex.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+/**
+ * targetDomain retourne le domaine d'une URL de recherche, sans le préfixe www.
+ * @param {string} targetURL - L'URL de la recherche.
+ * @return {string} - Le domaine, ou l'URL entière si elle ne peut pas être analysée.
+ */
+func targetDomain(targetURL string) string {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil || parsedURL.Hostname() == "" {
+		return targetURL
+	}
+	return strings.TrimPrefix(strings.ToLower(parsedURL.Hostname()), "www.")
+}
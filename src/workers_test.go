@@ -0,0 +1,124 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrencyProbe mesure le nombre maximal de tâches exécutées en même temps, au total et par clé
+type concurrencyProbe struct {
+	mutex     sync.Mutex
+	active    int
+	maxActive int
+	byKey     map[string]int
+	maxByKey  map[string]int
+	done      map[string]int
+}
+
+func newConcurrencyProbe() *concurrencyProbe {
+	return &concurrencyProbe{byKey: make(map[string]int), maxByKey: make(map[string]int), done: make(map[string]int)}
+}
+
+// run simule un scraping de la clé en la gardant active quelques millisecondes
+func (probe *concurrencyProbe) run(key string, id string) {
+	probe.mutex.Lock()
+	probe.active++
+	probe.byKey[key]++
+	probe.maxActive = max(probe.maxActive, probe.active)
+	probe.maxByKey[key] = max(probe.maxByKey[key], probe.byKey[key])
+	probe.mutex.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	probe.mutex.Lock()
+	probe.active--
+	probe.byKey[key]--
+	probe.done[id]++
+	probe.mutex.Unlock()
+}
+
+// scheduledRuns crée un scraping prévu par URL de recherche
+func scheduledRuns(urls ...string) []ScheduledRun {
+	runs := make([]ScheduledRun, 0, len(urls))
+	for _, targetURL := range urls {
+		runs = append(runs, ScheduledRun{Target: SearchTarget{URL: targetURL}})
+	}
+	return runs
+}
+
+func TestRunWorkersLimit(t *testing.T) {
+	urls := []string{"https://a.fr/1", "https://a.fr/2", "https://a.fr/3", "https://a.fr/4", "https://a.fr/5", "https://a.fr/6", "https://a.fr/7", "https://a.fr/8"}
+
+	tests := []struct {
+		name    string
+		workers int
+		want    int
+	}{
+		{name: "limite globale", workers: 3, want: 3},
+		{name: "plus de workers que de scrapings", workers: 20, want: len(urls)},
+		{name: "aucun worker configuré", workers: 0, want: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			probe := newConcurrencyProbe()
+			runWorkers(scheduledRuns(urls...), test.workers, func(run ScheduledRun) {
+				probe.run("", run.Target.URL)
+			})
+
+			if probe.maxActive != test.want {
+				t.Errorf("scrapings simultanés = %d, attendu %d", probe.maxActive, test.want)
+			}
+			for _, targetURL := range urls {
+				if probe.done[targetURL] != 1 {
+					t.Errorf("recherche %s scrapée %d fois, attendu 1", targetURL, probe.done[targetURL])
+				}
+			}
+		})
+	}
+}
+
+func TestRunWorkersDomainLocks(t *testing.T) {
+	// Trois recherches sur le même site (avec ou sans www, casse différente) et deux autres sites
+	runs := scheduledRuns(
+		"https://www.afedim.fr/location?ville=rennes",
+		"https://afedim.fr/location?ville=brest",
+		"https://www.AFEDIM.fr/location?ville=nantes",
+		"https://www.giboire.com/location",
+		"https://www.foncia.com/location",
+	)
+	domains := newDomainLocks()
+	probe := newConcurrencyProbe()
+
+	runWorkers(runs, 4, func(run ScheduledRun) {
+		domain := targetDomain(run.Target.URL)
+		unlock := domains.Lock(domain)
+		defer unlock()
+		probe.run(domain, run.Target.URL)
+	})
+
+	if got := probe.maxByKey["afedim.fr"]; got != 1 {
+		t.Errorf("scrapings simultanés du même site = %d, attendu 1", got)
+	}
+	// Les autres sites sont scrapés pendant que les recherches du premier attendent leur tour
+	if probe.maxActive < 2 {
+		t.Errorf("scrapings simultanés = %d, attendu au moins 2 sur des sites différents", probe.maxActive)
+	}
+	if len(probe.done) != len(runs) {
+		t.Errorf("recherches scrapées = %d, attendu %d", len(probe.done), len(runs))
+	}
+}
+
+func TestTargetDomain(t *testing.T) {
+	tests := map[string]string{
+		"https://www.afedim.fr/location?ville=rennes": "afedim.fr",
+		"https://AFEDIM.fr/location":                  "afedim.fr",
+		"https://api.squarehabitat.fr:8443/recherche": "api.squarehabitat.fr",
+		"location-sans-domaine":                       "location-sans-domaine",
+	}
+	for targetURL, want := range tests {
+		if got := targetDomain(targetURL); got != want {
+			t.Errorf("targetDomain(%q) = %q, attendu %q", targetURL, got, want)
+		}
+	}
+}